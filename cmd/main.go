@@ -1,8 +1,13 @@
 package main
 
-import "bank/internal/app"
+import (
+	"bank/internal/cli"
+	"os"
+)
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 func main() {
-	app.New().
-		Run()
+	os.Exit(cli.Run(os.Args[1:]))
 }
@@ -1,82 +1,336 @@
 package app
 
 import (
+	"bank/internal/auth"
 	"bank/internal/config"
+	"bank/internal/health"
+	"bank/internal/metrics"
+	"bank/internal/outbox"
+	"bank/internal/partition"
+	"bank/internal/ratelimit"
 	"bank/internal/service"
+	"bank/internal/snapshot"
 	"bank/internal/storage"
+	"bank/internal/stream"
+	"bank/internal/tracing"
 	"bank/internal/transport"
 	"bank/internal/transport/handler"
+	"bank/internal/transport/rpc"
+	"bank/internal/webhook"
 	"bank/pkg/apilayer"
+	"bank/pkg/backoff"
+	"bank/pkg/logger"
 	"bank/pkg/postgres"
 	"context"
 	"errors"
-	"github.com/pressly/goose"
-	"log"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"io"
+	"log/slog"
 	"net/http"
-	"os/signal"
-	"syscall"
+	"os"
+	"sync"
+	"time"
 )
 
 type App struct {
 	config config.Config
+	log    *slog.Logger
 }
 
-func New() *App {
-	conf, err := config.New()
-	if err != nil {
-		log.Panic(err)
-	}
+// New expects a validated config.
+func New(conf config.Config) *App {
+	log := logger.New(os.Stdout, conf.Log.Level)
+	slog.SetDefault(log)
 
 	return &App{
 		config: conf,
+		log:    log,
 	}
 }
 
-func (app *App) Run() {
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer cancel()
+// component returns the logger of a background component, its records name the component they come from.
+func (app *App) component(name string) *slog.Logger {
+	return app.log.With(slog.String("component", name))
+}
+
+// Run serves until ctx is done or a server fails, then shuts down gracefully.
+func (app *App) Run(ctx context.Context) error {
+	err := app.run(ctx)
+	if err != nil {
+		app.log.Error("app stopped", slog.Any("error", err))
+	}
+
+	return err
+}
+
+func (app *App) run(ctx context.Context) error {
+	shutdownTracing, err := tracing.Setup(ctx, app.config.Tracing)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := shutdownTracing(ctx); err != nil {
+			app.log.Error("flush traces", slog.Any("error", err))
+		}
+	}()
+
+	hub := stream.NewHub(app.component("stream"))
+	defer hub.Close()
+
+	backend, err := app.newBackend(ctx, hub)
+	if err != nil {
+		return err
+	}
+	defer backend.close()
+
+	apiLayerClient := apilayer.New(app.config.APILayer.APIKey).
+		WithHTTPClient(&http.Client{
+			Timeout:   app.config.APILayer.Timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		}).
+		WithObserver(metrics.ObserveAPILayer)
 
-	pgConfig := postgres.Config{
-		Host: app.config.Postgres.Host, Port: app.config.Postgres.Port, DB: app.config.Postgres.DB,
-		User: app.config.Postgres.User, Password: app.config.Postgres.Password,
+	policy, err := auth.NewPolicy(app.config.Auth.Policy)
+	if err != nil {
+		return err
 	}
-	pgClient, err := postgres.NewClient(ctx, pgConfig)
+	verifier := auth.NewVerifier(app.config.Auth.Secret, app.config.Auth.TokenTTL)
+
+	limiter, err := newLimiter(app.config.RateLimit, backend.rateLimit, app.component("ratelimit"))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	if err = migrate("up", "migration", pgConfig.String()); err != nil {
-		log.Fatal(err)
+	auditService := service.NewAuditService(backend.audit)
+	auditHandler := handler.NewAuditHandler(auditService, policy)
+
+	var webhookHandler *handler.WebhookHandler
+	if backend.webhook != nil {
+		webhookHandler = handler.NewWebhookHandler(service.NewWebhookService(backend.webhook), policy)
 	}
 
-	apiLayerClient := apilayer.New(app.config.APILayer.APIKey)
+	sink, closer, err := newSink(app.config.Outbox)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
 
-	transactionStorage := storage.NewTransactionStorage(pgClient)
+	// background workers outlive the signal context: they are stopped only after in-flight requests are drained
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
 
-	balanceStorage := storage.NewBalanceStorage(pgClient)
-	balanceService := service.NewBalanceService(balanceStorage, transactionStorage)
-	balanceHandler := handler.NewBalanceHandler(balanceService, apiLayerClient)
+	var workers sync.WaitGroup
 
+	sinks := []outbox.Sink{sink}
+	if backend.webhook != nil {
+		sinks = append(sinks, webhook.NewSink(backend.webhook))
+	}
+
+	relay := outbox.NewRelay(backend.transactor, backend.outbox, outbox.NewFanoutSink(sinks...),
+		app.config.Outbox.Interval, app.config.Outbox.Batch, app.config.Outbox.Lease, app.component("outbox"))
+	workers.Add(1)
 	go func() {
-		err = transport.New().Handle(balanceHandler).Listen(app.config.Server.Addr)
+		defer workers.Done()
+		relay.Run(workersCtx)
+	}()
+
+	if backend.webhook != nil {
+		dispatcher := webhook.NewDispatcher(
+			backend.webhook,
+			webhook.NewClient(app.config.Webhook.Timeout),
+			backoff.Backoff{Base: app.config.Webhook.Backoff, Max: app.config.Webhook.MaxBackoff},
+			app.config.Webhook.MaxAttempts,
+			app.config.Webhook.Interval,
+			app.config.Webhook.Batch,
+			app.component("webhook"),
+		)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			dispatcher.Run(workersCtx)
+		}()
+	}
+
+	if backend.partition != nil {
+		manager := partition.NewManager(backend.transactor, backend.partition, app.config.Partition.Ahead, app.config.Partition.Interval,
+			app.component("partition"))
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			manager.Run(workersCtx)
+		}()
+	}
+
+	snapshotJob := snapshot.NewJob(backend.transactor, backend.snapshot, app.config.Snapshot.Settle, app.config.Snapshot.Interval,
+		app.component("snapshot"))
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		snapshotJob.Run(workersCtx)
+	}()
+
+	if backend.listen != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+
+			err := backend.listen(workersCtx, hub)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				app.log.Error("balance activity listener", slog.Any("error", err))
+			}
+		}()
+	}
+
+	balanceService := service.WithMetrics(service.WithTracing(service.WithRateLimit(
+		service.NewBalanceService(backend.transactor, backend.balance, backend.transaction, backend.audit, backend.outbox,
+			backend.snapshot),
+		limiter,
+	)))
+	balanceHandler := handler.NewBalanceHandler(balanceService, apiLayerClient, policy, hub)
+
+	checker := health.NewChecker(app.config.Health.Timeout)
+	for _, check := range backend.checks {
+		checker.Add(check.name, check.check)
+	}
+	if app.config.Health.CheckRates {
+		checker.Add("apilayer", apiLayerClient.Ping)
+	}
+	healthHandler := handler.NewHealthHandler(checker)
+
+	balanceServer := rpc.NewBalanceServer(balanceService, apiLayerClient, policy)
+
+	httpServer := transport.New(app.config.Server, verifier, limiter, app.log).Handle(balanceHandler, auditHandler, webhookHandler, healthHandler)
+	grpcServer := rpc.New(verifier, limiter, app.log).Handle(balanceServer)
+
+	errs := make(chan error, 2)
+
+	go func() {
+		app.log.Info("http server started", slog.String("addr", app.config.Server.Addr))
+		err := httpServer.Listen(app.config.Server.Addr)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatal(err)
+			errs <- fmt.Errorf("http server: %w", err)
 		}
 	}()
 
-	<-ctx.Done()
+	go func() {
+		app.log.Info("grpc server started", slog.String("addr", app.config.Server.GRPCAddr))
+		err := grpcServer.Listen(app.config.Server.GRPCAddr)
+		if err != nil {
+			errs <- fmt.Errorf("grpc server: %w", err)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		err = nil
+	case err = <-errs:
+	}
+
+	app.log.Info("shutting down",
+		slog.Duration("delay", app.config.Server.ShutdownDelay),
+		slog.Duration("timeout", app.config.Server.ShutdownTimeout))
+
+	// 1. report unready and keep serving until the load balancers probed it and stopped routing here
+	checker.Shutdown()
+	time.Sleep(app.config.Server.ShutdownDelay)
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), app.config.Server.ShutdownTimeout)
+	defer cancelShutdown()
+
+	// 2. end open event streams, they would otherwise hold the drain until the timeout; stop accepting and wait
+	// for in-flight requests
+	hub.Close()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		app.log.Error("http server shutdown", slog.Any("error", err))
+	}
+	if err := grpcServer.Shutdown(shutdownCtx); err != nil {
+		app.log.Error("grpc server shutdown", slog.Any("error", err))
+	}
+
+	// 3. stop background workers and wait for them to return; an interrupted batch is retried on the next start
+	stopWorkers()
+	workers.Wait()
+
+	// 4. close the pool once nothing uses it
+	backend.close()
+
+	app.log.Info("stopped")
+
+	return err
 }
 
-func migrate(command string, dir string, dbstring string) error {
-	db, err := goose.OpenDBWithDriver("postgres", dbstring)
-	if err != nil {
-		return err
+// PostgresConfig maps the postgres config section to the client settings.
+func PostgresConfig(conf config.Postgres) postgres.Config {
+	return postgres.Config{
+		Host:              conf.Host,
+		Port:              conf.Port,
+		User:              conf.User,
+		Password:          conf.Password,
+		DB:                conf.DB,
+		SSLMode:           conf.SSLMode,
+		SSLRootCert:       conf.SSLRootCert,
+		MaxConns:          conf.MaxConns,
+		MinConns:          conf.MinConns,
+		MaxConnLifetime:   conf.MaxConnLifetime,
+		MaxConnIdleTime:   conf.MaxConnIdleTime,
+		HealthCheckPeriod: conf.HealthCheckPeriod,
+		StatementTimeout:  conf.StatementTimeout,
 	}
-	defer db.Close()
+}
 
-	if err = goose.Run(command, db, dir); err != nil {
-		return err
+// NewTransactor starts the transactions of the service on pool at the configured isolation level and retries.
+func NewTransactor(conf config.Postgres, pool postgres.Pool, log *slog.Logger) storage.Transactor {
+	retryPolicy := storage.RetryPolicy{
+		MaxAttempts: conf.RetryMaxAttempts,
+		Backoff:     conf.RetryBackoff,
+		MaxBackoff:  conf.RetryMaxBackoff,
+		Budget:      conf.RetryBudget,
 	}
 
+	return storage.NewTransactor(pool, retryPolicy, log, storage.WithIsolation(pgx.TxIsoLevel(conf.IsolationLevel)))
+}
+
+// nopCloser is the io.Closer of the sinks that hold nothing to close.
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
 	return nil
 }
+
+func newSink(conf config.Outbox) (outbox.Sink, io.Closer, error) {
+	switch conf.Sink {
+	case "none":
+		return outbox.NewFanoutSink(), nopCloser{}, nil
+	case "stdout":
+		return outbox.NewWriterSink(os.Stdout), nopCloser{}, nil
+	case "file":
+		return outbox.NewFileSink(conf.File)
+	case "webhook":
+		return outbox.NewWebhookSink(&http.Client{Timeout: conf.Timeout}, conf.Webhook), nopCloser{}, nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown outbox sink %q", conf.Sink)
+}
+
+func newLimiter(conf config.RateLimit, shared storage.RateLimitStorage, log *slog.Logger) (*ratelimit.Limiter, error) {
+	var store ratelimit.Store
+	switch conf.Backend {
+	case "memory":
+		store = ratelimit.NewMemoryStore()
+	case "postgres":
+		if shared == nil {
+			return nil, fmt.Errorf("rate limit backend %q needs the postgres storage backend", conf.Backend)
+		}
+		store = ratelimit.NewSharedStore(shared, log)
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", conf.Backend)
+	}
+
+	return ratelimit.NewLimiter(store, conf.Routes, conf.Default, conf.Account, log)
+}
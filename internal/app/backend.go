@@ -0,0 +1,159 @@
+package app
+
+import (
+	"bank/internal/health"
+	"bank/internal/metrics"
+	"bank/internal/storage"
+	"bank/internal/storage/memory"
+	"bank/internal/stream"
+	"bank/internal/tracing"
+	"bank/migration"
+	"bank/pkg/postgres"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// backend holds the storages of the configured STORAGE_BACKEND and what depends on where they keep the data.
+type backend struct {
+	transactor  storage.Transactor
+	balance     storage.BalanceStorage
+	transaction storage.TransactionStorage
+	audit       storage.AuditStorage
+	outbox      storage.OutboxStorage
+	snapshot    storage.SnapshotStorage
+	// webhook, rateLimit and partition are nil when the backend does not implement them.
+	webhook   storage.WebhookStorage
+	rateLimit storage.RateLimitStorage
+	partition storage.PartitionStorage
+
+	checks []namedCheck
+	// listen forwards the balance activity to the hub until ctx is done, nil when the storages publish it on commit.
+	listen func(ctx context.Context, hub *stream.Hub) error
+	// close releases the connections, it may be called more than once.
+	close func()
+}
+
+type namedCheck struct {
+	name  string
+	check health.Check
+}
+
+func (app *App) newBackend(ctx context.Context, hub *stream.Hub) (*backend, error) {
+	switch app.config.Storage.Backend {
+	case "postgres":
+		return app.newPostgresBackend(ctx)
+	case "memory":
+		app.log.Warn("storing data in memory, it is lost on exit and webhooks are disabled")
+
+		return newMemoryBackend(hub), nil
+	}
+
+	return nil, fmt.Errorf("unknown storage backend %q", app.config.Storage.Backend)
+}
+
+func (app *App) newPostgresBackend(ctx context.Context) (*backend, error) {
+	pgConfig := PostgresConfig(app.config.Postgres)
+	pgConfig.Tracer = tracing.QueryTracer{}
+
+	pgClient, err := postgres.NewClient(ctx, pgConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.RegisterPool(pgClient.Stat)
+
+	migrationDB, err := migration.Open(pgConfig.String())
+	if err != nil {
+		pgClient.Close()
+		return nil, err
+	}
+
+	client, closeReplica, err := app.newRouter(ctx, pgClient, pgConfig)
+	if err != nil {
+		migrationDB.Close()
+		pgClient.Close()
+		return nil, err
+	}
+
+	if app.config.Server.SkipMigrate {
+		app.log.Info("skipping migrations")
+	} else if err = migration.Run(migrationDB, "up"); err != nil {
+		closeReplica()
+		migrationDB.Close()
+		pgClient.Close()
+		return nil, err
+	}
+
+	return &backend{
+		transactor:  NewTransactor(app.config.Postgres, pgClient, app.component("storage")),
+		balance:     storage.NewBalanceStorage(client),
+		transaction: storage.NewTransactionStorage(client),
+		audit:       storage.NewAuditStorage(client),
+		outbox:      storage.NewOutboxStorage(pgClient),
+		snapshot:    storage.NewSnapshotStorage(client),
+		webhook:     storage.NewWebhookStorage(pgClient),
+		rateLimit:   storage.NewRateLimitStorage(pgClient),
+		partition:   storage.NewPartitionStorage(pgClient),
+		checks: []namedCheck{
+			{name: "postgres", check: health.Postgres(pgClient)},
+			{name: "migrations", check: health.Migrations(migrationDB)},
+		},
+		listen: func(ctx context.Context, hub *stream.Hub) error {
+			return postgres.NewListener(pgConfig).Listen(ctx, "balance_activity", hub.Notify)
+		},
+		close: sync.OnceFunc(func() {
+			closeReplica()
+			if err := migrationDB.Close(); err != nil {
+				app.log.Error("close migrations connection", slog.Any("error", err))
+			}
+			pgClient.Close()
+		}),
+	}, nil
+}
+
+// newRouter returns primary, or a storage.Router reading from the configured replica with the pool settings of
+// pgConfig. The replica is not required to be up: reads go to the primary until the watch, running until
+// closeReplica is called, finds it answering.
+func (app *App) newRouter(ctx context.Context, primary postgres.Pool, pgConfig postgres.Config) (client postgres.Client, closeReplica func(), err error) {
+	if app.config.Postgres.ReplicaDSN == "" {
+		return primary, func() {}, nil
+	}
+
+	pgConfig.DSN = app.config.Postgres.ReplicaDSN
+
+	replica, err := postgres.NewPool(ctx, pgConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("configure replica: %w", err)
+	}
+
+	router := storage.NewRouter(primary, replica, app.component("storage"))
+
+	watchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.Watch(watchCtx, app.config.Postgres.ReplicaCheckInterval)
+	}()
+
+	return router, func() {
+		cancel()
+		<-done
+		replica.Close()
+	}, nil
+}
+
+func newMemoryBackend(hub *stream.Hub) *backend {
+	store := memory.NewStore().OnCommit(hub.Publish)
+
+	return &backend{
+		transactor:  store,
+		balance:     memory.NewBalanceStorage(store),
+		transaction: memory.NewTransactionStorage(store),
+		audit:       memory.NewAuditStorage(store),
+		outbox:      memory.NewOutboxStorage(store),
+		snapshot:    memory.NewSnapshotStorage(store),
+		close:       func() {},
+	}
+}
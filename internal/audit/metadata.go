@@ -0,0 +1,22 @@
+package audit
+
+import "context"
+
+// Metadata describes who issued a mutating request and how, so it can be written next to the change it caused.
+type Metadata struct {
+	Actor       string
+	SourceIP    string
+	RequestID   string
+	PayloadHash string
+}
+
+type metadataKey struct{}
+
+func NewContext(ctx context.Context, metadata Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+func FromContext(ctx context.Context) Metadata {
+	metadata, _ := ctx.Value(metadataKey{}).(Metadata)
+	return metadata
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"bank/pkg/apperror"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+)
+
+type Action string
+
+const (
+	GetBalance        Action = "balance.get"
+	SelectTransaction Action = "balance.transaction"
+	Transfer          Action = "balance.transfer"
+	Debet             Action = "balance.debet"
+	Credit            Action = "balance.credit"
+	SelectAudit       Action = "audit.select"
+	VerifyAudit       Action = "audit.verify"
+	CreateWebhook     Action = "webhook.create"
+	SelectWebhook     Action = "webhook.select"
+	ReplayWebhook     Action = "webhook.replay"
+	DisableWebhook    Action = "webhook.disable"
+
+	anyAction Action = "*"
+)
+
+// Scope limits an allowed action to the principal's own account or lets it touch any account.
+type Scope int
+
+const (
+	Any Scope = iota
+	Own
+)
+
+type Policy struct {
+	rules map[Role]map[Action]Scope
+}
+
+// NewPolicy parses rules in the form role -> "action[=own] action[=own] ...",
+// e.g. "user" -> "balance.get=own balance.transfer=own", "admin" -> "*".
+func NewPolicy(rules map[string]string) (*Policy, error) {
+	policy := &Policy{
+		rules: make(map[Role]map[Action]Scope, len(rules)),
+	}
+
+	for role, value := range rules {
+		role = strings.TrimSpace(role)
+		if role == "" {
+			return nil, fmt.Errorf("policy: empty role")
+		}
+
+		actions := make(map[Action]Scope)
+		for _, field := range strings.Fields(value) {
+			action, scope, _ := strings.Cut(field, "=")
+
+			switch scope {
+			case "", "any":
+				actions[Action(action)] = Any
+			case "own":
+				actions[Action(action)] = Own
+			default:
+				return nil, fmt.Errorf("policy: role %q: unknown scope %q for %q", role, scope, action)
+			}
+		}
+
+		policy.rules[Role(role)] = actions
+	}
+
+	return policy, nil
+}
+
+// Authorize reports whether principal may perform action on the account, returning apperror.Forbidden otherwise.
+func (policy *Policy) Authorize(principal Principal, action Action, accountID uuid.UUID) error {
+	actions, ok := policy.rules[principal.Role]
+	if !ok {
+		return apperror.Forbidden.WithMessage(fmt.Sprintf("role %q is not allowed to perform %s", principal.Role, action))
+	}
+
+	scope, ok := actions[action]
+	if !ok {
+		scope, ok = actions[anyAction]
+	}
+	if !ok {
+		return apperror.Forbidden.WithMessage(fmt.Sprintf("role %q is not allowed to perform %s", principal.Role, action))
+	}
+
+	if scope == Own && principal.Subject != accountID.String() {
+		return apperror.Forbidden.WithMessage(fmt.Sprintf("%s is allowed only on your own account", action))
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package auth_test
+
+import (
+	"bank/internal/auth"
+	"bank/pkg/apperror"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// defaultPolicy is the default AUTH_POLICY, spelled out so the test does not depend on the environment.
+func defaultPolicy(t *testing.T) *auth.Policy {
+	policy, err := auth.NewPolicy(map[string]string{
+		"user":    "balance.get=own balance.transaction=own balance.transfer=own webhook.create=own webhook.select=own webhook.replay=own webhook.disable=own",
+		"service": "balance.debet balance.credit",
+		"admin":   "*",
+	})
+	require.NoError(t, err)
+
+	return policy
+}
+
+func TestPolicy_Authorize(t *testing.T) {
+	policy := defaultPolicy(t)
+
+	ownID := uuid.New()
+	otherID := uuid.New()
+
+	user := auth.Principal{Subject: ownID.String(), Role: auth.User}
+	service := auth.Principal{Subject: "billing", Role: auth.Service}
+	admin := auth.Principal{Subject: "root", Role: auth.Admin}
+	stranger := auth.Principal{Subject: ownID.String(), Role: "guest"}
+
+	tests := []struct {
+		name      string
+		principal auth.Principal
+		action    auth.Action
+		accountID uuid.UUID
+		wantErr   error
+	}{
+		{name: "user gets own balance", principal: user, action: auth.GetBalance, accountID: ownID, wantErr: nil},
+		{name: "user gets other balance", principal: user, action: auth.GetBalance, accountID: otherID, wantErr: apperror.Forbidden},
+		{name: "user selects own transactions", principal: user, action: auth.SelectTransaction, accountID: ownID, wantErr: nil},
+		{name: "user selects other transactions", principal: user, action: auth.SelectTransaction, accountID: otherID, wantErr: apperror.Forbidden},
+		{name: "user transfers from own account", principal: user, action: auth.Transfer, accountID: ownID, wantErr: nil},
+		{name: "user transfers from other account", principal: user, action: auth.Transfer, accountID: otherID, wantErr: apperror.Forbidden},
+		{name: "user debets", principal: user, action: auth.Debet, accountID: ownID, wantErr: apperror.Forbidden},
+		{name: "user credits", principal: user, action: auth.Credit, accountID: ownID, wantErr: apperror.Forbidden},
+
+		{name: "service gets balance", principal: service, action: auth.GetBalance, accountID: otherID, wantErr: apperror.Forbidden},
+		{name: "service selects transactions", principal: service, action: auth.SelectTransaction, accountID: otherID, wantErr: apperror.Forbidden},
+		{name: "service transfers", principal: service, action: auth.Transfer, accountID: otherID, wantErr: apperror.Forbidden},
+		{name: "service debets", principal: service, action: auth.Debet, accountID: otherID, wantErr: nil},
+		{name: "service credits", principal: service, action: auth.Credit, accountID: otherID, wantErr: nil},
+
+		{name: "admin gets balance", principal: admin, action: auth.GetBalance, accountID: otherID, wantErr: nil},
+		{name: "admin selects transactions", principal: admin, action: auth.SelectTransaction, accountID: otherID, wantErr: nil},
+		{name: "admin transfers", principal: admin, action: auth.Transfer, accountID: otherID, wantErr: nil},
+		{name: "admin debets", principal: admin, action: auth.Debet, accountID: otherID, wantErr: nil},
+		{name: "admin credits", principal: admin, action: auth.Credit, accountID: otherID, wantErr: nil},
+
+		{name: "unknown role gets own balance", principal: stranger, action: auth.GetBalance, accountID: ownID, wantErr: apperror.Forbidden},
+		{name: "unknown role debets", principal: stranger, action: auth.Debet, accountID: ownID, wantErr: apperror.Forbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Authorize(tt.principal, tt.action, tt.accountID)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Policy.Authorize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   map[string]string
+		wantErr bool
+	}{
+		{name: "ok", rules: map[string]string{"user": "balance.get=own balance.transfer=any", "admin": "*"}, wantErr: false},
+		{name: "unknown scope", rules: map[string]string{"user": "balance.get=mine"}, wantErr: true},
+		{name: "empty role", rules: map[string]string{" ": "*"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := auth.NewPolicy(tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	verifier := auth.NewVerifier("secret", time.Hour)
+
+	principal := auth.Principal{Subject: uuid.NewString(), Role: auth.User}
+	token, err := verifier.Sign(principal)
+	require.NoError(t, err)
+
+	got, err := verifier.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, principal, got)
+
+	_, err = auth.NewVerifier("other", time.Hour).Verify(token)
+	assert.ErrorIs(t, err, apperror.Unauthorized)
+
+	expired, err := auth.NewVerifier("secret", -time.Minute).Sign(principal)
+	require.NoError(t, err)
+	_, err = verifier.Verify(expired)
+	assert.ErrorIs(t, err, apperror.Unauthorized)
+
+	// a token without exp would be valid forever
+	eternal, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		Role:             principal.Role,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: principal.Subject},
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	_, err = verifier.Verify(eternal)
+	assert.ErrorIs(t, err, apperror.Unauthorized)
+}
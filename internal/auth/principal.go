@@ -0,0 +1,27 @@
+package auth
+
+import "context"
+
+type Role string
+
+const (
+	User    Role = "user"
+	Service Role = "service"
+	Admin   Role = "admin"
+)
+
+type Principal struct {
+	Subject string
+	Role    Role
+}
+
+type principalKey struct{}
+
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
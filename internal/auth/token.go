@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"bank/pkg/apperror"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+type Claims struct {
+	Role Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+type Verifier struct {
+	secret []byte
+	ttl    time.Duration
+	parser *jwt.Parser
+}
+
+// NewVerifier returns a verifier of the tokens signed with secret. Tokens without an expiry are rejected, the ones
+// it signs expire after ttl.
+func NewVerifier(secret string, ttl time.Duration) *Verifier {
+	return &Verifier{
+		secret: []byte(secret),
+		ttl:    ttl,
+		parser: jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}), jwt.WithExpirationRequired()),
+	}
+}
+
+func (verifier *Verifier) Verify(token string) (Principal, error) {
+	var claims Claims
+	_, err := verifier.parser.ParseWithClaims(token, &claims, func(token *jwt.Token) (any, error) {
+		return verifier.secret, nil
+	})
+	if err != nil {
+		return Principal{}, apperror.Unauthorized.WithError(err).WithMessage("invalid token")
+	}
+
+	if claims.Subject == "" || claims.Role == "" {
+		return Principal{}, apperror.Unauthorized.WithMessage("token has no subject or role")
+	}
+
+	return Principal{
+		Subject: claims.Subject,
+		Role:    claims.Role,
+	}, nil
+}
+
+func (verifier *Verifier) Sign(principal Principal) (string, error) {
+	claims := Claims{
+		Role: principal.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   principal.Subject,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(verifier.ttl)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(verifier.secret)
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"bank/internal/dto"
+	"bank/internal/service"
+	"bank/internal/storage"
+	"bank/pkg/sort"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func account(ctx context.Context, args []string) error {
+	flags := newFlagSet("account [flags] show|freeze USER_ID")
+	count := flags.Uint64("transactions", 10, "number of latest transactions listed by show")
+	unfreeze := flags.Bool("unfreeze", false, "lift the freeze set by freeze")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return usageError{message: "expected a subcommand and a USER_ID"}
+	}
+
+	command := flags.Arg(0)
+	if command != "show" && command != "freeze" {
+		return usageError{message: "unknown subcommand " + command}
+	}
+
+	userID, err := uuid.Parse(flags.Arg(1))
+	if err != nil {
+		return usageError{message: fmt.Sprintf("invalid USER_ID: %v", err)}
+	}
+
+	pool, transactor, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	balanceStorage := storage.NewBalanceStorage(pool)
+	accountService := service.NewAccountService(balanceStorage)
+
+	if command == "freeze" {
+		if err = accountService.Freeze(ctx, userID, !*unfreeze); err != nil {
+			return err
+		}
+
+		if *unfreeze {
+			fmt.Printf("account %s unfrozen\n", userID)
+		} else {
+			fmt.Printf("account %s frozen\n", userID)
+		}
+
+		return nil
+	}
+
+	acc, err := accountService.Show(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	balanceService := service.NewBalanceService(transactor, balanceStorage, storage.NewTransactionStorage(pool),
+		storage.NewAuditStorage(pool), storage.NewOutboxStorage(pool), storage.NewSnapshotStorage(pool))
+	transactions, err := balanceService.SelectTransaction(ctx, dto.SelectTransaction{
+		UserID: userID,
+		SelectTransactionFilter: dto.SelectTransactionFilter{
+			Sort:       "created_at",
+			Order:      "desc",
+			Pagination: sort.Pagination{Count: *count},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "user_id\t%s\n", acc.UserID)
+	fmt.Fprintf(w, "balance\t%.2f\n", acc.Balance.Balance)
+	fmt.Fprintf(w, "frozen\t%t\n", acc.Frozen)
+
+	if len(transactions) != 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "CREATED_AT\tTYPE\tAMOUNT\tCOUNTERPARTY\tCOMMENT")
+		for _, transaction := range transactions {
+			counterparty := "-"
+			if transaction.PayerID != nil {
+				counterparty = transaction.PayerID.String()
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%.2f\t%s\t%s\n", transaction.CreatedAt.Format(time.RFC3339), transaction.Type,
+				transaction.Amount, counterparty, transaction.Comment)
+		}
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"bank/internal/model"
+	"bank/internal/partition"
+	"bank/internal/storage"
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// archive moves the transaction partitions older than the retention to the archive tables. The history APIs read
+// both, so archiving only changes where the rows are kept.
+func archive(ctx context.Context, args []string) error {
+	flags := newFlagSet("archive [flags]")
+	bindEnv(flags, "retention", "PARTITION_RETENTION", "`months` of transactions kept in the transaction table")
+	bindEnv(flags, "tablespace", "PARTITION_ARCHIVE_TABLESPACE", "`tablespace` the archived partitions are moved to")
+	dryRun := flags.Bool("dry-run", false, "only list the partitions that would be archived")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return usageError{message: "unexpected arguments"}
+	}
+
+	conf, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+
+	pool, transactor, err := open(ctx, conf)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	manager := partition.NewManager(transactor, storage.NewPartitionStorage(pool), conf.Partition.Ahead, conf.Partition.Interval, newLogger(conf))
+
+	expired, err := manager.Expired(ctx, conf.Partition.Retention)
+	if err != nil {
+		return err
+	}
+
+	if len(expired) == 0 {
+		fmt.Printf("no partition is older than %d months\n", conf.Partition.Retention)
+		return nil
+	}
+
+	if *dryRun {
+		return printPartitions(expired)
+	}
+
+	// the partitions archived before a failure stay archived, they are listed either way
+	archived, archiveErr := manager.Archive(ctx, expired, conf.Partition.ArchiveTablespace)
+	if len(archived) != 0 {
+		if err = printPartitions(archived); err != nil {
+			return err
+		}
+	}
+
+	return archiveErr
+}
+
+func printPartitions(partitions []model.Partition) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tFROM\tTO\tARCHIVED")
+	for _, table := range partitions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\n", table.Name, table.From.UTC().Format(time.DateOnly),
+			table.To.UTC().Format(time.DateOnly), table.Archived)
+	}
+
+	return w.Flush()
+}
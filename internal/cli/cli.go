@@ -0,0 +1,199 @@
+// Package cli implements the bank command line: serving the API, running migrations and operator tasks.
+package cli
+
+import (
+	"bank/internal/app"
+	"bank/internal/config"
+	"bank/internal/storage"
+	"bank/pkg/logger"
+	"bank/pkg/postgres"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{name: "serve", summary: "run the HTTP and gRPC servers", run: serve},
+	{name: "migrate", summary: "manage the database schema", run: migrate},
+	{name: "account", summary: "inspect or freeze an account", run: account},
+	{name: "reconcile", summary: "compare every balance with the sum of its transactions", run: reconcile},
+	{name: "import", summary: "apply debets, credits and transfers from a CSV file", run: importFile},
+	{name: "archive", summary: "move old transaction partitions to the archive tables", run: archive},
+}
+
+// errFlags stands for a flag error the flag package has already reported together with the usage.
+var errFlags = errors.New("invalid flags")
+
+// usageError is reported with the command usage and exit code 2.
+type usageError struct {
+	message string
+}
+
+func (err usageError) Error() string {
+	return err.message
+}
+
+// Run executes the command named by args[0] and returns the process exit code. Without arguments it serves,
+// so existing deployments that start the bare binary keep working.
+func Run(args []string) int {
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	name := args[0]
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		err := cmd.run(ctx, args[1:])
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.Is(err, errFlags):
+			return 2
+		case errors.As(err, new(usageError)):
+			fmt.Fprintf(os.Stderr, "bank %s: %v\nrun \"bank %s -h\" for usage\n", name, err, name)
+			return 2
+		default:
+			fmt.Fprintf(os.Stderr, "bank %s: %v\n", name, err)
+			return 1
+		}
+	}
+
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "bank: unknown command %q\n\n", name)
+	printUsage(os.Stderr)
+
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: bank <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `run "bank <command> -h" for the flags of a command`)
+}
+
+// envFlag sets an environment variable when the flag is given, so flags take precedence over the environment
+// and the config file through the regular config loading.
+type envFlag struct {
+	env     string
+	boolean bool
+}
+
+func (f envFlag) String() string {
+	return ""
+}
+
+func (f envFlag) Set(value string) error {
+	return os.Setenv(f.env, value)
+}
+
+func (f envFlag) IsBoolFlag() bool {
+	return f.boolean
+}
+
+// newFlagSet returns the flags shared by every command: the config file and the database connection.
+func newFlagSet(usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(usage, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: bank %s\n\n", usage)
+		flags.PrintDefaults()
+	}
+
+	bindEnv(flags, "config", "CONFIG_FILE", "YAML or TOML config `file`")
+	bindEnv(flags, "postgres-host", "POSTGRES_HOST", "postgres `host`")
+	bindEnv(flags, "postgres-port", "POSTGRES_PORT", "postgres `port`")
+	bindEnv(flags, "postgres-user", "POSTGRES_USER", "postgres `user`")
+	bindEnv(flags, "postgres-password", "POSTGRES_PASSWORD", "postgres `password`")
+	bindEnv(flags, "postgres-db", "POSTGRES_DB", "postgres `database`")
+	bindEnv(flags, "postgres-sslmode", "POSTGRES_SSLMODE", "postgres `sslmode`")
+	bindEnv(flags, "log-level", "LOG_LEVEL", "log `level`")
+
+	return flags
+}
+
+func parse(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return errFlags
+	}
+
+	return err
+}
+
+func bindEnv(flags *flag.FlagSet, name, env, usage string) {
+	flags.Var(envFlag{env: env}, name, fmt.Sprintf("%s, overrides %s", usage, env))
+}
+
+func bindEnvBool(flags *flag.FlagSet, name, env, usage string) {
+	flags.Var(envFlag{env: env, boolean: true}, name, fmt.Sprintf("%s, overrides %s", usage, env))
+}
+
+// loadConfig reads the config after the flags were parsed. Commands that only touch the database check just
+// the postgres settings, so they can run with a partial environment.
+func loadConfig(full bool) (config.Config, error) {
+	conf, err := config.New()
+	if err != nil {
+		return conf, err
+	}
+
+	if full {
+		err = conf.Validate()
+	} else {
+		err = conf.ValidatePostgres()
+	}
+
+	return conf, err
+}
+
+// connect opens a pool to the configured database and a transactor starting transactions on it.
+func connect(ctx context.Context) (postgres.Pool, storage.Transactor, error) {
+	conf, err := loadConfig(false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return open(ctx, conf)
+}
+
+// open is connect for commands that need more of the config than the database settings.
+func open(ctx context.Context, conf config.Config) (postgres.Pool, storage.Transactor, error) {
+	pool, err := postgres.NewClient(ctx, app.PostgresConfig(conf.Postgres))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pool, app.NewTransactor(conf.Postgres, pool, newLogger(conf)), nil
+}
+
+// newLogger logs to stderr, leaving stdout to the output of the command.
+func newLogger(conf config.Config) *slog.Logger {
+	return logger.New(os.Stderr, conf.Log.Level)
+}
@@ -0,0 +1,114 @@
+package cli_test
+
+import (
+	"bank/internal/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun_Usage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "help", args: []string{"help"}, want: 0},
+		{name: "unknown command", args: []string{"deploy"}, want: 2},
+		{name: "unknown flag", args: []string{"reconcile", "-force"}, want: 2},
+		{name: "migrate without subcommand", args: []string{"migrate"}, want: 2},
+		{name: "unknown migrate subcommand", args: []string{"migrate", "sideways"}, want: 2},
+		{name: "account without user", args: []string{"account", "show"}, want: 2},
+		{name: "account with invalid user", args: []string{"account", "freeze", "42"}, want: 2},
+		{name: "import without file", args: []string{"import"}, want: 2},
+		{name: "archive with arguments", args: []string{"archive", "2023-01"}, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, cli.Run(tt.args))
+		})
+	}
+}
+
+func TestRun_FlagsOverrideEnv(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_DB", "bank")
+	t.Setenv("POSTGRES_SSLMODE", "disable")
+
+	// the invalid sslmode from the flag fails validation before anything connects
+	assert.Equal(t, 1, cli.Run([]string{"reconcile", "-postgres-sslmode", "sometimes"}))
+	assert.Equal(t, "sometimes", os.Getenv("POSTGRES_SSLMODE"))
+}
+
+func TestRun_MigrateCreate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "migration"), 0o755))
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	require.Equal(t, 0, cli.Run([]string{"migrate", "create", "add_index"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "migration", "*_add_index.sql"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	content, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "-- +goose Up"))
+}
+
+func TestRun_ImportDryRun(t *testing.T) {
+	payee, payer := "a39c71f8-6d1b-466c-8367-ebd86764268b", "bcf4b5f7-8f73-4205-82e6-cf20e898a98a"
+
+	tests := []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{
+			name: "ok",
+			content: "type,payee_id,payer_id,amount,comment\n" +
+				"debet," + payee + ",,100,salary\n" +
+				"credit," + payee + ",,10.5,atm\n" +
+				"transfer," + payee + "," + payer + ",20,\"debt, paid\"\n",
+			want: 0,
+		},
+		{
+			name:    "wrong header",
+			content: "kind,account,amount\n",
+			want:    1,
+		},
+		{
+			name:    "unknown type",
+			content: "type,payee_id,payer_id,amount,comment\nrefund," + payee + ",,1,\n",
+			want:    1,
+		},
+		{
+			name:    "non-positive amount",
+			content: "type,payee_id,payer_id,amount,comment\ndebet," + payee + ",,0,\n",
+			want:    1,
+		},
+		{
+			name:    "transfer without payer",
+			content: "type,payee_id,payer_id,amount,comment\ntransfer," + payee + ",,1,\n",
+			want:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "operations.csv")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			assert.Equal(t, tt.want, cli.Run([]string{"import", "-dry-run", path}))
+		})
+	}
+}
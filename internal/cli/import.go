@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"bank/internal/audit"
+	"bank/internal/domain"
+	"bank/internal/dto"
+	"bank/internal/service"
+	"bank/internal/storage"
+	"bank/pkg/validator"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+var importHeader = []string{"type", "payee_id", "payer_id", "amount", "comment"}
+
+// operation is one data row of an import file. For debets and credits payee_id is the account.
+type operation struct {
+	line    int
+	kind    string
+	payeeID uuid.UUID
+	payerID uuid.UUID
+	amount  float64
+	comment string
+}
+
+// importFile applies the operations of a CSV file one by one through BalanceService, so they are audited and
+// published like API calls. The whole file is parsed before anything is applied; on failure the line is reported
+// and the import can be resumed with -from.
+func importFile(ctx context.Context, args []string) error {
+	flags := newFlagSet("import [flags] FILE")
+	from := flags.Int("from", 0, "skip the rows before this file `line`, to resume a failed import")
+	dryRun := flags.Bool("dry-run", false, "only parse and validate the file")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageError{message: "expected a FILE, - reads stdin"}
+	}
+
+	path := flags.Arg(0)
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		r = file
+	}
+
+	operations, err := parseOperations(r)
+	if err != nil {
+		return err
+	}
+
+	operations = slices.DeleteFunc(operations, func(op operation) bool {
+		return op.line < *from
+	})
+
+	if *dryRun {
+		fmt.Printf("%d operations are valid\n", len(operations))
+		return nil
+	}
+
+	pool, transactor, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	balanceService := service.NewBalanceService(transactor, storage.NewBalanceStorage(pool), storage.NewTransactionStorage(pool),
+		storage.NewAuditStorage(pool), storage.NewOutboxStorage(pool), storage.NewSnapshotStorage(pool))
+
+	for i, op := range operations {
+		opCtx := audit.NewContext(ctx, audit.Metadata{
+			Actor:     "cli:import",
+			RequestID: fmt.Sprintf("import:%s:%d", filepath.Base(path), op.line),
+		})
+
+		if err = apply(opCtx, balanceService, op); err != nil {
+			return fmt.Errorf("line %d: %w (imported %d operations, resume with -from %d)", op.line, err, i, op.line)
+		}
+	}
+
+	fmt.Printf("imported %d operations\n", len(operations))
+
+	return nil
+}
+
+func apply(ctx context.Context, balanceService service.BalanceService, op operation) error {
+	var err error
+	switch op.kind {
+	case domain.Debet:
+		_, err = balanceService.Debet(ctx, dto.Debet{UserID: op.payeeID, Amount: op.amount, Comment: op.comment})
+	case domain.Credit:
+		_, err = balanceService.Credit(ctx, dto.Credit{UserID: op.payeeID, Amount: op.amount, Comment: op.comment})
+	case domain.Transfer:
+		err = balanceService.Transfer(ctx, dto.Transfer{PayeeID: op.payeeID, PayerID: op.payerID, Amount: op.amount, Comment: op.comment})
+	}
+
+	return err
+}
+
+// parseOperations reads a CSV file with the header "type,payee_id,payer_id,amount,comment", reporting the
+// first invalid row.
+func parseOperations(r io.Reader) ([]operation, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(importHeader)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("empty file")
+		}
+
+		return nil, err
+	}
+	if !slices.Equal(header, importHeader) {
+		return nil, fmt.Errorf("header must be %q", importHeader)
+	}
+
+	var operations []operation
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		op, err := parseOperation(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		op.line = line
+
+		operations = append(operations, op)
+	}
+
+	return operations, nil
+}
+
+func parseOperation(record []string) (operation, error) {
+	op := operation{kind: record[0], comment: record[4]}
+
+	var err error
+	op.payeeID, err = uuid.Parse(record[1])
+	if err != nil {
+		return op, fmt.Errorf("payee_id: %w", err)
+	}
+
+	op.amount, err = strconv.ParseFloat(record[3], 64)
+	if err != nil {
+		return op, fmt.Errorf("amount: %w", err)
+	}
+
+	switch op.kind {
+	case domain.Debet:
+		err = validator.Validate(dto.Debet{UserID: op.payeeID, Amount: op.amount, Comment: op.comment})
+	case domain.Credit:
+		err = validator.Validate(dto.Credit{UserID: op.payeeID, Amount: op.amount, Comment: op.comment})
+	case domain.Transfer:
+		op.payerID, err = uuid.Parse(record[2])
+		if err != nil {
+			return op, fmt.Errorf("payer_id: %w", err)
+		}
+
+		err = validator.Validate(dto.Transfer{PayeeID: op.payeeID, PayerID: op.payerID, Amount: op.amount, Comment: op.comment})
+	default:
+		return op, fmt.Errorf("unknown type %q", op.kind)
+	}
+
+	return op, err
+}
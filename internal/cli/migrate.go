@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"bank/internal/app"
+	"bank/migration"
+	"context"
+	"os"
+)
+
+func migrate(ctx context.Context, args []string) error {
+	flags := newFlagSet("migrate [flags] up|down|status|redo|version|create NAME")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return usageError{message: "missing subcommand"}
+	}
+
+	migration.SetOutput(os.Stdout)
+
+	command, rest := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "create":
+		if len(rest) != 1 {
+			return usageError{message: "create expects a migration NAME"}
+		}
+
+		return migration.Create(rest[0])
+	case "up", "down", "status", "redo", "version":
+		if len(rest) != 0 {
+			return usageError{message: "unexpected arguments"}
+		}
+	default:
+		return usageError{message: "unknown subcommand " + command}
+	}
+
+	conf, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+
+	db, err := migration.Open(app.PostgresConfig(conf.Postgres).String())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return migration.Run(db, command)
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"bank/internal/service"
+	"bank/internal/storage"
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// reconcile fails when any balance differs from the sum of its transactions, so it can run as a periodic check.
+func reconcile(ctx context.Context, args []string) error {
+	flags := newFlagSet("reconcile [flags]")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return usageError{message: "unexpected arguments"}
+	}
+
+	pool, _, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	reconciliations, err := service.NewAccountService(storage.NewBalanceStorage(pool)).Reconcile(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(reconciliations) == 0 {
+		fmt.Println("all balances match their transactions")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "USER_ID\tBALANCE\tLEDGER\tDIFFERENCE")
+	for _, reconciliation := range reconciliations {
+		fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%.2f\n", reconciliation.UserID, reconciliation.Balance, reconciliation.Ledger,
+			reconciliation.Balance-reconciliation.Ledger)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%d accounts do not match their transactions", len(reconciliations))
+}
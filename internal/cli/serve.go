@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"bank/internal/app"
+	"context"
+)
+
+func serve(ctx context.Context, args []string) error {
+	flags := newFlagSet("serve [flags]")
+	bindEnv(flags, "addr", "SERVER_ADDR", "HTTP listen `address`")
+	bindEnv(flags, "grpc-addr", "GRPC_ADDR", "gRPC listen `address`")
+	bindEnv(flags, "storage", "STORAGE_BACKEND", "storage `backend`: postgres or memory")
+	bindEnvBool(flags, "skip-migrate", "SERVER_SKIP_MIGRATE", "do not apply migrations on start")
+	if err := parse(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return usageError{message: "unexpected arguments"}
+	}
+
+	conf, err := loadConfig(true)
+	if err != nil {
+		return err
+	}
+
+	return app.New(conf).Run(ctx)
+}
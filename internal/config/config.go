@@ -2,33 +2,172 @@ package config
 
 import (
 	"github.com/ilyakaznacheev/cleanenv"
+	"os"
+	"time"
 )
 
+// Config is read from the file named by CONFIG_FILE (YAML, TOML, JSON or .env) when it is set,
+// then from the environment, which takes precedence over the file.
 type Config struct {
-	Server   Server
-	Postgres Postgres
-	APILayer APILayer
+	Server    Server    `yaml:"server" toml:"server"`
+	Storage   Storage   `yaml:"storage" toml:"storage"`
+	Postgres  Postgres  `yaml:"postgres" toml:"postgres"`
+	Partition Partition `yaml:"partition" toml:"partition"`
+	Snapshot  Snapshot  `yaml:"snapshot" toml:"snapshot"`
+	APILayer  APILayer  `yaml:"apilayer" toml:"apilayer"`
+	Auth      Auth      `yaml:"auth" toml:"auth"`
+	Outbox    Outbox    `yaml:"outbox" toml:"outbox"`
+	Webhook   Webhook   `yaml:"webhook" toml:"webhook"`
+	Log       Log       `yaml:"log" toml:"log"`
+	Tracing   Tracing   `yaml:"tracing" toml:"tracing"`
+	Health    Health    `yaml:"health" toml:"health"`
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
 }
 
 type Server struct {
-	Addr string `env:"SERVER_ADDR"`
+	Addr     string `yaml:"addr" toml:"addr" env:"SERVER_ADDR" env-default:":8080"`
+	GRPCAddr string `yaml:"grpc_addr" toml:"grpc_addr" env:"GRPC_ADDR" env-default:":9090"`
+
+	ReadTimeout  time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"SERVER_READ_TIMEOUT" env-default:"10s"`
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" env-default:"0s"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" env-default:"60s"`
+	BodyLimit    int           `yaml:"body_limit" toml:"body_limit" env:"SERVER_BODY_LIMIT" env-default:"1048576"`
+
+	// ShutdownDelay keeps serving after /readyz turned unready, so load balancers stop routing before the
+	// listeners close.
+	ShutdownDelay   time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay" env:"SERVER_SHUTDOWN_DELAY" env-default:"5s"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" env-default:"30s"`
+
+	// SkipMigrate leaves the schema alone on start, for deployments that run `bank migrate up` as a separate job.
+	SkipMigrate bool `yaml:"skip_migrate" toml:"skip_migrate" env:"SERVER_SKIP_MIGRATE" env-default:"false"`
+}
+
+// Storage selects where the data is kept. The memory backend needs no database but loses everything on exit,
+// and does not deliver webhooks.
+type Storage struct {
+	Backend string `yaml:"backend" toml:"backend" env:"STORAGE_BACKEND" env-default:"postgres"`
+}
+
+// RateLimit limits are "<count>/<s|m|h>[,<burst>]", empty means unlimited. Routes are keyed by
+// "METHOD /path" for HTTP and "GRPC /package.Service/Method" for gRPC.
+type RateLimit struct {
+	Backend string            `yaml:"backend" toml:"backend" env:"RATE_LIMIT_BACKEND" env-default:"memory"`
+	Default string            `yaml:"default" toml:"default" env:"RATE_LIMIT_DEFAULT" env-default:"50/s,100"`
+	Routes  map[string]string `yaml:"routes" toml:"routes" env:"RATE_LIMIT_ROUTES" env-separator:";" env-default:"POST /api/v1/balance/transfer:5/s,10;GRPC /bank.v1.BalanceService/Transfer:5/s,10"`
+	Account string            `yaml:"account" toml:"account" env:"RATE_LIMIT_ACCOUNT" env-default:"10/s,20"`
+}
+
+type Health struct {
+	Timeout    time.Duration `yaml:"timeout" toml:"timeout" env:"HEALTH_TIMEOUT" env-default:"2s"`
+	CheckRates bool          `yaml:"check_rates" toml:"check_rates" env:"HEALTH_CHECK_RATES" env-default:"false"`
+}
+
+type Log struct {
+	Level string `yaml:"level" toml:"level" env:"LOG_LEVEL" env-default:"info"`
+}
+
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER" env-default:"none"`
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint" env:"TRACING_ENDPOINT" env-default:"localhost:4317"`
+	Insecure    bool    `yaml:"insecure" toml:"insecure" env:"TRACING_INSECURE" env-default:"true"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" env-default:"1"`
+	ServiceName string  `yaml:"service_name" toml:"service_name" env:"TRACING_SERVICE_NAME" env-default:"bank"`
 }
 
 type Postgres struct {
-	Host     string `env:"POSTGRES_HOST"`
-	Port     string `env:"POSTGRES_PORT"`
-	User     string `env:"POSTGRES_USER"`
-	Password string `env:"POSTGRES_PASSWORD"`
-	DB       string `env:"POSTGRES_DB"`
+	Host     string `yaml:"host" toml:"host" env:"POSTGRES_HOST"`
+	Port     string `yaml:"port" toml:"port" env:"POSTGRES_PORT" env-default:"5432"`
+	User     string `yaml:"user" toml:"user" env:"POSTGRES_USER"`
+	Password string `yaml:"password" toml:"password" env:"POSTGRES_PASSWORD"`
+	DB       string `yaml:"db" toml:"db" env:"POSTGRES_DB"`
+
+	SSLMode     string `yaml:"sslmode" toml:"sslmode" env:"POSTGRES_SSLMODE" env-default:"disable"`
+	SSLRootCert string `yaml:"sslrootcert" toml:"sslrootcert" env:"POSTGRES_SSLROOTCERT"`
+
+	MaxConns          int32         `yaml:"max_conns" toml:"max_conns" env:"POSTGRES_MAX_CONNS" env-default:"10"`
+	MinConns          int32         `yaml:"min_conns" toml:"min_conns" env:"POSTGRES_MIN_CONNS" env-default:"0"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime" toml:"max_conn_lifetime" env:"POSTGRES_MAX_CONN_LIFETIME" env-default:"1h"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time" toml:"max_conn_idle_time" env:"POSTGRES_MAX_CONN_IDLE_TIME" env-default:"30m"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period" toml:"health_check_period" env:"POSTGRES_HEALTH_CHECK_PERIOD" env-default:"1m"`
+	StatementTimeout  time.Duration `yaml:"statement_timeout" toml:"statement_timeout" env:"POSTGRES_STATEMENT_TIMEOUT" env-default:"30s"`
+
+	// IsolationLevel is the default of the transactions started by the service.
+	IsolationLevel string `yaml:"isolation_level" toml:"isolation_level" env:"POSTGRES_ISOLATION_LEVEL" env-default:"read committed"`
+
+	// Transactions aborted by a serialization failure or a deadlock are run again up to RetryMaxAttempts times,
+	// while the RetryBudget shared by all of them lasts.
+	RetryMaxAttempts int           `yaml:"retry_max_attempts" toml:"retry_max_attempts" env:"POSTGRES_RETRY_MAX_ATTEMPTS" env-default:"3"`
+	RetryBackoff     time.Duration `yaml:"retry_backoff" toml:"retry_backoff" env:"POSTGRES_RETRY_BACKOFF" env-default:"10ms"`
+	RetryMaxBackoff  time.Duration `yaml:"retry_max_backoff" toml:"retry_max_backoff" env:"POSTGRES_RETRY_MAX_BACKOFF" env-default:"200ms"`
+	RetryBudget      int           `yaml:"retry_budget" toml:"retry_budget" env:"POSTGRES_RETRY_BUDGET" env-default:"100"`
+
+	// ReplicaDSN is the connection string of a read replica, empty reads everything from the primary. Its pool
+	// takes the settings above, and it is pinged every ReplicaCheckInterval to fall back to the primary while down.
+	ReplicaDSN           string        `yaml:"replica_dsn" toml:"replica_dsn" env:"POSTGRES_REPLICA_DSN"`
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval" toml:"replica_check_interval" env:"POSTGRES_REPLICA_CHECK_INTERVAL" env-default:"5s"`
+}
+
+// Partition sets up the monthly partitions of the transaction table: the service creates them Ahead months in
+// advance every Interval, and `bank archive` moves those older than Retention months to the archive tables, in
+// ArchiveTablespace when it is set.
+type Partition struct {
+	Ahead             int           `yaml:"ahead" toml:"ahead" env:"PARTITION_AHEAD" env-default:"3"`
+	Interval          time.Duration `yaml:"interval" toml:"interval" env:"PARTITION_INTERVAL" env-default:"1h"`
+	Retention         int           `yaml:"retention" toml:"retention" env:"PARTITION_RETENTION" env-default:"12"`
+	ArchiveTablespace string        `yaml:"archive_tablespace" toml:"archive_tablespace" env:"PARTITION_ARCHIVE_TABLESPACE"`
+}
+
+// Snapshot sets up the daily closing balances: every Interval the service snapshots the UTC days that ended at
+// least Settle ago, leaving transactions stamped before midnight the time to commit.
+type Snapshot struct {
+	Settle   time.Duration `yaml:"settle" toml:"settle" env:"SNAPSHOT_SETTLE" env-default:"5m"`
+	Interval time.Duration `yaml:"interval" toml:"interval" env:"SNAPSHOT_INTERVAL" env-default:"1h"`
 }
 
 type APILayer struct {
-	APIKey string `env:"APILAYER_APIKEY"`
+	APIKey  string        `yaml:"apikey" toml:"apikey" env:"APILAYER_APIKEY"`
+	Timeout time.Duration `yaml:"timeout" toml:"timeout" env:"APILAYER_TIMEOUT" env-default:"5s"`
+}
+
+type Auth struct {
+	Secret   string            `yaml:"secret" toml:"secret" env:"AUTH_SECRET"`
+	TokenTTL time.Duration     `yaml:"token_ttl" toml:"token_ttl" env:"AUTH_TOKEN_TTL" env-default:"1h"`
+	Policy   map[string]string `yaml:"policy" toml:"policy" env:"AUTH_POLICY" env-separator:";" env-default:"user:balance.get=own balance.transaction=own balance.transfer=own webhook.create=own webhook.select=own webhook.replay=own webhook.disable=own;service:balance.debet balance.credit;admin:*"`
+}
+
+type Outbox struct {
+	Sink     string        `yaml:"sink" toml:"sink" env:"OUTBOX_SINK" env-default:"none"`
+	File     string        `yaml:"file" toml:"file" env:"OUTBOX_FILE" env-default:"events.jsonl"`
+	Webhook  string        `yaml:"webhook_url" toml:"webhook_url" env:"OUTBOX_WEBHOOK_URL"`
+	Timeout  time.Duration `yaml:"webhook_timeout" toml:"webhook_timeout" env:"OUTBOX_WEBHOOK_TIMEOUT" env-default:"10s"`
+	Interval time.Duration `yaml:"interval" toml:"interval" env:"OUTBOX_INTERVAL" env-default:"1s"`
+	Batch    uint64        `yaml:"batch" toml:"batch" env:"OUTBOX_BATCH" env-default:"100"`
+	Lease    time.Duration `yaml:"lease" toml:"lease" env:"OUTBOX_LEASE" env-default:"1m"`
+}
+
+type Webhook struct {
+	Timeout     time.Duration `yaml:"timeout" toml:"timeout" env:"WEBHOOK_TIMEOUT" env-default:"10s"`
+	MaxAttempts int           `yaml:"max_attempts" toml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" env-default:"8"`
+	Backoff     time.Duration `yaml:"backoff" toml:"backoff" env:"WEBHOOK_BACKOFF" env-default:"5s"`
+	MaxBackoff  time.Duration `yaml:"max_backoff" toml:"max_backoff" env:"WEBHOOK_MAX_BACKOFF" env-default:"1h"`
+	Interval    time.Duration `yaml:"interval" toml:"interval" env:"WEBHOOK_INTERVAL" env-default:"1s"`
+	Batch       uint64        `yaml:"batch" toml:"batch" env:"WEBHOOK_BATCH" env-default:"50"`
 }
 
 func New() (Config, error) {
+	return Load(os.Getenv("CONFIG_FILE"))
+}
+
+// Load reads the config file at path, if any, and the environment. It does not validate the result.
+func Load(path string) (Config, error) {
 	var config Config
-	err := cleanenv.ReadEnv(&config)
+
+	var err error
+	if path != "" {
+		err = cleanenv.ReadConfig(path, &config)
+	} else {
+		err = cleanenv.ReadEnv(&config)
+	}
 	if err != nil {
 		return config, err
 	}
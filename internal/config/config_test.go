@@ -0,0 +1,221 @@
+package config_test
+
+import (
+	"bank/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func setRequired(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "localhost")
+	t.Setenv("POSTGRES_USER", "postgres")
+	t.Setenv("POSTGRES_DB", "bank")
+	t.Setenv("AUTH_SECRET", "secret")
+}
+
+func TestLoad_Env(t *testing.T) {
+	setRequired(t)
+	t.Setenv("POSTGRES_MAX_CONNS", "25")
+	t.Setenv("SERVER_READ_TIMEOUT", "3s")
+
+	conf, err := config.Load("")
+	require.NoError(t, err)
+	require.NoError(t, conf.Validate())
+
+	assert.Equal(t, int32(25), conf.Postgres.MaxConns)
+	assert.Equal(t, 3*time.Second, conf.Server.ReadTimeout)
+	assert.Equal(t, "disable", conf.Postgres.SSLMode)
+	assert.Equal(t, 30*time.Second, conf.Postgres.StatementTimeout)
+	assert.Equal(t, 5*time.Second, conf.Server.ShutdownDelay)
+	assert.Equal(t, "none", conf.Outbox.Sink)
+}
+
+// TestLoad_DocumentedPolicy checks that the AUTH_POLICY the README documents as the default is the default.
+func TestLoad_DocumentedPolicy(t *testing.T) {
+	readme, err := os.ReadFile(filepath.Join("..", "..", "README.md"))
+	require.NoError(t, err)
+
+	documented := regexp.MustCompile(`(?m)^AUTH_POLICY=(.*)$`).FindSubmatch(readme)
+	require.NotNil(t, documented, "README documents AUTH_POLICY")
+
+	setRequired(t)
+
+	defaults, err := config.Load("")
+	require.NoError(t, err)
+
+	t.Setenv("AUTH_POLICY", string(documented[1]))
+
+	conf, err := config.Load("")
+	require.NoError(t, err)
+	assert.Equal(t, defaults.Auth.Policy, conf.Auth.Policy)
+}
+
+func TestLoad_File(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": `
+server:
+  read_timeout: 5s
+  body_limit: 2048
+postgres:
+  host: db
+  user: bank
+  db: bank
+  max_conns: 20
+  statement_timeout: 1s
+auth:
+  secret: secret
+`,
+		"config.toml": `
+[server]
+read_timeout = "5s"
+body_limit = 2048
+
+[postgres]
+host = "db"
+user = "bank"
+db = "bank"
+max_conns = 20
+statement_timeout = "1s"
+
+[auth]
+secret = "secret"
+`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			// the environment overrides the file
+			t.Setenv("POSTGRES_MAX_CONNS", "30")
+
+			conf, err := config.Load(path)
+			require.NoError(t, err)
+			require.NoError(t, conf.Validate())
+
+			assert.Equal(t, 5*time.Second, conf.Server.ReadTimeout)
+			assert.Equal(t, 2048, conf.Server.BodyLimit)
+			assert.Equal(t, "db", conf.Postgres.Host)
+			assert.Equal(t, int32(30), conf.Postgres.MaxConns)
+			assert.Equal(t, time.Second, conf.Postgres.StatementTimeout)
+			assert.Equal(t, ":8080", conf.Server.Addr)
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr []string
+	}{
+		{
+			name: "ok",
+		},
+		{
+			name:    "unknown sslmode",
+			env:     map[string]string{"POSTGRES_SSLMODE": "on"},
+			wantErr: []string{"POSTGRES_SSLMODE"},
+		},
+		{
+			name:    "missing root cert",
+			env:     map[string]string{"POSTGRES_SSLROOTCERT": "/nonexistent/root.crt"},
+			wantErr: []string{"POSTGRES_SSLROOTCERT"},
+		},
+		{
+			name:    "min conns above max conns",
+			env:     map[string]string{"POSTGRES_MAX_CONNS": "2", "POSTGRES_MIN_CONNS": "5"},
+			wantErr: []string{"POSTGRES_MIN_CONNS"},
+		},
+		{
+			name:    "replica without check interval",
+			env:     map[string]string{"POSTGRES_REPLICA_DSN": "postgres://replica/bank", "POSTGRES_REPLICA_CHECK_INTERVAL": "0s"},
+			wantErr: []string{"POSTGRES_REPLICA_CHECK_INTERVAL"},
+		},
+		{
+			name:    "negative shutdown delay",
+			env:     map[string]string{"SERVER_SHUTDOWN_DELAY": "-1s"},
+			wantErr: []string{"SERVER_SHUTDOWN_DELAY"},
+		},
+		{
+			name:    "negative partition retention",
+			env:     map[string]string{"PARTITION_RETENTION": "-1"},
+			wantErr: []string{"PARTITION_RETENTION"},
+		},
+		{
+			name:    "zero snapshot interval",
+			env:     map[string]string{"SNAPSHOT_INTERVAL": "0s"},
+			wantErr: []string{"SNAPSHOT_INTERVAL"},
+		},
+		{
+			name:    "several errors",
+			env:     map[string]string{"SERVER_BODY_LIMIT": "-1", "TRACING_EXPORTER": "jaeger"},
+			wantErr: []string{"SERVER_BODY_LIMIT", "TRACING_EXPORTER"},
+		},
+		{
+			name:    "unknown storage backend",
+			env:     map[string]string{"STORAGE_BACKEND": "redis"},
+			wantErr: []string{"STORAGE_BACKEND"},
+		},
+		{
+			name: "memory storage skips postgres",
+			env:  map[string]string{"STORAGE_BACKEND": "memory", "POSTGRES_SSLMODE": "on"},
+		},
+		{
+			name:    "shared rate limits need postgres storage",
+			env:     map[string]string{"STORAGE_BACKEND": "memory", "RATE_LIMIT_BACKEND": "postgres"},
+			wantErr: []string{"RATE_LIMIT_BACKEND"},
+		},
+		{
+			name:    "webhook sink without url",
+			env:     map[string]string{"OUTBOX_SINK": "webhook"},
+			wantErr: []string{"OUTBOX_WEBHOOK_URL"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequired(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			conf, err := config.Load("")
+			require.NoError(t, err)
+
+			err = conf.Validate()
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			for _, want := range tt.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate_Required(t *testing.T) {
+	for _, key := range []string{"POSTGRES_HOST", "POSTGRES_USER", "POSTGRES_DB", "AUTH_SECRET"} {
+		// an empty variable counts as set, so restore it through t.Setenv and unset it for the test
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+
+	conf, err := config.Load("")
+	require.NoError(t, err)
+
+	err = conf.Validate()
+	require.Error(t, err)
+	for _, key := range []string{"POSTGRES_HOST", "POSTGRES_USER", "POSTGRES_DB", "AUTH_SECRET"} {
+		assert.Contains(t, err.Error(), key)
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) check(ok bool, format string, args ...any) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf(format, args...))
+	}
+}
+
+func (v *validator) oneOf(name, value string, values ...string) {
+	for _, allowed := range values {
+		if value == allowed {
+			return
+		}
+	}
+	v.errs = append(v.errs, fmt.Errorf("%s: %q must be one of %s", name, value, strings.Join(values, ", ")))
+}
+
+func (v *validator) err() error {
+	if len(v.errs) != 0 {
+		return fmt.Errorf("invalid config:\n%w", errors.Join(v.errs...))
+	}
+
+	return nil
+}
+
+// Validate reports every invalid setting at once, each prefixed with its environment variable.
+func (config Config) Validate() error {
+	v := &validator{}
+	check, oneOf := v.check, v.oneOf
+
+	check(config.Server.Addr != "", "SERVER_ADDR: is required")
+	check(config.Server.GRPCAddr != "", "GRPC_ADDR: is required")
+	check(config.Server.ReadTimeout >= 0, "SERVER_READ_TIMEOUT: must not be negative")
+	check(config.Server.WriteTimeout >= 0, "SERVER_WRITE_TIMEOUT: must not be negative")
+	check(config.Server.IdleTimeout >= 0, "SERVER_IDLE_TIMEOUT: must not be negative")
+	check(config.Server.BodyLimit > 0, "SERVER_BODY_LIMIT: must be positive")
+	check(config.Server.ShutdownDelay >= 0, "SERVER_SHUTDOWN_DELAY: must not be negative")
+	check(config.Server.ShutdownTimeout > 0, "SERVER_SHUTDOWN_TIMEOUT: must be positive")
+
+	oneOf("STORAGE_BACKEND", config.Storage.Backend, "postgres", "memory")
+	if config.Storage.Backend != "memory" {
+		config.Postgres.validate(v)
+		config.Partition.validate(v)
+	}
+
+	check(config.Snapshot.Settle >= 0, "SNAPSHOT_SETTLE: must not be negative")
+	check(config.Snapshot.Interval > 0, "SNAPSHOT_INTERVAL: must be positive")
+
+	check(config.APILayer.Timeout > 0, "APILAYER_TIMEOUT: must be positive")
+
+	check(config.Auth.Secret != "", "AUTH_SECRET: is required")
+	check(config.Auth.TokenTTL > 0, "AUTH_TOKEN_TTL: must be positive")
+
+	oneOf("OUTBOX_SINK", config.Outbox.Sink, "none", "stdout", "file", "webhook")
+	check(config.Outbox.Sink != "file" || config.Outbox.File != "", "OUTBOX_FILE: is required for the file sink")
+	check(config.Outbox.Sink != "webhook" || config.Outbox.Webhook != "", "OUTBOX_WEBHOOK_URL: is required for the webhook sink")
+	check(config.Outbox.Interval > 0, "OUTBOX_INTERVAL: must be positive")
+	check(config.Outbox.Batch > 0, "OUTBOX_BATCH: must be positive")
+	check(config.Outbox.Lease > 0, "OUTBOX_LEASE: must be positive")
+
+	check(config.Webhook.MaxAttempts > 0, "WEBHOOK_MAX_ATTEMPTS: must be positive")
+	check(config.Webhook.Backoff > 0, "WEBHOOK_BACKOFF: must be positive")
+	check(config.Webhook.MaxBackoff >= config.Webhook.Backoff, "WEBHOOK_MAX_BACKOFF: must not be less than WEBHOOK_BACKOFF")
+	check(config.Webhook.Interval > 0, "WEBHOOK_INTERVAL: must be positive")
+	check(config.Webhook.Batch > 0, "WEBHOOK_BATCH: must be positive")
+
+	oneOf("LOG_LEVEL", strings.ToLower(config.Log.Level), "debug", "info", "warn", "warning", "error")
+
+	oneOf("TRACING_EXPORTER", config.Tracing.Exporter, "none", "stdout", "otlp")
+	check(config.Tracing.SampleRatio >= 0 && config.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO: must be between 0 and 1")
+
+	check(config.Health.Timeout > 0, "HEALTH_TIMEOUT: must be positive")
+
+	oneOf("RATE_LIMIT_BACKEND", config.RateLimit.Backend, "memory", "postgres")
+	check(config.RateLimit.Backend != "postgres" || config.Storage.Backend == "postgres",
+		"RATE_LIMIT_BACKEND: postgres requires STORAGE_BACKEND=postgres")
+
+	return v.err()
+}
+
+// ValidatePostgres checks only the postgres settings, for commands that need nothing but the database.
+func (config Config) ValidatePostgres() error {
+	v := &validator{}
+	config.Postgres.validate(v)
+	config.Partition.validate(v)
+
+	return v.err()
+}
+
+func (postgres Postgres) validate(v *validator) {
+	check, oneOf := v.check, v.oneOf
+
+	check(postgres.Host != "", "POSTGRES_HOST: is required")
+	check(postgres.Port != "", "POSTGRES_PORT: is required")
+	check(postgres.User != "", "POSTGRES_USER: is required")
+	check(postgres.DB != "", "POSTGRES_DB: is required")
+	oneOf("POSTGRES_SSLMODE", postgres.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	if postgres.SSLRootCert != "" {
+		_, err := os.Stat(postgres.SSLRootCert)
+		check(err == nil, "POSTGRES_SSLROOTCERT: %v", err)
+	}
+	check(postgres.MaxConns > 0, "POSTGRES_MAX_CONNS: must be positive")
+	check(postgres.MinConns >= 0 && postgres.MinConns <= postgres.MaxConns,
+		"POSTGRES_MIN_CONNS: must be between 0 and POSTGRES_MAX_CONNS (%d)", postgres.MaxConns)
+	check(postgres.MaxConnLifetime > 0, "POSTGRES_MAX_CONN_LIFETIME: must be positive")
+	check(postgres.MaxConnIdleTime > 0, "POSTGRES_MAX_CONN_IDLE_TIME: must be positive")
+	check(postgres.HealthCheckPeriod > 0, "POSTGRES_HEALTH_CHECK_PERIOD: must be positive")
+	check(postgres.StatementTimeout >= 0, "POSTGRES_STATEMENT_TIMEOUT: must not be negative")
+	oneOf("POSTGRES_ISOLATION_LEVEL", postgres.IsolationLevel, "read committed", "serializable")
+	check(postgres.RetryMaxAttempts > 0, "POSTGRES_RETRY_MAX_ATTEMPTS: must be positive")
+	check(postgres.RetryBackoff >= 0, "POSTGRES_RETRY_BACKOFF: must not be negative")
+	check(postgres.RetryMaxBackoff >= postgres.RetryBackoff, "POSTGRES_RETRY_MAX_BACKOFF: must not be less than POSTGRES_RETRY_BACKOFF")
+	check(postgres.RetryBudget >= 0, "POSTGRES_RETRY_BUDGET: must not be negative")
+	if postgres.ReplicaDSN != "" {
+		check(postgres.ReplicaCheckInterval > 0, "POSTGRES_REPLICA_CHECK_INTERVAL: must be positive")
+	}
+}
+
+func (partition Partition) validate(v *validator) {
+	v.check(partition.Ahead >= 0, "PARTITION_AHEAD: must not be negative")
+	v.check(partition.Interval > 0, "PARTITION_INTERVAL: must be positive")
+	v.check(partition.Retention >= 0, "PARTITION_RETENTION: must not be negative")
+}
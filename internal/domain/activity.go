@@ -0,0 +1,15 @@
+package domain
+
+import "github.com/google/uuid"
+
+const (
+	ActivityBalance     string = "balance"
+	ActivityTransaction string = "transaction"
+)
+
+type Activity struct {
+	Kind        string       `json:"kind"`
+	AccountID   uuid.UUID    `json:"account_id"`
+	Balance     *Balance     `json:"balance,omitempty"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+}
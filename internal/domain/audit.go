@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"bank/internal/model"
+	"github.com/google/uuid"
+	"time"
+)
+
+type Audit struct {
+	ID            int64     `json:"id"`
+	Actor         string    `json:"actor"`
+	SourceIP      string    `json:"source_ip"`
+	RequestID     string    `json:"request_id"`
+	Operation     string    `json:"operation"`
+	AccountID     uuid.UUID `json:"account_id"`
+	BalanceBefore float64   `json:"balance_before"`
+	BalanceAfter  float64   `json:"balance_after"`
+	PayloadHash   string    `json:"payload_hash"`
+	PrevHash      string    `json:"prev_hash"`
+	Hash          string    `json:"hash"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int    `json:"checked"`
+	BrokenID *int64 `json:"broken_id,omitempty"`
+}
+
+func AuditFromModel(audit model.Audit) Audit {
+	return Audit{
+		ID:            audit.ID,
+		Actor:         audit.Actor,
+		SourceIP:      audit.SourceIP,
+		RequestID:     audit.RequestID,
+		Operation:     audit.Operation,
+		AccountID:     audit.AccountID,
+		BalanceBefore: float64(audit.BalanceBefore) / 100.0,
+		BalanceAfter:  float64(audit.BalanceAfter) / 100.0,
+		PayloadHash:   audit.PayloadHash,
+		PrevHash:      audit.PrevHash,
+		Hash:          audit.Hash,
+		CreatedAt:     audit.CreatedAt,
+	}
+}
+
+func AuditsFromModels(audits []model.Audit) []Audit {
+	res := make([]Audit, len(audits))
+
+	for i, audit := range audits {
+		res[i] = AuditFromModel(audit)
+	}
+
+	return res
+}
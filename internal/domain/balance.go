@@ -16,3 +16,29 @@ func BalanceFromModel(balance model.Balance) Balance {
 		Balance: float64(balance.Balance) / 100.0,
 	}
 }
+
+type Account struct {
+	Balance
+	Frozen bool `json:"frozen"`
+}
+
+func AccountFromModel(balance model.Balance) Account {
+	return Account{
+		Balance: BalanceFromModel(balance),
+		Frozen:  balance.Frozen,
+	}
+}
+
+type Reconciliation struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Balance float64   `json:"balance"`
+	Ledger  float64   `json:"ledger"`
+}
+
+func ReconciliationFromModel(reconciliation model.Reconciliation) Reconciliation {
+	return Reconciliation{
+		UserID:  reconciliation.UserID,
+		Balance: float64(reconciliation.Balance) / 100.0,
+		Ledger:  float64(reconciliation.Ledger) / 100.0,
+	}
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"bank/internal/model"
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	EventTransfer string = "balance.transfer"
+	EventDebet    string = "balance.debet"
+	EventCredit   string = "balance.credit"
+)
+
+type Event struct {
+	ID         uuid.UUID       `json:"id"`
+	Type       string          `json:"type"`
+	AccountIDs []uuid.UUID     `json:"account_ids"`
+	Payload    json.RawMessage `json:"payload"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type BalanceChanged struct {
+	Balances     []Balance     `json:"balances"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+// For returns the balances and transactions of accountID only, the part of the change its owner may see.
+func (changed BalanceChanged) For(accountID uuid.UUID) BalanceChanged {
+	scoped := BalanceChanged{Balances: []Balance{}, Transactions: []Transaction{}}
+	for _, balance := range changed.Balances {
+		if balance.UserID == accountID {
+			scoped.Balances = append(scoped.Balances, balance)
+		}
+	}
+	for _, transaction := range changed.Transactions {
+		if transaction.PayeeID == accountID {
+			scoped.Transactions = append(scoped.Transactions, transaction)
+		}
+	}
+
+	return scoped
+}
+
+func EventFromModel(event model.Event) Event {
+	return Event{
+		ID:         event.EventID,
+		Type:       event.Type,
+		AccountIDs: event.AccountIDs,
+		Payload:    event.Payload,
+		CreatedAt:  event.CreatedAt,
+	}
+}
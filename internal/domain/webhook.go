@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"bank/internal/model"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	DeliveryPending   string = "pending"
+	DeliveryDelivered string = "delivered"
+	DeliveryDead      string = "dead"
+)
+
+type WebhookSubscription struct {
+	ID         uuid.UUID  `json:"id"`
+	AccountID  *uuid.UUID `json:"account_id,omitempty"`
+	URL        string     `json:"url"`
+	Secret     string     `json:"secret,omitempty"`
+	EventTypes []string   `json:"event_types"`
+	Enabled    bool       `json:"enabled"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID  `json:"id"`
+	SubscriptionID uuid.UUID  `json:"subscription_id"`
+	EventID        uuid.UUID  `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// WebhookSubscriptionFromModel hides the signing secret, it is returned only once on creation.
+func WebhookSubscriptionFromModel(subscription model.WebhookSubscription) WebhookSubscription {
+	return WebhookSubscription{
+		ID:         subscription.ID,
+		AccountID:  subscription.AccountID,
+		URL:        subscription.URL,
+		EventTypes: subscription.EventTypes,
+		Enabled:    subscription.Enabled,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+func WebhookSubscriptionsFromModels(subscriptions []model.WebhookSubscription) []WebhookSubscription {
+	res := make([]WebhookSubscription, len(subscriptions))
+
+	for i, subscription := range subscriptions {
+		res[i] = WebhookSubscriptionFromModel(subscription)
+	}
+
+	return res
+}
+
+func WebhookDeliveryFromModel(delivery model.WebhookDelivery) WebhookDelivery {
+	return WebhookDelivery{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+}
+
+func WebhookDeliveriesFromModels(deliveries []model.WebhookDelivery) []WebhookDelivery {
+	res := make([]WebhookDelivery, len(deliveries))
+
+	for i, delivery := range deliveries {
+		res[i] = WebhookDeliveryFromModel(delivery)
+	}
+
+	return res
+}
@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"bank/pkg/validator"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// MaxAmount bounds the amount of a single operation, so balances stay far from the int64 limit in minor units.
+const MaxAmount = 1_000_000_000
+
+func init() {
+	validator.Register("amount", fmt.Sprintf("must be from 0.01 to %d with at most two decimal places", MaxAmount),
+		func(value reflect.Value) bool {
+			return ValidAmount(value.Float())
+		})
+}
+
+// ValidAmount reports whether amount is a whole number of kopecks from 0.01 up to MaxAmount. An amount with more
+// decimal places is rejected rather than rounded, so the operation is the one the client asked for.
+func ValidAmount(amount float64) bool {
+	return amount >= 0.01 && amount <= MaxAmount && float64(Minor(amount))/100 == amount
+}
+
+// Minor converts an amount to minor units (kopecks), rounding to the nearest one. Valid amounts are whole
+// kopecks, the rounding only absorbs the binary error of amounts such as 0.29.
+func Minor(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
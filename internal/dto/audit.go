@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"bank/pkg/sort"
+	"github.com/google/uuid"
+)
+
+type SelectAudit struct {
+	AccountID uuid.UUID `query:"account_id" json:"account_id"`
+	Actor     string    `query:"actor" json:"actor"`
+	Operation string    `query:"operation" json:"operation" validate:"omitempty,oneof=transfer debet credit"`
+	RequestID string    `query:"request_id" json:"request_id"`
+	From      string    `query:"from" json:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To        string    `query:"to" json:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Order     string    `query:"order" json:"order"`
+	sort.Pagination
+}
+
+func (request SelectAudit) GetOrder() string {
+	switch request.Order {
+	case "asc", "desc":
+		return request.Order
+	}
+
+	return "asc"
+}
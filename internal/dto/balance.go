@@ -3,6 +3,7 @@ package dto
 import (
 	"bank/pkg/sort"
 	"github.com/google/uuid"
+	"time"
 )
 
 type SelectTransactionFilter struct {
@@ -34,6 +35,16 @@ type GetBalance struct {
 	Currency string    `query:"currency"`
 }
 
+type GetBalanceAt struct {
+	UserID    uuid.UUID `query:"user_id"`
+	Timestamp string    `json:"timestamp" query:"timestamp" validate:"required,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+// At parses Timestamp in the layout of its validation, time.RFC3339.
+func (request GetBalanceAt) At() (time.Time, error) {
+	return time.Parse(time.RFC3339, request.Timestamp)
+}
+
 type SelectTransaction struct {
 	UserID uuid.UUID `query:"user_id"`
 	SelectTransactionFilter
@@ -42,18 +53,22 @@ type SelectTransaction struct {
 type Transfer struct {
 	PayeeID uuid.UUID `json:"payee_id" example:"a39c71f8-6d1b-466c-8367-ebd86764268b"`
 	PayerID uuid.UUID `json:"payer_id" example:"bcf4b5f7-8f73-4205-82e6-cf20e898a98a"`
-	Amount  float64   `json:"amount" validate:"gt=0" example:"100"`
+	Amount  float64   `json:"amount" validate:"amount" example:"100"`
 	Comment string    `json:"comment" example:"paid the debt"`
 }
 
 type Debet struct {
 	UserID  uuid.UUID `json:"user_id" example:"bcf4b5f7-8f73-4205-82e6-cf20e898a98a"`
-	Amount  float64   `json:"amount" validate:"gt=0" example:"100"`
+	Amount  float64   `json:"amount" validate:"amount" example:"100"`
 	Comment string    `json:"comment" example:"salary"`
 }
 
 type Credit struct {
 	UserID  uuid.UUID `json:"user_id" example:"bcf4b5f7-8f73-4205-82e6-cf20e898a98a"`
-	Amount  float64   `json:"amount" validate:"gt=0" example:"100"`
+	Amount  float64   `json:"amount" validate:"amount" example:"100"`
 	Comment string    `json:"comment" example:"took it from an ATM"`
 }
+
+type StreamBalance struct {
+	UserID uuid.UUID `query:"user_id"`
+}
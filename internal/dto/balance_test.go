@@ -0,0 +1,99 @@
+package dto_test
+
+import (
+	"bank/internal/dto"
+	"bank/pkg/validator"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMinor(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   int64
+	}{
+		{amount: 100, want: 10000},
+		{amount: 0.01, want: 1},
+		{amount: 0.29, want: 29},
+		{amount: 19.99, want: 1999},
+		{amount: 0.1 + 0.2, want: 30},
+		{amount: 0.004, want: 0},
+		{amount: dto.MaxAmount, want: dto.MaxAmount * 100},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, dto.Minor(test.amount), "%v", test.amount)
+	}
+}
+
+func TestGetBalanceAt_At(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		timestamp string
+		want      time.Time
+		wantErr   bool
+	}{
+		{timestamp: "2023-06-01T12:30:00Z", want: time.Date(2023, 6, 1, 12, 30, 0, 0, time.UTC)},
+		{timestamp: "2023-06-01T15:30:00.5+03:00", want: time.Date(2023, 6, 1, 12, 30, 0, 500_000_000, time.UTC)},
+		{timestamp: "2023-06-01", wantErr: true},
+		{timestamp: "yesterday", wantErr: true},
+		{timestamp: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		request := dto.GetBalanceAt{UserID: userID, Timestamp: test.timestamp}
+
+		at, err := request.At()
+		assert.Equal(t, test.wantErr, validator.Validate(request) != nil, "%q is validated as it is parsed", test.timestamp)
+		if test.wantErr {
+			assert.Error(t, err, test.timestamp)
+			continue
+		}
+
+		require.NoError(t, err, test.timestamp)
+		assert.True(t, test.want.Equal(at), "%q parsed as %s", test.timestamp, at)
+	}
+}
+
+// FuzzAmount checks that an amount accepted by the validation converts to a positive number of kopecks within the
+// limit, and that every amount of at least a kopeck up to the limit with at most two decimal places is accepted.
+func FuzzAmount(f *testing.F) {
+	for _, amount := range []string{"100", "0.29", "0.01", "0.005", "10.004", "19.99", "0.30000000000000004", "1e9", "1000000000.001", "1e300", "-1", "0", "-0", "1E-2", `"100"`, "null", "1.7976931348623157e308"} {
+		f.Add(amount)
+	}
+
+	f.Fuzz(func(t *testing.T, amount string) {
+		data := []byte(`{"amount":` + amount + `}`)
+
+		var debet dto.Debet
+		var credit dto.Credit
+		var transfer dto.Transfer
+		if json.Unmarshal(data, &debet) != nil {
+			return
+		}
+		require.NoError(t, json.Unmarshal(data, &credit))
+		require.NoError(t, json.Unmarshal(data, &transfer))
+		require.Equal(t, debet.Amount, credit.Amount)
+		require.Equal(t, debet.Amount, transfer.Amount)
+
+		valid := validator.Validate(debet) == nil
+		assert.Equal(t, valid, validator.Validate(credit) == nil)
+		assert.Equal(t, valid, validator.Validate(transfer) == nil)
+		_, decimals, _ := strings.Cut(strconv.FormatFloat(debet.Amount, 'f', -1, 64), ".")
+		assert.Equal(t, debet.Amount >= 0.01 && debet.Amount <= dto.MaxAmount && len(decimals) <= 2, valid, "amount %v", debet.Amount)
+
+		if valid {
+			minor := dto.Minor(debet.Amount)
+			assert.GreaterOrEqual(t, minor, int64(1))
+			assert.LessOrEqual(t, minor, int64(dto.MaxAmount*100))
+			assert.Equal(t, debet.Amount, float64(minor)/100)
+		}
+	})
+}
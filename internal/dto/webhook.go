@@ -0,0 +1,23 @@
+package dto
+
+import (
+	"bank/pkg/sort"
+	"github.com/google/uuid"
+)
+
+type CreateWebhookSubscription struct {
+	AccountID  *uuid.UUID `json:"account_id" example:"bcf4b5f7-8f73-4205-82e6-cf20e898a98a"`
+	URL        string     `json:"url" validate:"required,url" example:"https://partner.example/hooks/bank"`
+	EventTypes []string   `json:"event_types" validate:"dive,oneof=balance.transfer balance.debet balance.credit" example:"balance.transfer"`
+}
+
+type SelectWebhookSubscription struct {
+	AccountID uuid.UUID `query:"account_id" json:"account_id"`
+	sort.Pagination
+}
+
+type SelectWebhookDelivery struct {
+	SubscriptionID uuid.UUID `json:"id"`
+	Status         string    `query:"status" json:"status" validate:"omitempty,oneof=pending delivered dead"`
+	sort.Pagination
+}
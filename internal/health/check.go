@@ -0,0 +1,38 @@
+package health
+
+import (
+	"bank/migration"
+	"bank/pkg/postgres"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Postgres checks that a pooled connection can run a query.
+func Postgres(client postgres.Client) Check {
+	return func(ctx context.Context) error {
+		_, err := client.Exec(ctx, "SELECT 1")
+		return err
+	}
+}
+
+// Migrations checks that the database is at the latest embedded migration.
+func Migrations(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		latest, err := migration.Latest()
+		if err != nil {
+			return err
+		}
+
+		current, err := migration.Current(db)
+		if err != nil {
+			return err
+		}
+
+		if current != latest {
+			return fmt.Errorf("database is at version %d, expected %d", current, latest)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,102 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK           = "ok"
+	StatusFail         = "fail"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Check reports whether a dependency is usable.
+type Check func(ctx context.Context) error
+
+type CheckResult struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Latency string `json:"latency"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type Checker struct {
+	names        []string
+	checks       map[string]Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		checks:  make(map[string]Check),
+		timeout: timeout,
+	}
+}
+
+func (checker *Checker) Add(name string, check Check) *Checker {
+	checker.names = append(checker.names, name)
+	checker.checks[name] = check
+
+	return checker
+}
+
+// Shutdown makes every following Ready report unready, so load balancers stop routing here before the server stops.
+func (checker *Checker) Shutdown() {
+	checker.shuttingDown.Store(true)
+}
+
+// Ready runs all checks concurrently, each bounded by the checker timeout.
+func (checker *Checker) Ready(ctx context.Context) (Report, bool) {
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(checker.names)),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checker.timeout)
+	defer cancel()
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, name := range checker.names {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check(ctx)
+
+			result := CheckResult{Status: StatusOK, Latency: time.Since(start).String()}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			report.Checks[name] = result
+			mu.Unlock()
+		}(name, checker.checks[name])
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	if checker.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+
+	return report, report.Status == StatusOK
+}
@@ -0,0 +1,58 @@
+package health_test
+
+import (
+	"bank/internal/health"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestChecker_Ready(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	fail := func(ctx context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name     string
+		checker  *health.Checker
+		shutdown bool
+		want     string
+		failed   []string
+	}{
+		{name: "ok", checker: health.NewChecker(time.Second).Add("postgres", ok).Add("migrations", ok), want: health.StatusOK},
+		{name: "dependency down", checker: health.NewChecker(time.Second).Add("postgres", fail).Add("migrations", ok), want: health.StatusFail, failed: []string{"postgres"}},
+		{name: "dependency times out", checker: health.NewChecker(10*time.Millisecond).Add("apilayer", slow), want: health.StatusFail, failed: []string{"apilayer"}},
+		{name: "shutting down", checker: health.NewChecker(time.Second).Add("postgres", ok), shutdown: true, want: health.StatusShuttingDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.shutdown {
+				tt.checker.Shutdown()
+			}
+
+			report, ready := tt.checker.Ready(context.Background())
+			assert.Equal(t, tt.want, report.Status)
+			assert.Equal(t, tt.want == health.StatusOK, ready)
+
+			for name, result := range report.Checks {
+				failed := false
+				for _, f := range tt.failed {
+					failed = failed || f == name
+				}
+
+				if failed {
+					assert.Equal(t, health.StatusFail, result.Status, name)
+					assert.NotEmpty(t, result.Error, name)
+				} else {
+					assert.Equal(t, health.StatusOK, result.Status, name)
+				}
+			}
+		})
+	}
+}
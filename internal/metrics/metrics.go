@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"bank/pkg/apperror"
+	"errors"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const namespace = "bank"
+
+const (
+	Commit      = "commit"
+	Rollback    = "rollback"
+	CommitError = "commit_error"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "service",
+		Name:      "operations_total",
+		Help:      "Service operations by service, method and outcome.",
+	}, []string{"service", "method", "outcome"})
+
+	transactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "transactions_total",
+		Help:      "Database transactions by outcome: commit, rollback or commit_error.",
+	}, []string{"outcome"})
+
+	transactionRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "transaction_retries_total",
+		Help:      "Database transactions run again by the SQLSTATE that failed them.",
+	}, []string{"code"})
+
+	replicaHealthy = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "postgres",
+		Name:      "replica_healthy",
+		Help:      "1 while reads go to the read replica, 0 while they fall back to the primary.",
+	})
+
+	apiLayerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "apilayer",
+		Name:      "request_duration_seconds",
+		Help:      "apilayer call latency by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	apiLayerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "apilayer",
+		Name:      "errors_total",
+		Help:      "Failed apilayer calls by operation.",
+	}, []string{"operation"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		operations,
+		transactions,
+		transactionRetries,
+		replicaHealthy,
+		apiLayerDuration,
+		apiLayerErrors,
+	)
+}
+
+// Handler serves every registered metric in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+
+	httpRequests.With(labels).Inc()
+	httpDuration.With(labels).Observe(duration.Seconds())
+}
+
+func ObserveOperation(service, method string, err error) {
+	operations.WithLabelValues(service, method, Outcome(err)).Inc()
+}
+
+func ObserveTransaction(outcome string) {
+	transactions.WithLabelValues(outcome).Inc()
+}
+
+func ObserveTransactionRetry(code string) {
+	transactionRetries.WithLabelValues(code).Inc()
+}
+
+func SetReplicaHealthy(healthy bool) {
+	if healthy {
+		replicaHealthy.Set(1)
+	} else {
+		replicaHealthy.Set(0)
+	}
+}
+
+func ObserveAPILayer(operation string, duration time.Duration, err error) {
+	apiLayerDuration.WithLabelValues(operation).Observe(duration.Seconds())
+
+	if err != nil {
+		apiLayerErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// RegisterPool exports the connection pool statistics returned by stat on every scrape.
+func RegisterPool(stat func() *pgxpool.Stat) {
+	registry.MustRegister(poolCollector{stat: stat})
+}
+
+// Outcome labels an error by its apperror status, e.g. "ok", "not_found" or "internal_error".
+func Outcome(err error) string {
+	if err == nil {
+		return "ok"
+	}
+
+	var apperr apperror.Error
+	if !errors.As(err, &apperr) {
+		apperr = apperror.Unknown
+	}
+
+	return strings.ReplaceAll(apperr.Status, " ", "_")
+}
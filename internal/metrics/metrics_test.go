@@ -0,0 +1,61 @@
+package metrics_test
+
+import (
+	"bank/internal/metrics"
+	"bank/pkg/apperror"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "ok", err: nil, want: "ok"},
+		{name: "not found", err: apperror.NotFound, want: "not_found"},
+		{name: "wrapped bad request", err: apperror.BadRequest.WithMessage("insufficient funds"), want: "bad_request"},
+		{name: "foreign error", err: errors.New("boom"), want: "unknown_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, metrics.Outcome(tt.err))
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	metrics.ObserveRequest(http.MethodGet, "/api/v1/balance", http.StatusOK, 10*time.Millisecond)
+	metrics.ObserveOperation("balance", "Transfer", apperror.BadRequest)
+	metrics.ObserveTransaction(metrics.Rollback)
+	metrics.ObserveTransactionRetry("40001")
+	metrics.SetReplicaHealthy(true)
+	metrics.ObserveAPILayer("convert", time.Second, errors.New("timeout"))
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+
+	for _, series := range []string{
+		`bank_http_requests_total{method="GET",route="/api/v1/balance",status="200"} 1`,
+		`bank_http_request_duration_seconds_count{method="GET",route="/api/v1/balance",status="200"} 1`,
+		`bank_service_operations_total{method="Transfer",outcome="bad_request",service="balance"} 1`,
+		`bank_postgres_transactions_total{outcome="rollback"} 1`,
+		`bank_postgres_transaction_retries_total{code="40001"} 1`,
+		`bank_postgres_replica_healthy 1`,
+		`bank_apilayer_request_duration_seconds_count{operation="convert"} 1`,
+		`bank_apilayer_errors_total{operation="convert"} 1`,
+	} {
+		assert.Contains(t, string(body), series)
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolAcquiredConns = prometheus.NewDesc(namespace+"_pgxpool_acquired_conns",
+		"Connections currently in use.", nil, nil)
+	poolIdleConns = prometheus.NewDesc(namespace+"_pgxpool_idle_conns",
+		"Idle connections.", nil, nil)
+	poolTotalConns = prometheus.NewDesc(namespace+"_pgxpool_total_conns",
+		"Total connections owned by the pool.", nil, nil)
+	poolMaxConns = prometheus.NewDesc(namespace+"_pgxpool_max_conns",
+		"Maximum size of the pool.", nil, nil)
+	poolAcquireCount = prometheus.NewDesc(namespace+"_pgxpool_acquire_total",
+		"Successful connection acquisitions.", nil, nil)
+	poolEmptyAcquireCount = prometheus.NewDesc(namespace+"_pgxpool_empty_acquire_total",
+		"Acquisitions that had to wait for a connection.", nil, nil)
+	poolCanceledAcquireCount = prometheus.NewDesc(namespace+"_pgxpool_canceled_acquire_total",
+		"Acquisitions canceled by their context.", nil, nil)
+	poolAcquireDuration = prometheus.NewDesc(namespace+"_pgxpool_acquire_duration_seconds_total",
+		"Total time spent acquiring connections.", nil, nil)
+)
+
+type poolCollector struct {
+	stat func() *pgxpool.Stat
+}
+
+func (collector poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredConns
+	ch <- poolIdleConns
+	ch <- poolTotalConns
+	ch <- poolMaxConns
+	ch <- poolAcquireCount
+	ch <- poolEmptyAcquireCount
+	ch <- poolCanceledAcquireCount
+	ch <- poolAcquireDuration
+}
+
+func (collector poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := collector.stat()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceledAcquireCount, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/google/uuid"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Audit struct {
+	ID            int64     `db:"id"`
+	Actor         string    `db:"actor"`
+	SourceIP      string    `db:"source_ip"`
+	RequestID     string    `db:"request_id"`
+	Operation     string    `db:"operation"`
+	AccountID     uuid.UUID `db:"account_id"`
+	BalanceBefore int64     `db:"balance_before"`
+	BalanceAfter  int64     `db:"balance_after"`
+	PayloadHash   string    `db:"payload_hash"`
+	PrevHash      string    `db:"prev_hash"`
+	Hash          string    `db:"hash"`
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+// Digest chains the entry to PrevHash, so editing or removing any entry breaks every hash after it.
+func (audit Audit) Digest() string {
+	fields := []string{
+		audit.PrevHash,
+		audit.Actor,
+		audit.SourceIP,
+		audit.RequestID,
+		audit.Operation,
+		audit.AccountID.String(),
+		strconv.FormatInt(audit.BalanceBefore, 10),
+		strconv.FormatInt(audit.BalanceAfter, 10),
+		audit.PayloadHash,
+		audit.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+
+	return hex.EncodeToString(sum[:])
+}
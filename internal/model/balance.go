@@ -5,4 +5,12 @@ import "github.com/google/uuid"
 type Balance struct {
 	UserID  uuid.UUID `db:"user_id"`
 	Balance int64     `db:"balance"`
+	Frozen  bool      `db:"frozen"`
+}
+
+// Reconciliation compares a stored balance with the sum of the transactions recorded for it.
+type Reconciliation struct {
+	UserID  uuid.UUID `db:"user_id"`
+	Balance int64     `db:"balance"`
+	Ledger  int64     `db:"ledger"`
 }
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type Event struct {
+	ID          int64       `db:"id"`
+	EventID     uuid.UUID   `db:"event_id"`
+	Type        string      `db:"type"`
+	AccountIDs  []uuid.UUID `db:"account_ids"`
+	Payload     []byte      `db:"payload"`
+	CreatedAt   time.Time   `db:"created_at"`
+	PublishedAt *time.Time  `db:"published_at"`
+	LeasedUntil *time.Time  `db:"leased_until"`
+}
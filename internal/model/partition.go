@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// Partition is a monthly partition of the transaction history, covering created_at from From up to To.
+type Partition struct {
+	Name     string    `db:"name"`
+	From     time.Time `db:"from"`
+	To       time.Time `db:"to"`
+	Archived bool      `db:"archived"`
+}
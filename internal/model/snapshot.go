@@ -0,0 +1,20 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Snapshot is the closing balance of an account on Day, a UTC day that ends at midnight of the next one.
+type Snapshot struct {
+	UserID    uuid.UUID `db:"user_id"`
+	Day       time.Time `db:"day"`
+	Balance   int64     `db:"balance"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Close is the end of the snapshot day. The balance covers the transactions created before it; one created exactly
+// at Close belongs to the next day and is left to the transactions summed from Close on.
+func (snapshot Snapshot) Close() time.Time {
+	return snapshot.Day.AddDate(0, 0, 1)
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type WebhookSubscription struct {
+	ID         uuid.UUID  `db:"id"`
+	AccountID  *uuid.UUID `db:"account_id"`
+	URL        string     `db:"url"`
+	Secret     string     `db:"secret"`
+	EventTypes []string   `db:"event_types"`
+	Enabled    bool       `db:"enabled"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID  `db:"id"`
+	SubscriptionID uuid.UUID  `db:"subscription_id"`
+	EventID        uuid.UUID  `db:"event_id"`
+	EventType      string     `db:"event_type"`
+	Payload        []byte     `db:"payload"`
+	Status         string     `db:"status"`
+	Attempts       int        `db:"attempts"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at"`
+	LastError      string     `db:"last_error"`
+	CreatedAt      time.Time  `db:"created_at"`
+	DeliveredAt    *time.Time `db:"delivered_at"`
+}
@@ -0,0 +1,88 @@
+package outbox
+
+import (
+	"bank/internal/domain"
+	"bank/internal/storage"
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Relay struct {
+	transactor    storage.Transactor
+	outboxStorage storage.OutboxStorage
+	sink          Sink
+	interval      time.Duration
+	batch         uint64
+	lease         time.Duration
+	log           *slog.Logger
+}
+
+func NewRelay(transactor storage.Transactor, outboxStorage storage.OutboxStorage, sink Sink, interval time.Duration, batch uint64, lease time.Duration, log *slog.Logger) *Relay {
+	return &Relay{
+		transactor:    transactor,
+		outboxStorage: outboxStorage,
+		sink:          sink,
+		interval:      interval,
+		batch:         batch,
+		lease:         lease,
+		log:           log,
+	}
+}
+
+// Run publishes pending events until ctx is done. A full batch is followed immediately by the next one,
+// otherwise the relay waits for interval.
+func (relay *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(relay.interval)
+	defer ticker.Stop()
+
+	for {
+		published, err := relay.Flush(ctx)
+		if err != nil && ctx.Err() == nil {
+			relay.log.ErrorContext(ctx, "outbox relay", slog.Any("error", err))
+		}
+
+		if err == nil && uint64(published) == relay.batch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush claims a batch of pending events, publishes them in order and marks the published ones. The sink runs
+// outside of any transaction, so a slow one holds neither row locks nor a connection. Flush stops at the first
+// failed event, which is claimed again with the rest of the batch once their lease expires.
+func (relay *Relay) Flush(ctx context.Context) (int, error) {
+	events, err := relay.outboxStorage.Claim(ctx, relay.batch, relay.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]int64, 0, len(events))
+
+	var publishErr error
+	for _, event := range events {
+		publishErr = relay.sink.Publish(ctx, domain.EventFromModel(event))
+		if publishErr != nil {
+			break
+		}
+
+		ids = append(ids, event.ID)
+	}
+
+	if len(ids) != 0 {
+		err = relay.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			return relay.outboxStorage.MarkPublished(ctx, ids)
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), publishErr
+}
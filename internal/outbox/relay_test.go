@@ -0,0 +1,163 @@
+package outbox_test
+
+import (
+	"bank/internal/domain"
+	"bank/internal/model"
+	"bank/internal/outbox"
+	"bank/internal/storage"
+	"bank/pkg/logger"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type failingSink struct {
+	failOn uuid.UUID
+	sent   []uuid.UUID
+}
+
+func (sink *failingSink) Publish(_ context.Context, event domain.Event) error {
+	if event.ID == sink.failOn {
+		return errors.New("sink is down")
+	}
+
+	sink.sent = append(sink.sent, event.ID)
+
+	return nil
+}
+
+type txKey struct{}
+
+// pendingStorage claims events and expects them to be marked published in a transaction of transactor.
+func pendingStorage(t *testing.T, events []model.Event) *storage.OutboxStorageMock {
+	return &storage.OutboxStorageMock{
+		ClaimFunc: func(ctx context.Context, limit uint64, lease time.Duration) ([]model.Event, error) {
+			return events, nil
+		},
+		MarkPublishedFunc: func(ctx context.Context, ids []int64) error {
+			assert.NotNil(t, ctx.Value(txKey{}), "marked in a transaction")
+			return nil
+		},
+	}
+}
+
+// transactor marks the context of its transactions and tells whether one is running.
+func transactor(running *bool) *storage.TransactorMock {
+	return &storage.TransactorMock{
+		WithTransactionFunc: func(ctx context.Context, fn func(ctx context.Context) error, opts ...storage.TxOption) error {
+			*running = true
+			defer func() { *running = false }()
+
+			return fn(context.WithValue(ctx, txKey{}, true))
+		},
+	}
+}
+
+func TestRelay_Flush(t *testing.T) {
+	events := []model.Event{
+		{ID: 1, EventID: uuid.New(), Type: domain.EventDebet, Payload: []byte(`{}`)},
+		{ID: 2, EventID: uuid.New(), Type: domain.EventCredit, Payload: []byte(`{}`)},
+		{ID: 3, EventID: uuid.New(), Type: domain.EventTransfer, Payload: []byte(`{}`)},
+	}
+
+	tests := []struct {
+		name      string
+		failOn    uuid.UUID
+		published int
+		marked    [][]int64
+		wantErr   bool
+	}{
+		{name: "all published", failOn: uuid.Nil, published: 3, marked: [][]int64{{1, 2, 3}}, wantErr: false},
+		{name: "stops at failed event", failOn: events[1].EventID, published: 1, marked: [][]int64{{1}}, wantErr: true},
+		{name: "first event failed", failOn: events[0].EventID, published: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inTx bool
+			failing := &failingSink{failOn: tt.failOn}
+			sink := &sinkFunc{publish: func(ctx context.Context, event domain.Event) error {
+				assert.False(t, inTx, "published outside of a transaction")
+				return failing.Publish(ctx, event)
+			}}
+			outboxStorage := pendingStorage(t, events)
+			relay := outbox.NewRelay(transactor(&inTx), outboxStorage, sink, time.Second, 100, time.Minute, logger.New(io.Discard, "info"))
+
+			published, err := relay.Flush(context.Background())
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.published, published)
+			assert.Len(t, failing.sent, tt.published)
+
+			require.Len(t, outboxStorage.ClaimCalls(), 1)
+			assert.Equal(t, uint64(100), outboxStorage.ClaimCalls()[0].Limit)
+			assert.Equal(t, time.Minute, outboxStorage.ClaimCalls()[0].Lease)
+
+			var marked [][]int64
+			for _, call := range outboxStorage.MarkPublishedCalls() {
+				marked = append(marked, call.Ids)
+			}
+			assert.Equal(t, tt.marked, marked)
+		})
+	}
+}
+
+type sinkFunc struct {
+	publish func(ctx context.Context, event domain.Event) error
+}
+
+func (sink *sinkFunc) Publish(ctx context.Context, event domain.Event) error {
+	return sink.publish(ctx, event)
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := outbox.NewWriterSink(&buf)
+
+	event := domain.Event{ID: uuid.New(), Type: domain.EventDebet, Payload: json.RawMessage(`{"balances":[]}`)}
+	require.NoError(t, sink.Publish(context.Background(), event))
+	require.NoError(t, sink.Publish(context.Background(), event))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var got domain.Event
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &got))
+	assert.Equal(t, event.ID, got.ID)
+}
+
+func TestWebhookSink(t *testing.T) {
+	event := domain.Event{ID: uuid.New(), Type: domain.EventCredit, Payload: json.RawMessage(`{}`)}
+
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "accepted", status: http.StatusNoContent, wantErr: false},
+		{name: "rejected", status: http.StatusInternalServerError, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, event.ID.String(), r.Header.Get("X-Event-ID"))
+				assert.Equal(t, event.Type, r.Header.Get("X-Event-Type"))
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := outbox.NewWebhookSink(server.Client(), server.URL).Publish(context.Background(), event)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
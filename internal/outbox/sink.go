@@ -0,0 +1,112 @@
+package outbox
+
+import (
+	"bank/internal/domain"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Sink delivers a single event. Delivery is at-least-once, so consumers must deduplicate by event id.
+type Sink interface {
+	Publish(ctx context.Context, event domain.Event) error
+}
+
+type writerSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewWriterSink writes every event as a JSON line to writer.
+func NewWriterSink(writer io.Writer) Sink {
+	return &writerSink{writer: writer}
+}
+
+// NewFileSink appends every event as a JSON line to the file at path.
+func NewFileSink(path string) (Sink, io.Closer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return NewWriterSink(file), file, nil
+}
+
+func (sink *writerSink) Publish(_ context.Context, event domain.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	_, err = sink.writer.Write(append(data, '\n'))
+
+	return err
+}
+
+type webhookSink struct {
+	http *http.Client
+	url  string
+}
+
+// NewWebhookSink POSTs every event as JSON to url and treats any non-2xx response as a failed delivery.
+func NewWebhookSink(client *http.Client, url string) Sink {
+	return &webhookSink{http: client, url: url}
+}
+
+func (sink *webhookSink) Publish(ctx context.Context, event domain.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Event-ID", event.ID.String())
+	request.Header.Set("X-Event-Type", event.Type)
+
+	response, err := sink.http.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook sink: unexpected status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+type fanoutSink struct {
+	sinks []Sink
+}
+
+// NewFanoutSink publishes every event to all sinks and fails if any of them fails; the relay then retries the
+// event on every sink, which at-least-once consumers already tolerate.
+func NewFanoutSink(sinks ...Sink) Sink {
+	return &fanoutSink{sinks: sinks}
+}
+
+func (sink *fanoutSink) Publish(ctx context.Context, event domain.Event) error {
+	for _, s := range sink.sinks {
+		if err := s.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
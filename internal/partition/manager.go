@@ -0,0 +1,144 @@
+// Package partition looks after the monthly partitions of the transaction table: it creates them before the
+// inserts need them and moves old ones to the archive.
+package partition
+
+import (
+	"bank/internal/model"
+	"bank/internal/storage"
+	"context"
+	"log/slog"
+	"time"
+)
+
+type Manager struct {
+	transactor       storage.Transactor
+	partitionStorage storage.PartitionStorage
+	ahead            int
+	interval         time.Duration
+	now              func() time.Time
+	log              *slog.Logger
+}
+
+// NewManager keeps partitions for the current month and the ahead months after it.
+func NewManager(transactor storage.Transactor, partitionStorage storage.PartitionStorage, ahead int, interval time.Duration, log *slog.Logger) *Manager {
+	return &Manager{
+		transactor:       transactor,
+		partitionStorage: partitionStorage,
+		ahead:            ahead,
+		interval:         interval,
+		now:              time.Now,
+		log:              log,
+	}
+}
+
+// Run creates the missing partitions right away and then every interval until ctx is done.
+func (manager *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(manager.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := manager.Ensure(ctx); err != nil && ctx.Err() == nil {
+			manager.log.ErrorContext(ctx, "create transaction partitions", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Ensure creates the partitions of the current month and the ahead months after it that do not exist yet. Months
+// with rows in the default partition, e.g. backdated imports or rows written while no partition was ahead, get
+// their partition too, except the archived ones: their late rows stay in the default partition.
+func (manager *Manager) Ensure(ctx context.Context) error {
+	month := startOfMonth(manager.now())
+
+	err := manager.partitionStorage.Create(ctx, month, month.AddDate(0, manager.ahead, 0))
+	if err != nil {
+		return err
+	}
+
+	months, err := manager.partitionStorage.Unpartitioned(ctx)
+	if err != nil || len(months) == 0 {
+		return err
+	}
+
+	partitions, err := manager.partitionStorage.Select(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, month := range months {
+		if archived(partitions, month) {
+			continue
+		}
+
+		// one statement per month, moving the rows locks the default partition only briefly
+		if err = manager.partitionStorage.Create(ctx, month, month); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func archived(partitions []model.Partition, month time.Time) bool {
+	for _, partition := range partitions {
+		if partition.Archived && !month.Before(partition.From) && month.Before(partition.To) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Expired returns the hot partitions that end before the month retention months ago, oldest first. The partition
+// of the current month is kept even with a retention of zero.
+func (manager *Manager) Expired(ctx context.Context, retention int) ([]model.Partition, error) {
+	partitions, err := manager.partitionStorage.Select(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	before := startOfMonth(manager.now()).AddDate(0, -retention, 0)
+
+	var expired []model.Partition
+	for _, partition := range partitions {
+		if !partition.Archived && !partition.To.After(before) {
+			expired = append(expired, partition)
+		}
+	}
+
+	return expired, nil
+}
+
+// Archive moves partitions to the archive, each in a transaction of its own so the transaction table is locked
+// only briefly. It returns the archived partitions, up to the one that failed.
+func (manager *Manager) Archive(ctx context.Context, partitions []model.Partition, tablespace string) ([]model.Partition, error) {
+	archived := make([]model.Partition, 0, len(partitions))
+
+	for _, partition := range partitions {
+		var moved model.Partition
+		err := manager.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			moved, err = manager.partitionStorage.Archive(ctx, partition, tablespace)
+
+			return err
+		})
+		if err != nil {
+			return archived, err
+		}
+
+		archived = append(archived, moved)
+	}
+
+	return archived, nil
+}
+
+func startOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
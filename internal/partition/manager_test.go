@@ -0,0 +1,135 @@
+package partition_test
+
+import (
+	"bank/internal/model"
+	"bank/internal/partition"
+	"bank/internal/pgtest"
+	"bank/internal/storage"
+	"bank/pkg/logger"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+// month returns the start of the UTC month offset months from the current one.
+func month(offset int) time.Time {
+	now := time.Now().UTC()
+
+	return time.Date(now.Year(), now.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func newPartition(offset int, archived bool) model.Partition {
+	from := month(offset)
+
+	return model.Partition{Name: "transaction_" + from.Format("2006_01"), From: from, To: month(offset + 1), Archived: archived}
+}
+
+func TestManager_Ensure(t *testing.T) {
+	partitionStorage := &storage.PartitionStorageMock{
+		CreateFunc: func(ctx context.Context, from, to time.Time) error { return nil },
+		UnpartitionedFunc: func(ctx context.Context) ([]time.Time, error) {
+			return []time.Time{}, nil
+		},
+	}
+
+	require.NoError(t, partition.NewManager(pgtest.Transactor(), partitionStorage, 3, time.Hour, logger.New(io.Discard, "info")).Ensure(context.Background()))
+
+	calls := partitionStorage.CreateCalls()
+	require.Len(t, calls, 1)
+	assert.Equal(t, month(0), calls[0].From)
+	assert.Equal(t, month(3), calls[0].To)
+	assert.Empty(t, partitionStorage.SelectCalls())
+}
+
+func TestManager_Ensure_Unpartitioned(t *testing.T) {
+	partitionStorage := &storage.PartitionStorageMock{
+		CreateFunc: func(ctx context.Context, from, to time.Time) error { return nil },
+		UnpartitionedFunc: func(ctx context.Context) ([]time.Time, error) {
+			return []time.Time{month(-14), month(-2)}, nil
+		},
+		SelectFunc: func(ctx context.Context) ([]model.Partition, error) {
+			return []model.Partition{newPartition(-14, true), newPartition(0, false)}, nil
+		},
+	}
+
+	require.NoError(t, partition.NewManager(pgtest.Transactor(), partitionStorage, 0, time.Hour, logger.New(io.Discard, "info")).Ensure(context.Background()))
+
+	calls := partitionStorage.CreateCalls()
+	require.Len(t, calls, 2, "the archived month keeps its late rows in the default partition")
+	assert.Equal(t, month(-2), calls[1].From)
+	assert.Equal(t, month(-2), calls[1].To)
+}
+
+func TestManager_Expired(t *testing.T) {
+	partitionStorage := &storage.PartitionStorageMock{
+		SelectFunc: func(ctx context.Context) ([]model.Partition, error) {
+			return []model.Partition{
+				newPartition(-14, true),
+				newPartition(-13, false),
+				newPartition(-12, false),
+				newPartition(-11, false),
+				newPartition(0, false),
+				newPartition(1, false),
+			}, nil
+		},
+	}
+	manager := partition.NewManager(pgtest.Transactor(), partitionStorage, 3, time.Hour, logger.New(io.Discard, "info"))
+
+	tests := []struct {
+		name      string
+		retention int
+		want      []model.Partition
+	}{
+		{
+			name:      "a year",
+			retention: 12,
+			want:      []model.Partition{newPartition(-13, false)},
+		},
+		{
+			name:      "nothing kept but the current month",
+			retention: 0,
+			want:      []model.Partition{newPartition(-13, false), newPartition(-12, false), newPartition(-11, false)},
+		},
+		{
+			name:      "nothing expired",
+			retention: 24,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := manager.Expired(context.Background(), tt.retention)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestManager_Archive(t *testing.T) {
+	errLocked := errors.New("lock timeout")
+	partitions := []model.Partition{newPartition(-14, false), newPartition(-13, false), newPartition(-12, false)}
+
+	partitionStorage := &storage.PartitionStorageMock{
+		ArchiveFunc: func(ctx context.Context, p model.Partition, tablespace string) (model.Partition, error) {
+			if p.Name == partitions[1].Name {
+				return p, errLocked
+			}
+
+			p.Name, p.Archived = "transaction_archive_"+p.From.Format("2006_01"), true
+
+			return p, nil
+		},
+	}
+	transactor := pgtest.Transactor()
+
+	archived, err := partition.NewManager(transactor, partitionStorage, 3, time.Hour, logger.New(io.Discard, "info")).Archive(context.Background(), partitions, "cold")
+	assert.ErrorIs(t, err, errLocked)
+	assert.Len(t, transactor.WithTransactionCalls(), 2, "every partition moves in its own transaction")
+	require.Len(t, archived, 1, "the partitions up to the failed one stay archived")
+	assert.True(t, archived[0].Archived)
+	assert.Equal(t, "cold", partitionStorage.ArchiveCalls()[0].Tablespace)
+}
@@ -0,0 +1,222 @@
+// Package pgtest gives tests disposable Postgres databases, and a transactor for the tests that do without one.
+//
+// Databases are created on the server at PG_TEST_DSN, a superuser URL, when it is set. Otherwise a throwaway server is started
+// from the initdb and pg_ctl binaries in PG_BIN or PATH, and stopped by Run. Tests are skipped when neither is
+// available.
+package pgtest
+
+import (
+	"bank/migration"
+	"bank/pkg/postgres"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	once     sync.Once
+	adminDSN string
+	startErr error
+	stop     func()
+)
+
+// Run runs the tests of a package and stops the server started for them, use it in TestMain:
+//
+//	func TestMain(m *testing.M) { os.Exit(pgtest.Run(m)) }
+func Run(m *testing.M) int {
+	code := m.Run()
+	if stop != nil {
+		stop()
+	}
+
+	return code
+}
+
+// DSN creates an empty database and returns its DSN. The database is dropped when the test ends.
+func DSN(t testing.TB) string {
+	t.Helper()
+
+	once.Do(func() {
+		adminDSN, stop, startErr = server()
+	})
+	if startErr != nil {
+		t.Fatalf("pgtest: %v", startErr)
+	}
+	if adminDSN == "" {
+		t.Skip("pgtest: set PG_TEST_DSN or put the postgres binaries in PATH to run database tests")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	admin, err := pgx.Connect(ctx, adminDSN)
+	if err != nil {
+		t.Fatalf("pgtest: connect: %v", err)
+	}
+	defer admin.Close(ctx)
+
+	name := fmt.Sprintf("test_%d_%d", time.Now().UnixNano(), os.Getpid())
+	if _, err = admin.Exec(ctx, "CREATE DATABASE "+name); err != nil {
+		t.Fatalf("pgtest: create database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		admin, err := pgx.Connect(ctx, adminDSN)
+		if err != nil {
+			t.Errorf("pgtest: connect: %v", err)
+			return
+		}
+		defer admin.Close(ctx)
+
+		if _, err = admin.Exec(ctx, "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)"); err != nil {
+			t.Errorf("pgtest: drop database: %v", err)
+		}
+	})
+
+	u, err := url.Parse(adminDSN)
+	if err != nil {
+		t.Fatalf("pgtest: %v", err)
+	}
+	u.Path = "/" + name
+
+	return u.String()
+}
+
+// Pool connects to a fresh database migrated to the latest version. The pool is closed when the test ends.
+func Pool(t testing.TB) postgres.Pool {
+	t.Helper()
+
+	dsn := DSN(t)
+
+	db, err := migration.Open(dsn)
+	if err != nil {
+		t.Fatalf("pgtest: %v", err)
+	}
+	defer db.Close()
+
+	migration.SetOutput(io.Discard)
+	if err = migration.Run(db, "up"); err != nil {
+		t.Fatalf("pgtest: migrate: %v", err)
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("pgtest: %v", err)
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "5432"
+	}
+	password, _ := u.User.Password()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pool, err := postgres.NewClient(ctx, postgres.Config{
+		Host:     u.Hostname(),
+		Port:     port,
+		User:     u.User.Username(),
+		Password: password,
+		DB:       strings.TrimPrefix(u.Path, "/"),
+		SSLMode:  u.Query().Get("sslmode"),
+	})
+	if err != nil {
+		t.Fatalf("pgtest: connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+// server returns the DSN of the admin database, or an empty DSN when no server can be used.
+func server() (string, func(), error) {
+	if dsn := os.Getenv("PG_TEST_DSN"); dsn != "" {
+		return dsn, nil, nil
+	}
+
+	initdb, err := lookPath("initdb")
+	if err != nil {
+		return "", nil, nil
+	}
+	pgctl, err := lookPath("pg_ctl")
+	if err != nil {
+		return "", nil, nil
+	}
+
+	dir, err := os.MkdirTemp("", "pgtest")
+	if err != nil {
+		return "", nil, err
+	}
+	data := filepath.Join(dir, "data")
+
+	out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("initdb: %w: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off", port, dir)
+	out, err = exec.Command(pgctl, "-D", data, "-o", options, "-l", filepath.Join(dir, "postgres.log"), "-w", "start").CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("pg_ctl start: %w: %s", err, out)
+	}
+
+	stop := func() {
+		_ = exec.Command(pgctl, "-D", data, "-m", "immediate", "-w", "stop").Run()
+		os.RemoveAll(dir)
+	}
+
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port), stop, nil
+}
+
+func lookPath(name string) (string, error) {
+	if bin := os.Getenv("PG_BIN"); bin != "" {
+		path := filepath.Join(bin, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	if path, err := exec.LookPath(name); err == nil {
+		return path, nil
+	}
+
+	// distributions keep the server binaries out of PATH
+	matches, _ := filepath.Glob("/usr/lib/postgresql/*/bin/" + name)
+	if len(matches) != 0 {
+		return matches[len(matches)-1], nil
+	}
+
+	return "", exec.ErrNotFound
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
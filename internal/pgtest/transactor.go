@@ -0,0 +1,16 @@
+package pgtest
+
+import (
+	"bank/internal/storage"
+	"context"
+)
+
+// Transactor runs the functions like the real transactor, for tests of code that starts transactions without a
+// database.
+func Transactor() *storage.TransactorMock {
+	return &storage.TransactorMock{
+		WithTransactionFunc: func(ctx context.Context, fn func(ctx context.Context) error, opts ...storage.TxOption) error {
+			return fn(ctx)
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second and holding at most Burst tokens.
+// The zero Limit means unlimited.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (limit Limit) Unlimited() bool {
+	return limit.Rate <= 0
+}
+
+// ParseLimit parses "<count>/<s|m|h>[,<burst>]", e.g. "5/s,10" or "300/m". The burst defaults to count.
+// An empty string is the unlimited Limit.
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Limit{}, nil
+	}
+
+	rate, burst, hasBurst := strings.Cut(value, ",")
+
+	count, unit, ok := strings.Cut(rate, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q: expected <count>/<unit>", value)
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(count), 64)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q: invalid count %q", value, count)
+	}
+
+	var per time.Duration
+	switch strings.TrimSpace(unit) {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("rate limit %q: unknown unit %q", value, unit)
+	}
+
+	limit := Limit{
+		Rate:  n / per.Seconds(),
+		Burst: int(math.Max(1, math.Ceil(n))),
+	}
+
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(strings.TrimSpace(burst))
+		if err != nil || limit.Burst < 1 {
+			return Limit{}, fmt.Errorf("rate limit %q: invalid burst %q", value, burst)
+		}
+	}
+
+	return limit, nil
+}
+
+// bucket is the token bucket arithmetic shared by the stores.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// take refills the bucket up to now and consumes a token if there is one. When there is none,
+// it returns how long until the next token arrives.
+func (b *bucket) take(limit Limit, now time.Time) (bool, time.Duration) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	}
+	b.updated = now
+	b.limit = limit
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, RetryAfter(b.tokens, limit)
+}
+
+// full reports whether the bucket would be refilled completely by now, making it equal to a new one.
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.updated).Seconds()*b.limit.Rate >= float64(b.limit.Burst)
+}
+
+// RetryAfter is the time until a bucket holding tokens has a whole token again.
+func RetryAfter(tokens float64, limit Limit) time.Duration {
+	return time.Duration(math.Ceil((1 - tokens) / limit.Rate * float64(time.Second)))
+}
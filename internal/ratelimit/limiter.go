@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"bank/pkg/apperror"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+)
+
+// Exceeded is returned when a bucket is empty. It unwraps to apperror.TooManyRequests.
+type Exceeded struct {
+	RetryAfter time.Duration
+}
+
+func (exceeded Exceeded) Error() string {
+	return exceeded.Unwrap().Error()
+}
+
+func (exceeded Exceeded) Unwrap() error {
+	return apperror.TooManyRequests.WithMessage(fmt.Sprintf("rate limit exceeded, retry in %d seconds", exceeded.Seconds()))
+}
+
+// Seconds is the Retry-After value: the delay rounded up to whole seconds.
+func (exceeded Exceeded) Seconds() int {
+	return int(math.Ceil(exceeded.RetryAfter.Seconds()))
+}
+
+type Limiter struct {
+	store    Store
+	routes   map[string]Limit
+	fallback Limit
+	account  Limit
+	log      *slog.Logger
+}
+
+// NewLimiter limits requests per route and caller and mutations per target account. routes maps
+// "METHOD /path" onto a limit spec (see ParseLimit), other routes share the fallback bucket of a caller.
+func NewLimiter(store Store, routes map[string]string, fallback string, account string, log *slog.Logger) (*Limiter, error) {
+	limiter := &Limiter{
+		store:  store,
+		routes: make(map[string]Limit, len(routes)),
+		log:    log,
+	}
+
+	for route, value := range routes {
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("route %q: %w", route, err)
+		}
+
+		limiter.routes[normalizeRoute(route)] = limit
+	}
+
+	var err error
+	limiter.fallback, err = ParseLimit(fallback)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter.account, err = ParseLimit(account)
+	if err != nil {
+		return nil, err
+	}
+
+	return limiter, nil
+}
+
+// AllowRequest takes a token for caller (a principal subject or an IP) on the route method+" "+path.
+func (limiter *Limiter) AllowRequest(ctx context.Context, method, path, caller string) error {
+	route := normalizeRoute(method + " " + path)
+
+	limit, ok := limiter.routes[route]
+	if !ok {
+		route, limit = "*", limiter.fallback
+	}
+
+	return limiter.take(ctx, "route:"+route+":"+caller, limit)
+}
+
+// AllowAccount takes a token for a mutation of the account, whoever performs it.
+func (limiter *Limiter) AllowAccount(ctx context.Context, accountID uuid.UUID) error {
+	return limiter.take(ctx, "account:"+accountID.String(), limiter.account)
+}
+
+// take fails open: a broken shared store must not take the whole API down with it.
+func (limiter *Limiter) take(ctx context.Context, key string, limit Limit) error {
+	if limit.Unlimited() {
+		return nil
+	}
+
+	allowed, retryAfter, err := limiter.store.Take(ctx, key, limit)
+	if err != nil {
+		limiter.log.WarnContext(ctx, "rate limit store", slog.String("key", key), slog.Any("error", err))
+		return nil
+	}
+
+	if !allowed {
+		return Exceeded{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+func normalizeRoute(route string) string {
+	method, path, _ := strings.Cut(strings.TrimSpace(route), " ")
+
+	return strings.ToUpper(method) + " " + strings.TrimRight(strings.TrimSpace(path), "/")
+}
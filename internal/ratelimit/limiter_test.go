@@ -0,0 +1,150 @@
+package ratelimit_test
+
+import (
+	"bank/internal/ratelimit"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"bank/pkg/logger"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    ratelimit.Limit
+		wantErr bool
+	}{
+		{name: "empty is unlimited", value: "", want: ratelimit.Limit{}},
+		{name: "per second with burst", value: "5/s,10", want: ratelimit.Limit{Rate: 5, Burst: 10}},
+		{name: "per minute", value: "120/m", want: ratelimit.Limit{Rate: 2, Burst: 120}},
+		{name: "fractional rate keeps burst of one", value: "0.5/h", want: ratelimit.Limit{Rate: 0.5 / 3600, Burst: 1}},
+		{name: "missing unit", value: "5", wantErr: true},
+		{name: "unknown unit", value: "5/d", wantErr: true},
+		{name: "zero count", value: "0/s", wantErr: true},
+		{name: "invalid burst", value: "5/s,0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ratelimit.ParseLimit(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLimiter_AllowRequest(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(),
+		map[string]string{"POST /api/v1/balance/transfer": "1/h,2"},
+		"1/h,3",
+		"",
+		logger.New(io.Discard, "info"),
+	)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// the route has its own bucket per caller
+	assert.NoError(t, limiter.AllowRequest(ctx, "POST", "/api/v1/balance/transfer/", "alice"))
+	assert.NoError(t, limiter.AllowRequest(ctx, "post", "/api/v1/balance/transfer", "alice"))
+
+	err = limiter.AllowRequest(ctx, "POST", "/api/v1/balance/transfer", "alice")
+	assert.ErrorIs(t, err, apperror.TooManyRequests)
+
+	var exceeded ratelimit.Exceeded
+	require.True(t, errors.As(err, &exceeded))
+	assert.InDelta(t, time.Hour.Seconds(), exceeded.RetryAfter.Seconds(), 1)
+	assert.Equal(t, 3600, exceeded.Seconds())
+
+	assert.NoError(t, limiter.AllowRequest(ctx, "POST", "/api/v1/balance/transfer", "bob"))
+
+	// other routes share the fallback bucket of the caller
+	assert.NoError(t, limiter.AllowRequest(ctx, "GET", "/api/v1/balance", "alice"))
+	assert.NoError(t, limiter.AllowRequest(ctx, "GET", "/api/v1/balance/transaction", "alice"))
+	assert.NoError(t, limiter.AllowRequest(ctx, "GET", "/api/v1/audit", "alice"))
+	assert.ErrorIs(t, limiter.AllowRequest(ctx, "GET", "/api/v1/balance", "alice"), apperror.TooManyRequests)
+}
+
+func TestLimiter_AllowAccount(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), nil, "", "1/h,1", logger.New(io.Discard, "info"))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	accountID := uuid.New()
+
+	assert.NoError(t, limiter.AllowAccount(ctx, accountID))
+	assert.ErrorIs(t, limiter.AllowAccount(ctx, accountID), apperror.TooManyRequests)
+	assert.NoError(t, limiter.AllowAccount(ctx, uuid.New()))
+
+	// unlimited routes never touch the store
+	assert.NoError(t, limiter.AllowRequest(ctx, "GET", "/api/v1/balance", "alice"))
+}
+
+func TestSharedStore(t *testing.T) {
+	limit := ratelimit.Limit{Rate: 2, Burst: 4}
+
+	tests := []struct {
+		name           string
+		take           func(ctx context.Context, key string, rate float64, burst int) (bool, float64, error)
+		wantAllowed    bool
+		wantRetryAfter time.Duration
+		wantErr        bool
+	}{
+		{
+			name: "allowed",
+			take: func(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+				return true, 2, nil
+			},
+			wantAllowed: true,
+		},
+		{
+			name: "empty bucket",
+			take: func(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+				return false, 0.5, nil
+			},
+			wantAllowed:    false,
+			wantRetryAfter: 250 * time.Millisecond,
+		},
+		{
+			name: "storage error",
+			take: func(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+				return false, 0, apperror.Internal
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rateLimitStorage := &storage.RateLimitStorageMock{
+				TakeFunc: tt.take,
+				SweepFunc: func(ctx context.Context) (int64, error) {
+					return 0, nil
+				},
+			}
+
+			allowed, retryAfter, err := ratelimit.NewSharedStore(rateLimitStorage, logger.New(io.Discard, "info")).Take(context.Background(), "key", limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SharedStore.Take() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			assert.Equal(t, tt.wantAllowed, allowed)
+			assert.Equal(t, tt.wantRetryAfter, retryAfter)
+
+			call := rateLimitStorage.TakeCalls()[0]
+			assert.Equal(t, limit.Rate, call.Rate)
+			assert.Equal(t, limit.Burst, call.Burst)
+		})
+	}
+}
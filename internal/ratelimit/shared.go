@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// BucketStorage persists token buckets, see storage.RateLimitStorage.
+type BucketStorage interface {
+	Take(ctx context.Context, key string, rate float64, burst int) (allowed bool, tokens float64, err error)
+	Sweep(ctx context.Context) (int64, error)
+}
+
+// SharedStore keeps buckets in the database, so all instances draw from the same buckets.
+type SharedStore struct {
+	storage BucketStorage
+	swept   atomic.Int64
+	log     *slog.Logger
+}
+
+func NewSharedStore(storage BucketStorage, log *slog.Logger) *SharedStore {
+	return &SharedStore{storage: storage, log: log}
+}
+
+func (store *SharedStore) Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	store.sweep(ctx)
+
+	allowed, tokens, err := store.storage.Take(ctx, key, limit.Rate, limit.Burst)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if !allowed {
+		return false, RetryAfter(tokens, limit), nil
+	}
+
+	return true, 0, nil
+}
+
+// sweep runs at most once per sweepInterval across concurrent callers.
+func (store *SharedStore) sweep(ctx context.Context) {
+	now := time.Now().UnixNano()
+	swept := store.swept.Load()
+	if now-swept < int64(sweepInterval) || !store.swept.CompareAndSwap(swept, now) {
+		return
+	}
+
+	if _, err := store.storage.Sweep(ctx); err != nil {
+		store.log.WarnContext(ctx, "rate limit sweep", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store keeps token buckets by key.
+type Store interface {
+	// Take consumes a token from the bucket at key, reporting whether one was available
+	// and, if not, how long until the next one is.
+	Take(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+// MemoryStore keeps buckets in process memory, so each instance limits on its own.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+	swept   time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// sweepInterval is how often buckets that have refilled completely are dropped.
+const sweepInterval = time.Minute
+
+func (store *MemoryStore) Take(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.now()
+	store.sweep(now)
+
+	b, ok := store.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		store.buckets[key] = b
+	}
+
+	allowed, retryAfter := b.take(limit, now)
+
+	return allowed, retryAfter, nil
+}
+
+func (store *MemoryStore) sweep(now time.Time) {
+	if now.Sub(store.swept) < sweepInterval {
+		return
+	}
+	store.swept = now
+
+	for key, b := range store.buckets {
+		if b.full(now) {
+			delete(store.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"bank/internal/domain"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"context"
+	"github.com/google/uuid"
+)
+
+// AccountService holds the operator tasks on accounts that are not exposed to API callers.
+type AccountService interface {
+	Show(ctx context.Context, userID uuid.UUID) (domain.Account, error)
+	Freeze(ctx context.Context, userID uuid.UUID, frozen bool) error
+	Reconcile(ctx context.Context) ([]domain.Reconciliation, error)
+}
+
+type accountService struct {
+	balanceStorage storage.BalanceStorage
+}
+
+func NewAccountService(balanceStorage storage.BalanceStorage) AccountService {
+	return &accountService{balanceStorage: balanceStorage}
+}
+
+func (service *accountService) Show(ctx context.Context, userID uuid.UUID) (domain.Account, error) {
+	balance, err := service.balanceStorage.Get(ctx, userID)
+	if err != nil {
+		if apperr, ok := apperror.Is(err, apperror.NotFound); ok {
+			return domain.Account{}, apperr.WithMessage("balance not found")
+		}
+		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+			return domain.Account{}, apperr.WithScope("accountService.Show")
+		}
+
+		return domain.Account{}, err
+	}
+
+	return domain.AccountFromModel(balance), nil
+}
+
+// Freeze blocks (or, with frozen false, unblocks) transfers, debets and credits of the account.
+func (service *accountService) Freeze(ctx context.Context, userID uuid.UUID, frozen bool) error {
+	err := service.balanceStorage.SetFrozen(ctx, userID, frozen)
+	if err != nil {
+		if apperr, ok := apperror.Is(err, apperror.NotFound); ok {
+			return apperr.WithMessage("balance not found")
+		}
+		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+			return apperr.WithScope("accountService.Freeze")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// Reconcile returns every account whose balance does not match the sum of its transactions.
+func (service *accountService) Reconcile(ctx context.Context) ([]domain.Reconciliation, error) {
+	reconciliations, err := service.balanceStorage.SelectUnreconciled(ctx)
+	if err != nil {
+		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+			return []domain.Reconciliation{}, apperr.WithScope("accountService.Reconcile")
+		}
+
+		return []domain.Reconciliation{}, err
+	}
+
+	res := make([]domain.Reconciliation, len(reconciliations))
+	for i, reconciliation := range reconciliations {
+		res[i] = domain.ReconciliationFromModel(reconciliation)
+	}
+
+	return res, nil
+}
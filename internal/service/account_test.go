@@ -0,0 +1,89 @@
+package service_test
+
+import (
+	"bank/internal/domain"
+	"bank/internal/model"
+	"bank/internal/service"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAccountService_Show(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name           string
+		balanceStorage *storage.BalanceStorageMock
+		response       domain.Account
+		wantErr        error
+	}{
+		{
+			name: "ok",
+			balanceStorage: &storage.BalanceStorageMock{
+				GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+					return model.Balance{UserID: userID, Balance: 1050, Frozen: true}, nil
+				},
+			},
+			response: domain.Account{Balance: domain.Balance{UserID: userID, Balance: 10.5}, Frozen: true},
+		},
+		{
+			name: "not found",
+			balanceStorage: &storage.BalanceStorageMock{
+				GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+					return model.Balance{}, apperror.NotFound
+				},
+			},
+			wantErr: apperror.NotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accountService := service.NewAccountService(tt.balanceStorage)
+
+			got, err := accountService.Show(context.Background(), userID)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.response, got)
+		})
+	}
+}
+
+func TestAccountService_Freeze(t *testing.T) {
+	userID := uuid.New()
+
+	var frozen []bool
+	balanceStorage := &storage.BalanceStorageMock{
+		SetFrozenFunc: func(ctx context.Context, id uuid.UUID, value bool) error {
+			if id != userID {
+				return apperror.NotFound
+			}
+			frozen = append(frozen, value)
+
+			return nil
+		},
+	}
+	accountService := service.NewAccountService(balanceStorage)
+
+	assert.NoError(t, accountService.Freeze(context.Background(), userID, true))
+	assert.NoError(t, accountService.Freeze(context.Background(), userID, false))
+	assert.ErrorIs(t, accountService.Freeze(context.Background(), uuid.New(), true), apperror.NotFound)
+	assert.Equal(t, []bool{true, false}, frozen)
+}
+
+func TestAccountService_Reconcile(t *testing.T) {
+	userID := uuid.New()
+
+	balanceStorage := &storage.BalanceStorageMock{
+		SelectUnreconciledFunc: func(ctx context.Context) ([]model.Reconciliation, error) {
+			return []model.Reconciliation{{UserID: userID, Balance: 1000, Ledger: 900}}, nil
+		},
+	}
+
+	got, err := service.NewAccountService(balanceStorage).Reconcile(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Reconciliation{{UserID: userID, Balance: 10, Ledger: 9}}, got)
+}
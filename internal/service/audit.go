@@ -0,0 +1,101 @@
+package service
+
+import (
+	"bank/internal/domain"
+	"bank/internal/dto"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"context"
+	"time"
+)
+
+const auditVerifyBatch = 1000
+
+type AuditService interface {
+	Select(ctx context.Context, request dto.SelectAudit) ([]domain.Audit, error)
+	Verify(ctx context.Context) (domain.AuditVerification, error)
+}
+
+type auditService struct {
+	auditStorage storage.AuditStorage
+}
+
+func NewAuditService(auditStorage storage.AuditStorage) AuditService {
+	return &auditService{
+		auditStorage: auditStorage,
+	}
+}
+
+func (service *auditService) Select(ctx context.Context, request dto.SelectAudit) ([]domain.Audit, error) {
+	filter := storage.AuditFilter{
+		AccountID: request.AccountID,
+		Actor:     request.Actor,
+		Operation: request.Operation,
+		RequestID: request.RequestID,
+		Order:     request.GetOrder(),
+		Count:     request.GetCount(),
+		Offset:    request.GetOffset(),
+	}
+
+	var err error
+	if request.From != "" {
+		filter.From, err = time.Parse(time.RFC3339, request.From)
+		if err != nil {
+			return []domain.Audit{}, apperror.BadRequest.WithError(err).WithMessage("from must be RFC 3339 timestamp")
+		}
+	}
+
+	if request.To != "" {
+		filter.To, err = time.Parse(time.RFC3339, request.To)
+		if err != nil {
+			return []domain.Audit{}, apperror.BadRequest.WithError(err).WithMessage("to must be RFC 3339 timestamp")
+		}
+	}
+
+	audits, err := service.auditStorage.Select(ctx, filter)
+	if err != nil {
+		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+			return []domain.Audit{}, apperr.WithScope("auditService.Select")
+		}
+
+		return []domain.Audit{}, err
+	}
+
+	return domain.AuditsFromModels(audits), nil
+}
+
+func (service *auditService) Verify(ctx context.Context) (domain.AuditVerification, error) {
+	verification := domain.AuditVerification{Valid: true}
+
+	var prevHash string
+	var afterID int64
+	for {
+		audits, err := service.auditStorage.Select(ctx, storage.AuditFilter{AfterID: afterID, Count: auditVerifyBatch})
+		if err != nil {
+			if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+				return verification, apperr.WithScope("auditService.Verify")
+			}
+
+			return verification, err
+		}
+
+		for _, audit := range audits {
+			if audit.PrevHash != prevHash || audit.Digest() != audit.Hash {
+				brokenID := audit.ID
+
+				verification.Valid = false
+				verification.BrokenID = &brokenID
+
+				return verification, nil
+			}
+
+			verification.Checked++
+			prevHash = audit.Hash
+			afterID = audit.ID
+		}
+
+		if len(audits) < auditVerifyBatch {
+			return verification, nil
+		}
+	}
+}
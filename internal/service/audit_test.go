@@ -0,0 +1,80 @@
+package service_test
+
+import (
+	"bank/internal/model"
+	"bank/internal/service"
+	"bank/internal/storage"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func chain(n int) []model.Audit {
+	audits := make([]model.Audit, n)
+
+	var prevHash string
+	for i := range audits {
+		audits[i] = model.Audit{
+			ID:            int64(i + 1),
+			Actor:         "admin",
+			Operation:     "debet",
+			AccountID:     uuid.New(),
+			BalanceBefore: int64(i * 100),
+			BalanceAfter:  int64((i + 1) * 100),
+			PrevHash:      prevHash,
+			CreatedAt:     time.Now(),
+		}
+		audits[i].Hash = audits[i].Digest()
+		prevHash = audits[i].Hash
+	}
+
+	return audits
+}
+
+func TestAuditService_Verify(t *testing.T) {
+	tampered := chain(3)
+	tampered[1].BalanceAfter = 1_000_000
+
+	unlinked := chain(3)
+	unlinked[2].PrevHash = ""
+	unlinked[2].Hash = unlinked[2].Digest()
+
+	tests := []struct {
+		name     string
+		audits   []model.Audit
+		valid    bool
+		brokenID int64
+	}{
+		{name: "empty", audits: nil, valid: true},
+		{name: "intact", audits: chain(3), valid: true},
+		{name: "tampered entry", audits: tampered, valid: false, brokenID: 2},
+		{name: "removed link", audits: unlinked, valid: false, brokenID: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditStorage := &storage.AuditStorageMock{
+				SelectFunc: func(ctx context.Context, filter storage.AuditFilter) ([]model.Audit, error) {
+					var res []model.Audit
+					for _, audit := range tt.audits {
+						if audit.ID > filter.AfterID {
+							res = append(res, audit)
+						}
+					}
+
+					return res, nil
+				},
+			}
+
+			got, err := service.NewAuditService(auditStorage).Verify(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.valid, got.Valid)
+
+			if !tt.valid {
+				assert.Equal(t, tt.brokenID, *got.BrokenID)
+			}
+		})
+	}
+}
@@ -1,38 +1,56 @@
 package service
 
 import (
+	"bank/internal/audit"
 	"bank/internal/domain"
 	"bank/internal/dto"
 	"bank/internal/model"
 	"bank/internal/storage"
 	"bank/pkg/apperror"
-	"bank/pkg/postgres"
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"slices"
 	"time"
 )
 
 type BalanceService interface {
 	Get(ctx context.Context, userID uuid.UUID) (domain.Balance, error)
+	GetAt(ctx context.Context, request dto.GetBalanceAt) (domain.Balance, error)
 	Transfer(ctx context.Context, request dto.Transfer) error
 	Debet(ctx context.Context, request dto.Debet) (domain.Balance, error)
 	Credit(ctx context.Context, request dto.Credit) (domain.Balance, error)
 	SelectTransaction(ctx context.Context, request dto.SelectTransaction) ([]domain.Transaction, error)
+	SelectTransactionAfter(ctx context.Context, userID uuid.UUID, afterID uuid.UUID) ([]domain.Transaction, error)
 }
 
 type balanceService struct {
+	transactor         storage.Transactor
 	balanceStorage     storage.BalanceStorage
 	transactionStorage storage.TransactionStorage
+	auditStorage       storage.AuditStorage
+	outboxStorage      storage.OutboxStorage
+	snapshotStorage    storage.SnapshotStorage
 }
 
 func NewBalanceService(
+	transactor storage.Transactor,
 	balanceStorage storage.BalanceStorage,
 	transactionStorage storage.TransactionStorage,
+	auditStorage storage.AuditStorage,
+	outboxStorage storage.OutboxStorage,
+	snapshotStorage storage.SnapshotStorage,
 ) BalanceService {
 	return &balanceService{
+		transactor:         transactor,
 		balanceStorage:     balanceStorage,
 		transactionStorage: transactionStorage,
+		auditStorage:       auditStorage,
+		outboxStorage:      outboxStorage,
+		snapshotStorage:    snapshotStorage,
 	}
 }
 
@@ -45,11 +63,58 @@ func (service *balanceService) Get(ctx context.Context, userID uuid.UUID) (domai
 	return domain.BalanceFromModel(balance), nil
 }
 
+// GetAt returns the balance of the account at the timestamp of request: the closing balance of the last day
+// snapshotted before it plus the transactions since.
+func (service *balanceService) GetAt(ctx context.Context, request dto.GetBalanceAt) (domain.Balance, error) {
+	at, err := request.At()
+	if err != nil {
+		return domain.Balance{}, apperror.BadRequest.WithError(err).WithMessage("timestamp must be RFC 3339 timestamp")
+	}
+
+	balance, err := service.get(ctx, request.UserID, false)
+	if err != nil {
+		return domain.Balance{}, err
+	}
+
+	// before any snapshot the balance is the sum of all the transactions up to at
+	balance.Balance = 0
+	var from time.Time
+
+	snapshot, err := service.snapshotStorage.GetBefore(ctx, balance.UserID, at)
+	if err != nil {
+		if !errors.Is(err, apperror.NotFound) {
+			if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+				return domain.Balance{}, apperr.WithScope("balanceService.GetAt")
+			}
+
+			return domain.Balance{}, err
+		}
+	} else {
+		// the snapshot stops right before Close and the sum starts at it, so each transaction is counted once
+		balance.Balance, from = snapshot.Balance, snapshot.Close()
+	}
+
+	sum, err := service.transactionStorage.Sum(ctx, balance.UserID, from, at)
+	if err != nil {
+		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+			return domain.Balance{}, apperr.WithScope("balanceService.GetAt")
+		}
+
+		return domain.Balance{}, err
+	}
+	balance.Balance += sum
+
+	return domain.BalanceFromModel(balance), nil
+}
+
 func (service *balanceService) Transfer(ctx context.Context, request dto.Transfer) error {
 	if request.PayerID == request.PayeeID {
 		return apperror.BadRequest.WithMessage("transfer is not possible with yourself")
 	}
 
+	// the checks ahead of the transaction decide about writes, a lagging replica could miss a balance just created
+	ctx = storage.WithReadYourWrites(ctx)
+
 	payerBalance, err := service.get(ctx, request.PayerID, false)
 	if err != nil {
 		return err
@@ -60,14 +125,31 @@ func (service *balanceService) Transfer(ctx context.Context, request dto.Transfe
 		return err
 	}
 
-	err = service.balanceStorage.WithTransaction(ctx, func(tx postgres.Client) error {
-		payerBalance.Balance -= int64(request.Amount * 100.0)
+	if err = checkFrozen(payerBalance, payeeBalance); err != nil {
+		return err
+	}
+
+	err = service.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		var locked []model.Balance
+		locked, err = service.lock(ctx, request.PayerID, request.PayeeID)
+		if err != nil {
+			return err
+		}
+		payerBalance, payeeBalance = locked[0], locked[1]
+
+		if err = checkFrozen(payerBalance, payeeBalance); err != nil {
+			return err
+		}
+
+		payerBefore, payeeBefore := payerBalance.Balance, payeeBalance.Balance
+
+		payerBalance.Balance -= dto.Minor(request.Amount)
 
 		if payerBalance.Balance < 0 {
 			return apperror.BadRequest.WithMessage("insufficient funds")
 		}
 
-		err = service.balanceStorage.AtomicUpdate(ctx, tx, payerBalance)
+		err = service.balanceStorage.Update(ctx, payerBalance)
 		if err != nil {
 			if apperr, ok := apperror.Is(err, apperror.Internal); ok {
 				return apperr.WithScope("balanceService.Transfer")
@@ -76,9 +158,9 @@ func (service *balanceService) Transfer(ctx context.Context, request dto.Transfe
 			return err
 		}
 
-		payeeBalance.Balance += int64(request.Amount * 100.0)
+		payeeBalance.Balance += dto.Minor(request.Amount)
 
-		err = service.balanceStorage.AtomicUpdate(ctx, tx, payeeBalance)
+		err = service.balanceStorage.Update(ctx, payeeBalance)
 		if err != nil {
 			if apperr, ok := apperror.Is(err, apperror.Internal); ok {
 				return apperr.WithScope("balanceService.Transfer")
@@ -89,12 +171,29 @@ func (service *balanceService) Transfer(ctx context.Context, request dto.Transfe
 
 		now := time.Now()
 
-		_, err = service.atomicRecord(ctx, tx, domain.Transfer, request.PayeeID, &request.PayerID, int64(request.Amount*100.0), request.Comment, now)
+		var payeeTransaction, payerTransaction model.Transaction
+		payeeTransaction, err = service.record(ctx, domain.Transfer, request.PayeeID, &request.PayerID, dto.Minor(request.Amount), request.Comment, now)
 		if err != nil {
 			return err
 		}
 
-		_, err = service.atomicRecord(ctx, tx, domain.Transfer, request.PayerID, &request.PayeeID, -int64(request.Amount*100.0), request.Comment, now)
+		payerTransaction, err = service.record(ctx, domain.Transfer, request.PayerID, &request.PayeeID, -dto.Minor(request.Amount), request.Comment, now)
+		if err != nil {
+			return err
+		}
+
+		err = service.audit(ctx, domain.Transfer, request.PayerID, payerBefore, payerBalance.Balance, now)
+		if err != nil {
+			return err
+		}
+
+		err = service.audit(ctx, domain.Transfer, request.PayeeID, payeeBefore, payeeBalance.Balance, now)
+		if err != nil {
+			return err
+		}
+
+		err = service.publish(ctx, domain.EventTransfer,
+			[]model.Balance{payerBalance, payeeBalance}, []model.Transaction{payerTransaction, payeeTransaction}, now)
 		if err != nil {
 			return err
 		}
@@ -113,15 +212,33 @@ func (service *balanceService) Transfer(ctx context.Context, request dto.Transfe
 }
 
 func (service *balanceService) Debet(ctx context.Context, request dto.Debet) (domain.Balance, error) {
+	ctx = storage.WithReadYourWrites(ctx)
+
 	balance, err := service.get(ctx, request.UserID, true)
 	if err != nil {
 		return domain.Balance{}, err
 	}
 
-	balance.Balance += int64(request.Amount * 100.0)
+	if err = checkFrozen(balance); err != nil {
+		return domain.Balance{}, err
+	}
 
-	err = service.balanceStorage.WithTransaction(ctx, func(tx postgres.Client) error {
-		err = service.balanceStorage.AtomicUpdate(ctx, tx, balance)
+	err = service.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		var locked []model.Balance
+		locked, err = service.lock(ctx, balance.UserID)
+		if err != nil {
+			return err
+		}
+		balance = locked[0]
+
+		if err = checkFrozen(balance); err != nil {
+			return err
+		}
+
+		before := balance.Balance
+		balance.Balance += dto.Minor(request.Amount)
+
+		err = service.balanceStorage.Update(ctx, balance)
 		if err != nil {
 			if apperr, ok := apperror.Is(err, apperror.Internal); ok {
 				return apperr.WithScope("balanceService.Debet")
@@ -132,7 +249,18 @@ func (service *balanceService) Debet(ctx context.Context, request dto.Debet) (do
 
 		now := time.Now()
 
-		_, err = service.atomicRecord(ctx, tx, domain.Debet, request.UserID, nil, int64(request.Amount*100.0), request.Comment, now)
+		var transaction model.Transaction
+		transaction, err = service.record(ctx, domain.Debet, request.UserID, nil, dto.Minor(request.Amount), request.Comment, now)
+		if err != nil {
+			return err
+		}
+
+		err = service.audit(ctx, domain.Debet, request.UserID, before, balance.Balance, now)
+		if err != nil {
+			return err
+		}
+
+		err = service.publish(ctx, domain.EventDebet, []model.Balance{balance}, []model.Transaction{transaction}, now)
 		if err != nil {
 			return err
 		}
@@ -151,19 +279,37 @@ func (service *balanceService) Debet(ctx context.Context, request dto.Debet) (do
 }
 
 func (service *balanceService) Credit(ctx context.Context, request dto.Credit) (domain.Balance, error) {
+	ctx = storage.WithReadYourWrites(ctx)
+
 	balance, err := service.get(ctx, request.UserID, false)
 	if err != nil {
 		return domain.Balance{}, err
 	}
 
-	balance.Balance -= int64(request.Amount * 100.0)
-
-	if balance.Balance < 0 {
-		return domain.Balance{}, apperror.BadRequest.WithMessage("insufficient funds")
+	if err = checkFrozen(balance); err != nil {
+		return domain.Balance{}, err
 	}
 
-	err = service.balanceStorage.WithTransaction(ctx, func(tx postgres.Client) error {
-		err = service.balanceStorage.AtomicUpdate(ctx, tx, balance)
+	err = service.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		var locked []model.Balance
+		locked, err = service.lock(ctx, balance.UserID)
+		if err != nil {
+			return err
+		}
+		balance = locked[0]
+
+		if err = checkFrozen(balance); err != nil {
+			return err
+		}
+
+		before := balance.Balance
+		balance.Balance -= dto.Minor(request.Amount)
+
+		if balance.Balance < 0 {
+			return apperror.BadRequest.WithMessage("insufficient funds")
+		}
+
+		err = service.balanceStorage.Update(ctx, balance)
 		if err != nil {
 			if apperr, ok := apperror.Is(err, apperror.Internal); ok {
 				return apperr.WithScope("balanceService.Credit")
@@ -174,7 +320,18 @@ func (service *balanceService) Credit(ctx context.Context, request dto.Credit) (
 
 		now := time.Now()
 
-		_, err = service.atomicRecord(ctx, tx, domain.Credit, request.UserID, nil, -int64(request.Amount*100.0), request.Comment, now)
+		var transaction model.Transaction
+		transaction, err = service.record(ctx, domain.Credit, request.UserID, nil, -dto.Minor(request.Amount), request.Comment, now)
+		if err != nil {
+			return err
+		}
+
+		err = service.audit(ctx, domain.Credit, request.UserID, before, balance.Balance, now)
+		if err != nil {
+			return err
+		}
+
+		err = service.publish(ctx, domain.EventCredit, []model.Balance{balance}, []model.Transaction{transaction}, now)
 		if err != nil {
 			return err
 		}
@@ -207,6 +364,26 @@ func (service *balanceService) SelectTransaction(ctx context.Context, request dt
 	return domain.TransactionsFromModels(transactions), nil
 }
 
+func (service *balanceService) SelectTransactionAfter(ctx context.Context, userID uuid.UUID, afterID uuid.UUID) ([]domain.Transaction, error) {
+	// afterID was seen live from the primary, the replica may not have it yet
+	ctx = storage.WithReadYourWrites(ctx)
+
+	transactions, err := service.transactionStorage.SelectAfter(ctx, userID, afterID)
+	if err != nil {
+		// the client has to start over from the current balance rather than miss what came after afterID
+		if apperr, ok := apperror.Is(err, apperror.NotFound); ok {
+			return []domain.Transaction{}, apperr.WithMessage("last event not found, reconnect without Last-Event-ID")
+		}
+		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+			return []domain.Transaction{}, apperr.WithScope("balanceService.SelectTransactionAfter")
+		}
+
+		return []domain.Transaction{}, err
+	}
+
+	return domain.TransactionsFromModels(transactions), nil
+}
+
 func (service *balanceService) get(ctx context.Context, userID uuid.UUID, forceCreate bool) (model.Balance, error) {
 	if userID == uuid.Nil {
 		userID = uuid.New()
@@ -244,6 +421,51 @@ func (service *balanceService) get(ctx context.Context, userID uuid.UUID, forceC
 	return balance, nil
 }
 
+// lock re-reads the balances in the transaction of ctx and locks their rows until it ends, so concurrent
+// operations on the same accounts apply one after another. Rows are locked in a fixed order to keep two opposite
+// transfers from deadlocking; the balances are returned in the order of userIDs.
+func (service *balanceService) lock(ctx context.Context, userIDs ...uuid.UUID) ([]model.Balance, error) {
+	order := slices.Clone(userIDs)
+	slices.SortFunc(order, func(a, b uuid.UUID) int {
+		return bytes.Compare(a[:], b[:])
+	})
+
+	locked := make(map[uuid.UUID]model.Balance, len(order))
+	for _, userID := range order {
+		balance, err := service.balanceStorage.GetForUpdate(ctx, userID)
+		if err != nil {
+			if apperr, ok := apperror.Is(err, apperror.NotFound); ok {
+				return nil, apperr.WithMessage("balance not found")
+			}
+			if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+				return nil, apperr.WithScope("balanceService.lock")
+			}
+
+			return nil, err
+		}
+
+		locked[userID] = balance
+	}
+
+	balances := make([]model.Balance, len(userIDs))
+	for i, userID := range userIDs {
+		balances[i] = locked[userID]
+	}
+
+	return balances, nil
+}
+
+// checkFrozen rejects any money movement touching a frozen account.
+func checkFrozen(balances ...model.Balance) error {
+	for _, balance := range balances {
+		if balance.Frozen {
+			return apperror.Forbidden.WithMessage(fmt.Sprintf("account %s is frozen", balance.UserID))
+		}
+	}
+
+	return nil
+}
+
 func (service *balanceService) record(ctx context.Context, transactionType string, payeeID uuid.UUID, payerID *uuid.UUID, amount int64, comment string, createdAt time.Time) (model.Transaction, error) {
 	transaction := model.Transaction{
 		ID:        uuid.New(),
@@ -266,24 +488,62 @@ func (service *balanceService) record(ctx context.Context, transactionType strin
 	return transaction, nil
 }
 
-func (service *balanceService) atomicRecord(ctx context.Context, tx postgres.Client, transactionType string, payeeID uuid.UUID, payerID *uuid.UUID, amount int64, comment string, createdAt time.Time) (model.Transaction, error) {
-	transaction := model.Transaction{
-		ID:        uuid.New(),
-		PayeeID:   payeeID,
-		PayerID:   payerID,
-		Type:      transactionType,
-		Amount:    amount,
-		Comment:   comment,
-		CreatedAt: createdAt,
+func (service *balanceService) audit(ctx context.Context, operation string, accountID uuid.UUID, before, after int64, createdAt time.Time) error {
+	metadata := audit.FromContext(ctx)
+
+	_, err := service.auditStorage.Create(ctx, model.Audit{
+		Actor:         metadata.Actor,
+		SourceIP:      metadata.SourceIP,
+		RequestID:     metadata.RequestID,
+		Operation:     operation,
+		AccountID:     accountID,
+		BalanceBefore: before,
+		BalanceAfter:  after,
+		PayloadHash:   metadata.PayloadHash,
+		CreatedAt:     createdAt,
+	})
+	if err != nil {
+		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+			return apperr.WithScope("balanceService.audit")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (service *balanceService) publish(ctx context.Context, eventType string, balances []model.Balance, transactions []model.Transaction, createdAt time.Time) error {
+	payload := domain.BalanceChanged{
+		Balances:     make([]domain.Balance, len(balances)),
+		Transactions: domain.TransactionsFromModels(transactions),
 	}
-	err := service.transactionStorage.AtomicCreate(ctx, tx, transaction)
+
+	accountIDs := make([]uuid.UUID, len(balances))
+	for i, balance := range balances {
+		payload.Balances[i] = domain.BalanceFromModel(balance)
+		accountIDs[i] = balance.UserID
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return apperror.Internal.WithError(err).WithScope("balanceService.publish")
+	}
+
+	err = service.outboxStorage.Create(ctx, model.Event{
+		EventID:    uuid.New(),
+		Type:       eventType,
+		AccountIDs: accountIDs,
+		Payload:    data,
+		CreatedAt:  createdAt,
+	})
 	if err != nil {
 		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
-			return transaction, apperr.WithScope("balanceService.record")
+			return apperr.WithScope("balanceService.publish")
 		}
 
-		return transaction, err
+		return err
 	}
 
-	return transaction, nil
+	return nil
 }
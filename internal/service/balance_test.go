@@ -7,14 +7,15 @@ import (
 	"bank/internal/service"
 	"bank/internal/storage"
 	"bank/pkg/apperror"
-	"bank/pkg/postgres"
 	"bank/pkg/sort"
 	"context"
 	"errors"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func DeepEqualWithZero(obj1, obj2 interface{}) bool {
@@ -84,7 +85,7 @@ func TestBalanceService_Get(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			balanceService := service.NewBalanceService(tt.balanceStorage, tt.transactionStorage)
+			balanceService := service.NewBalanceService(&storage.TransactorMock{}, tt.balanceStorage, tt.transactionStorage, &storage.AuditStorageMock{}, &storage.OutboxStorageMock{}, &storage.SnapshotStorageMock{})
 
 			got, err := balanceService.Get(context.Background(), tt.request.UserID)
 			if !DeepEqualWithZero(got, tt.response) {
@@ -98,12 +99,104 @@ func TestBalanceService_Get(t *testing.T) {
 	}
 }
 
+func TestBalanceService_GetAt(t *testing.T) {
+	userID := uuid.New()
+	day := time.Date(2023, time.June, 14, 0, 0, 0, 0, time.UTC)
+	at := time.Date(2023, time.June, 16, 9, 30, 0, 0, time.UTC)
+
+	balanceStorage := &storage.BalanceStorageMock{
+		GetFunc: func(ctx context.Context, id uuid.UUID) (model.Balance, error) {
+			if id != userID {
+				return model.Balance{}, apperror.NotFound
+			}
+
+			return model.Balance{UserID: userID, Balance: 99999}, nil
+		},
+	}
+
+	tests := []struct {
+		name            string
+		snapshotStorage *storage.SnapshotStorageMock
+		request         dto.GetBalanceAt
+		from            time.Time
+		response        domain.Balance
+		wantErr         error
+	}{
+		{
+			name: "from snapshot",
+			snapshotStorage: &storage.SnapshotStorageMock{
+				GetBeforeFunc: func(ctx context.Context, userID uuid.UUID, at time.Time) (model.Snapshot, error) {
+					return model.Snapshot{UserID: userID, Day: day, Balance: 10000}, nil
+				},
+			},
+			request:  dto.GetBalanceAt{UserID: userID, Timestamp: at.Format(time.RFC3339)},
+			from:     day.AddDate(0, 0, 1),
+			response: domain.Balance{UserID: userID, Balance: 125.5},
+		},
+		{
+			name: "before the first snapshot",
+			snapshotStorage: &storage.SnapshotStorageMock{
+				GetBeforeFunc: func(ctx context.Context, userID uuid.UUID, at time.Time) (model.Snapshot, error) {
+					return model.Snapshot{}, apperror.NotFound
+				},
+			},
+			request:  dto.GetBalanceAt{UserID: userID, Timestamp: at.Format(time.RFC3339)},
+			response: domain.Balance{UserID: userID, Balance: 25.5},
+		},
+		{
+			name:            "invalid timestamp",
+			snapshotStorage: &storage.SnapshotStorageMock{},
+			request:         dto.GetBalanceAt{UserID: userID, Timestamp: "yesterday"},
+			wantErr:         apperror.BadRequest,
+		},
+		{
+			name:            "balance not found",
+			snapshotStorage: &storage.SnapshotStorageMock{},
+			request:         dto.GetBalanceAt{UserID: uuid.New(), Timestamp: at.Format(time.RFC3339)},
+			wantErr:         apperror.NotFound,
+		},
+		{
+			name: "snapshot storage failure",
+			snapshotStorage: &storage.SnapshotStorageMock{
+				GetBeforeFunc: func(ctx context.Context, userID uuid.UUID, at time.Time) (model.Snapshot, error) {
+					return model.Snapshot{}, apperror.Internal.WithError(errors.New("connection refused"))
+				},
+			},
+			request: dto.GetBalanceAt{UserID: userID, Timestamp: at.Format(time.RFC3339)},
+			wantErr: apperror.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactionStorage := &storage.TransactionStorageMock{
+				SumFunc: func(ctx context.Context, userID uuid.UUID, from, to time.Time) (int64, error) {
+					return 2550, nil
+				},
+			}
+			balanceService := service.NewBalanceService(&storage.TransactorMock{}, balanceStorage, transactionStorage, &storage.AuditStorageMock{}, &storage.OutboxStorageMock{}, tt.snapshotStorage)
+
+			got, err := balanceService.GetAt(context.Background(), tt.request)
+			require.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.response, got)
+
+			if tt.wantErr == nil {
+				calls := transactionStorage.SumCalls()
+				require.Len(t, calls, 1)
+				assert.Equal(t, tt.from, calls[0].From)
+				assert.True(t, at.Equal(calls[0].To))
+			}
+		})
+	}
+}
+
 func TestBalanceService_Transfer(t *testing.T) {
 	payeeID := uuid.New()
 	payerID := uuid.New()
 
 	tests := []struct {
 		name               string
+		transactor         *storage.TransactorMock
 		balanceStorage     *storage.BalanceStorageMock
 		transactionStorage *storage.TransactionStorageMock
 		request            dto.Transfer
@@ -118,7 +211,9 @@ func TestBalanceService_Transfer(t *testing.T) {
 				UpdateFunc: func(ctx context.Context, balance model.Balance) error {
 					return nil
 				},
-				WithTransactionFunc: func(ctx context.Context, fn func(tx postgres.Client) error) error {
+			},
+			transactor: &storage.TransactorMock{
+				WithTransactionFunc: func(ctx context.Context, fn func(ctx context.Context) error, opts ...storage.TxOption) error {
 					return nil
 				},
 			},
@@ -144,7 +239,9 @@ func TestBalanceService_Transfer(t *testing.T) {
 				UpdateFunc: func(ctx context.Context, balance model.Balance) error {
 					return nil
 				},
-				WithTransactionFunc: func(ctx context.Context, fn func(tx postgres.Client) error) error {
+			},
+			transactor: &storage.TransactorMock{
+				WithTransactionFunc: func(ctx context.Context, fn func(ctx context.Context) error, opts ...storage.TxOption) error {
 					return apperror.BadRequest
 				},
 			},
@@ -161,6 +258,22 @@ func TestBalanceService_Transfer(t *testing.T) {
 			},
 			response: apperror.BadRequest,
 		},
+		{
+			name: "frozen account",
+			balanceStorage: &storage.BalanceStorageMock{
+				GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+					return model.Balance{UserID: userID, Balance: 10000, Frozen: userID == payeeID}, nil
+				},
+			},
+			transactionStorage: &storage.TransactionStorageMock{},
+			request: dto.Transfer{
+				PayeeID: payeeID,
+				PayerID: payerID,
+				Amount:  100,
+				Comment: "comment",
+			},
+			response: apperror.Forbidden,
+		},
 		{
 			name: "balance not found",
 			balanceStorage: &storage.BalanceStorageMock{
@@ -170,7 +283,9 @@ func TestBalanceService_Transfer(t *testing.T) {
 				UpdateFunc: func(ctx context.Context, balance model.Balance) error {
 					return nil
 				},
-				WithTransactionFunc: func(ctx context.Context, fn func(tx postgres.Client) error) error {
+			},
+			transactor: &storage.TransactorMock{
+				WithTransactionFunc: func(ctx context.Context, fn func(ctx context.Context) error, opts ...storage.TxOption) error {
 					return nil
 				},
 			},
@@ -191,7 +306,7 @@ func TestBalanceService_Transfer(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			balanceService := service.NewBalanceService(tt.balanceStorage, tt.transactionStorage)
+			balanceService := service.NewBalanceService(tt.transactor, tt.balanceStorage, tt.transactionStorage, &storage.AuditStorageMock{}, &storage.OutboxStorageMock{}, &storage.SnapshotStorageMock{})
 
 			err := balanceService.Transfer(context.Background(), tt.request)
 			if !errors.Is(err, tt.response) {
@@ -206,6 +321,7 @@ func TestBalanceService_Debet(t *testing.T) {
 
 	tests := []struct {
 		name               string
+		transactor         *storage.TransactorMock
 		balanceStorage     *storage.BalanceStorageMock
 		transactionStorage *storage.TransactionStorageMock
 		request            dto.Debet
@@ -217,11 +333,16 @@ func TestBalanceService_Debet(t *testing.T) {
 				GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
 					return model.Balance{UserID: userID, Balance: 0}, nil
 				},
+				GetForUpdateFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+					return model.Balance{UserID: userID, Balance: 0}, nil
+				},
 				UpdateFunc: func(ctx context.Context, balance model.Balance) error {
 					return nil
 				},
-				WithTransactionFunc: func(ctx context.Context, fn func(tx postgres.Client) error) error {
-					return nil
+			},
+			transactor: &storage.TransactorMock{
+				WithTransactionFunc: func(ctx context.Context, fn func(ctx context.Context) error, opts ...storage.TxOption) error {
+					return fn(ctx)
 				},
 			},
 			transactionStorage: &storage.TransactionStorageMock{
@@ -242,7 +363,15 @@ func TestBalanceService_Debet(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			balanceService := service.NewBalanceService(tt.balanceStorage, tt.transactionStorage)
+			balanceService := service.NewBalanceService(tt.transactor, tt.balanceStorage, tt.transactionStorage,
+				&storage.AuditStorageMock{
+					CreateFunc: func(ctx context.Context, audit model.Audit) (model.Audit, error) { return audit, nil },
+				},
+				&storage.OutboxStorageMock{
+					CreateFunc: func(ctx context.Context, event model.Event) error { return nil },
+				},
+				&storage.SnapshotStorageMock{},
+			)
 
 			transaction, err := balanceService.Debet(context.Background(), tt.request)
 			if !DeepEqualWithZero(transaction, tt.response) {
@@ -261,6 +390,7 @@ func TestBalanceService_Credit(t *testing.T) {
 
 	tests := []struct {
 		name               string
+		transactor         *storage.TransactorMock
 		balanceStorage     *storage.BalanceStorageMock
 		transactionStorage *storage.TransactionStorageMock
 		request            dto.Credit
@@ -273,11 +403,16 @@ func TestBalanceService_Credit(t *testing.T) {
 				GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
 					return model.Balance{UserID: userID, Balance: 50}, nil
 				},
+				GetForUpdateFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+					return model.Balance{UserID: userID, Balance: 50}, nil
+				},
 				UpdateFunc: func(ctx context.Context, balance model.Balance) error {
 					return nil
 				},
-				WithTransactionFunc: func(ctx context.Context, fn func(tx postgres.Client) error) error {
-					return nil
+			},
+			transactor: &storage.TransactorMock{
+				WithTransactionFunc: func(ctx context.Context, fn func(ctx context.Context) error, opts ...storage.TxOption) error {
+					return fn(ctx)
 				},
 			},
 			transactionStorage: &storage.TransactionStorageMock{
@@ -292,17 +427,40 @@ func TestBalanceService_Credit(t *testing.T) {
 			response: domain.Balance{},
 			wantErr:  apperror.BadRequest,
 		},
+		{
+			name: "frozen account",
+			balanceStorage: &storage.BalanceStorageMock{
+				GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+					return model.Balance{UserID: userID, Balance: 10000, Frozen: true}, nil
+				},
+				GetForUpdateFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+					return model.Balance{UserID: userID, Balance: 10000, Frozen: true}, nil
+				},
+			},
+			transactionStorage: &storage.TransactionStorageMock{},
+			request: dto.Credit{
+				UserID: userID,
+				Amount: 10.0,
+			},
+			response: domain.Balance{},
+			wantErr:  apperror.Forbidden,
+		},
 		{
 			name: "ok",
 			balanceStorage: &storage.BalanceStorageMock{
 				GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
 					return model.Balance{UserID: userID, Balance: 10000}, nil
 				},
+				GetForUpdateFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+					return model.Balance{UserID: userID, Balance: 10000}, nil
+				},
 				UpdateFunc: func(ctx context.Context, balance model.Balance) error {
 					return nil
 				},
-				WithTransactionFunc: func(ctx context.Context, fn func(tx postgres.Client) error) error {
-					return nil
+			},
+			transactor: &storage.TransactorMock{
+				WithTransactionFunc: func(ctx context.Context, fn func(ctx context.Context) error, opts ...storage.TxOption) error {
+					return fn(ctx)
 				},
 			},
 			transactionStorage: &storage.TransactionStorageMock{
@@ -324,7 +482,15 @@ func TestBalanceService_Credit(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			balanceService := service.NewBalanceService(tt.balanceStorage, tt.transactionStorage)
+			balanceService := service.NewBalanceService(tt.transactor, tt.balanceStorage, tt.transactionStorage,
+				&storage.AuditStorageMock{
+					CreateFunc: func(ctx context.Context, audit model.Audit) (model.Audit, error) { return audit, nil },
+				},
+				&storage.OutboxStorageMock{
+					CreateFunc: func(ctx context.Context, event model.Event) error { return nil },
+				},
+				&storage.SnapshotStorageMock{},
+			)
 
 			transaction, err := balanceService.Credit(context.Background(), tt.request)
 			if err != nil && tt.wantErr == nil {
@@ -361,7 +527,7 @@ func TestBalanceService_SelectTransaction(t *testing.T) {
 		},
 	}
 
-	balanceService := service.NewBalanceService(balanceStorage, transactionStorage)
+	balanceService := service.NewBalanceService(&storage.TransactorMock{}, balanceStorage, transactionStorage, &storage.AuditStorageMock{}, &storage.OutboxStorageMock{}, &storage.SnapshotStorageMock{})
 
 	userID := uuid.New()
 	transactions, err := balanceService.SelectTransaction(context.Background(), dto.SelectTransaction{
@@ -371,3 +537,152 @@ func TestBalanceService_SelectTransaction(t *testing.T) {
 
 	assert.Equal(t, 2, len(transactions))
 }
+
+// TestBalanceService_RetriedTransaction runs every transaction twice, like the transactor does after a
+// serialization failure, and checks that the second run starts from the stored balances again.
+func TestBalanceService_RetriedTransaction(t *testing.T) {
+	payeeID := uuid.New()
+	payerID := uuid.New()
+
+	tests := []struct {
+		name    string
+		run     func(balanceService service.BalanceService) (domain.Balance, error)
+		want    domain.Balance
+		updates map[uuid.UUID]int64
+	}{
+		{
+			name: "debet",
+			run: func(balanceService service.BalanceService) (domain.Balance, error) {
+				return balanceService.Debet(context.Background(), dto.Debet{UserID: payeeID, Amount: 10})
+			},
+			want:    domain.Balance{UserID: payeeID, Balance: 110},
+			updates: map[uuid.UUID]int64{payeeID: 11000},
+		},
+		{
+			name: "credit",
+			run: func(balanceService service.BalanceService) (domain.Balance, error) {
+				return balanceService.Credit(context.Background(), dto.Credit{UserID: payeeID, Amount: 10})
+			},
+			want:    domain.Balance{UserID: payeeID, Balance: 90},
+			updates: map[uuid.UUID]int64{payeeID: 9000},
+		},
+		{
+			name: "transfer",
+			run: func(balanceService service.BalanceService) (domain.Balance, error) {
+				return domain.Balance{}, balanceService.Transfer(context.Background(), dto.Transfer{PayeeID: payeeID, PayerID: payerID, Amount: 10})
+			},
+			updates: map[uuid.UUID]int64{payeeID: 11000, payerID: 9000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactor := &storage.TransactorMock{
+				WithTransactionFunc: func(ctx context.Context, fn func(ctx context.Context) error, opts ...storage.TxOption) error {
+					if err := fn(ctx); err != nil {
+						return err
+					}
+
+					return fn(ctx)
+				},
+			}
+
+			updates := make(map[uuid.UUID]int64)
+			balanceStorage := &storage.BalanceStorageMock{
+				GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+					return model.Balance{UserID: userID, Balance: 10000}, nil
+				},
+				GetForUpdateFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+					return model.Balance{UserID: userID, Balance: 10000}, nil
+				},
+				UpdateFunc: func(ctx context.Context, balance model.Balance) error {
+					updates[balance.UserID] = balance.Balance
+					return nil
+				},
+			}
+
+			balanceService := service.NewBalanceService(transactor, balanceStorage,
+				&storage.TransactionStorageMock{
+					CreateFunc: func(ctx context.Context, transaction model.Transaction) error { return nil },
+				},
+				&storage.AuditStorageMock{
+					CreateFunc: func(ctx context.Context, audit model.Audit) (model.Audit, error) { return audit, nil },
+				},
+				&storage.OutboxStorageMock{
+					CreateFunc: func(ctx context.Context, event model.Event) error { return nil },
+				},
+				&storage.SnapshotStorageMock{},
+			)
+
+			got, err := tt.run(balanceService)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.updates, updates)
+			assert.Len(t, transactor.WithTransactionCalls(), 1)
+		})
+	}
+}
+
+func TestBalanceService_ReadYourWrites(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name string
+		run  func(balanceService service.BalanceService) error
+		want bool
+	}{
+		{
+			name: "get reads from the replica",
+			run: func(balanceService service.BalanceService) error {
+				_, err := balanceService.Get(context.Background(), userID)
+				return err
+			},
+		},
+		{
+			name: "debet reads from the primary",
+			run: func(balanceService service.BalanceService) error {
+				_, err := balanceService.Debet(context.Background(), dto.Debet{UserID: userID, Amount: 10})
+				return err
+			},
+			want: true,
+		},
+		{
+			name: "credit reads from the primary",
+			run: func(balanceService service.BalanceService) error {
+				_, err := balanceService.Credit(context.Background(), dto.Credit{UserID: userID, Amount: 10})
+				return err
+			},
+			want: true,
+		},
+		{
+			name: "transfer reads from the primary",
+			run: func(balanceService service.BalanceService) error {
+				return balanceService.Transfer(context.Background(), dto.Transfer{PayeeID: userID, PayerID: uuid.New(), Amount: 10})
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balanceStorage := &storage.BalanceStorageMock{
+				GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+					return model.Balance{UserID: userID, Balance: 10000}, nil
+				},
+			}
+			transactor := &storage.TransactorMock{
+				WithTransactionFunc: func(ctx context.Context, fn func(ctx context.Context) error, opts ...storage.TxOption) error {
+					return nil
+				},
+			}
+
+			balanceService := service.NewBalanceService(transactor, balanceStorage, &storage.TransactionStorageMock{}, &storage.AuditStorageMock{}, &storage.OutboxStorageMock{}, &storage.SnapshotStorageMock{})
+
+			require.NoError(t, tt.run(balanceService))
+			require.NotEmpty(t, balanceStorage.GetCalls())
+			for _, call := range balanceStorage.GetCalls() {
+				assert.Equal(t, tt.want, storage.ReadYourWrites(call.Ctx))
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package service_test
+
+import (
+	"bank/internal/dto"
+	"bank/internal/pgtest"
+	"bank/internal/service"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"bank/pkg/logger"
+	"bank/pkg/postgres"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Run(m))
+}
+
+// TestBalanceService_ConcurrentTransfers moves money between a few accounts from many goroutines and checks
+// that none is lost or created, no balance goes negative and every balance matches its transactions.
+func TestBalanceService_ConcurrentTransfers(t *testing.T) {
+	tests := []struct {
+		name       string
+		transactor func(pool postgres.Pool) storage.Transactor
+	}{
+		{
+			name: "read committed",
+			transactor: func(pool postgres.Pool) storage.Transactor {
+				return storage.NewTransactor(pool, storage.RetryPolicy{}, logger.New(io.Discard, "info"))
+			},
+		},
+		{
+			// serialization failures are expected here, none may reach the caller
+			name: "serializable",
+			transactor: func(pool postgres.Pool) storage.Transactor {
+				retryPolicy := storage.RetryPolicy{MaxAttempts: 20, Backoff: time.Millisecond, MaxBackoff: 50 * time.Millisecond, Budget: 1000}
+				return storage.NewTransactor(pool, retryPolicy, logger.New(io.Discard, "info"), storage.WithIsolation(pgx.Serializable))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testConcurrentTransfers(t, tt.transactor)
+		})
+	}
+}
+
+func testConcurrentTransfers(t *testing.T, newTransactor func(pool postgres.Pool) storage.Transactor) {
+	const (
+		accounts  = 5
+		workers   = 8
+		transfers = 25
+		initial   = 100.0
+	)
+
+	ctx := context.Background()
+	pool := pgtest.Pool(t)
+	balanceStorage := storage.NewBalanceStorage(pool)
+	balanceService := service.NewBalanceService(newTransactor(pool), balanceStorage,
+		storage.NewTransactionStorage(pool), storage.NewAuditStorage(pool), storage.NewOutboxStorage(pool),
+		storage.NewSnapshotStorage(pool))
+
+	userIDs := make([]uuid.UUID, accounts)
+	for i := range userIDs {
+		userIDs[i] = uuid.New()
+		_, err := balanceService.Debet(ctx, dto.Debet{UserID: userIDs[i], Amount: initial})
+		require.NoError(t, err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*transfers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+
+			random := rand.New(rand.NewSource(seed))
+			for i := 0; i < transfers; i++ {
+				payer, payee := random.Intn(accounts), random.Intn(accounts-1)
+				if payee >= payer {
+					payee++
+				}
+
+				err := balanceService.Transfer(ctx, dto.Transfer{
+					PayerID: userIDs[payer],
+					PayeeID: userIDs[payee],
+					Amount:  float64(1 + random.Intn(40)),
+				})
+				// running out of money is expected, anything else is not
+				if err != nil && !errors.Is(err, apperror.BadRequest) {
+					errs <- err
+				}
+			}
+		}(int64(w))
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	var total float64
+	for _, userID := range userIDs {
+		balance, err := balanceService.Get(ctx, userID)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, balance.Balance, 0.0)
+
+		total += balance.Balance
+	}
+	assert.Equal(t, accounts*initial, total)
+
+	unreconciled, err := balanceStorage.SelectUnreconciled(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, unreconciled)
+}
+
+// TestBalanceService_ConcurrentCredits spends the same balance from many goroutines, only as many credits as the
+// balance covers may succeed.
+func TestBalanceService_ConcurrentCredits(t *testing.T) {
+	const workers = 10
+
+	ctx := context.Background()
+	pool := pgtest.Pool(t)
+	balanceService := service.NewBalanceService(storage.NewTransactor(pool, storage.RetryPolicy{}, logger.New(io.Discard, "info")),
+		storage.NewBalanceStorage(pool), storage.NewTransactionStorage(pool), storage.NewAuditStorage(pool),
+		storage.NewOutboxStorage(pool), storage.NewSnapshotStorage(pool))
+
+	userID := uuid.New()
+	_, err := balanceService.Debet(ctx, dto.Debet{UserID: userID, Amount: 30})
+	require.NoError(t, err)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := balanceService.Credit(ctx, dto.Credit{UserID: userID, Amount: 10})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			} else if !errors.Is(err, apperror.BadRequest) {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, succeeded)
+
+	balance, err := balanceService.Get(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, balance.Balance)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"bank/internal/domain"
+	"bank/internal/dto"
+	"bank/internal/metrics"
+	"context"
+	"github.com/google/uuid"
+)
+
+type instrumentedBalanceService struct {
+	next BalanceService
+}
+
+// WithMetrics counts every BalanceService call by method and outcome.
+func WithMetrics(balanceService BalanceService) BalanceService {
+	return &instrumentedBalanceService{next: balanceService}
+}
+
+func (service *instrumentedBalanceService) Get(ctx context.Context, userID uuid.UUID) (domain.Balance, error) {
+	balance, err := service.next.Get(ctx, userID)
+	metrics.ObserveOperation("balance", "Get", err)
+
+	return balance, err
+}
+
+func (service *instrumentedBalanceService) GetAt(ctx context.Context, request dto.GetBalanceAt) (domain.Balance, error) {
+	balance, err := service.next.GetAt(ctx, request)
+	metrics.ObserveOperation("balance", "GetAt", err)
+
+	return balance, err
+}
+
+func (service *instrumentedBalanceService) Transfer(ctx context.Context, request dto.Transfer) error {
+	err := service.next.Transfer(ctx, request)
+	metrics.ObserveOperation("balance", "Transfer", err)
+
+	return err
+}
+
+func (service *instrumentedBalanceService) Debet(ctx context.Context, request dto.Debet) (domain.Balance, error) {
+	balance, err := service.next.Debet(ctx, request)
+	metrics.ObserveOperation("balance", "Debet", err)
+
+	return balance, err
+}
+
+func (service *instrumentedBalanceService) Credit(ctx context.Context, request dto.Credit) (domain.Balance, error) {
+	balance, err := service.next.Credit(ctx, request)
+	metrics.ObserveOperation("balance", "Credit", err)
+
+	return balance, err
+}
+
+func (service *instrumentedBalanceService) SelectTransaction(ctx context.Context, request dto.SelectTransaction) ([]domain.Transaction, error) {
+	transactions, err := service.next.SelectTransaction(ctx, request)
+	metrics.ObserveOperation("balance", "SelectTransaction", err)
+
+	return transactions, err
+}
+
+func (service *instrumentedBalanceService) SelectTransactionAfter(ctx context.Context, userID uuid.UUID, afterID uuid.UUID) ([]domain.Transaction, error) {
+	transactions, err := service.next.SelectTransactionAfter(ctx, userID, afterID)
+	metrics.ObserveOperation("balance", "SelectTransactionAfter", err)
+
+	return transactions, err
+}
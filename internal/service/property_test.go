@@ -0,0 +1,238 @@
+package service_test
+
+import (
+	"bank/internal/domain"
+	"bank/internal/dto"
+	"bank/internal/service"
+	"bank/internal/storage"
+	"bank/internal/storage/memory"
+	"bank/pkg/apperror"
+	"bank/pkg/validator"
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	opDebet = iota
+	opCredit
+	opTransfer
+)
+
+var accounts = []uuid.UUID{
+	uuid.MustParse("00000000-0000-0000-0000-00000000000a"),
+	uuid.MustParse("00000000-0000-0000-0000-00000000000b"),
+	uuid.MustParse("00000000-0000-0000-0000-00000000000c"),
+	uuid.MustParse("00000000-0000-0000-0000-00000000000d"),
+}
+
+type operation struct {
+	kind   int
+	payee  uuid.UUID
+	payer  uuid.UUID
+	amount float64
+}
+
+// ledger sums the minor units of the debets and credits that succeeded.
+type ledger struct {
+	mu      sync.Mutex
+	debets  int64
+	credits int64
+}
+
+type memoryBank struct {
+	service            service.BalanceService
+	balanceStorage     storage.BalanceStorage
+	transactionStorage storage.TransactionStorage
+	ledger             ledger
+}
+
+func newMemoryBank() *memoryBank {
+	store := memory.NewStore()
+	bank := &memoryBank{
+		balanceStorage:     memory.NewBalanceStorage(store),
+		transactionStorage: memory.NewTransactionStorage(store),
+	}
+
+	bank.service = service.NewBalanceService(
+		store,
+		bank.balanceStorage,
+		bank.transactionStorage,
+		memory.NewAuditStorage(store),
+		memory.NewOutboxStorage(store),
+		memory.NewSnapshotStorage(store),
+	)
+
+	return bank
+}
+
+// apply runs op like the API would: requests failing validation are skipped, and the only errors accepted from
+// the service are the business rejections.
+func (bank *memoryBank) apply(t *testing.T, op operation) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	var request any
+	switch op.kind {
+	case opDebet:
+		request = dto.Debet{UserID: op.payee, Amount: op.amount}
+	case opCredit:
+		request = dto.Credit{UserID: op.payee, Amount: op.amount}
+	case opTransfer:
+		request = dto.Transfer{PayeeID: op.payee, PayerID: op.payer, Amount: op.amount}
+	}
+	if validator.Validate(request) != nil {
+		return
+	}
+
+	var err error
+	switch request := request.(type) {
+	case dto.Debet:
+		_, err = bank.service.Debet(ctx, request)
+	case dto.Credit:
+		_, err = bank.service.Credit(ctx, request)
+	case dto.Transfer:
+		err = bank.service.Transfer(ctx, request)
+	}
+	if err != nil {
+		require.Truef(t, errors.Is(err, apperror.BadRequest) || errors.Is(err, apperror.NotFound), "%+v: %v", op, err)
+		return
+	}
+
+	bank.ledger.mu.Lock()
+	defer bank.ledger.mu.Unlock()
+
+	switch op.kind {
+	case opDebet:
+		bank.ledger.debets += dto.Minor(op.amount)
+	case opCredit:
+		bank.ledger.credits += dto.Minor(op.amount)
+	}
+}
+
+// check asserts that no balance is negative, that money is neither created nor destroyed, and that the history
+// of every account replays to its balance without going below zero and sums up to it at present.
+func (bank *memoryBank) check(t *testing.T) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	var total int64
+	for _, userID := range accounts {
+		balance, err := bank.balanceStorage.Get(ctx, userID)
+		if errors.Is(err, apperror.NotFound) {
+			continue
+		}
+		require.NoError(t, err)
+
+		assert.GreaterOrEqual(t, balance.Balance, int64(0), "balance of %s", userID)
+		total += balance.Balance
+
+		transactions, err := bank.transactionStorage.Select(ctx, userID, dto.SelectTransactionFilter{Sort: "created_at", Order: "asc"})
+		require.NoError(t, err)
+
+		var replayed int64
+		for _, transaction := range transactions {
+			replayed += transaction.Amount
+			assert.GreaterOrEqual(t, replayed, int64(0), "history of %s after %s", userID, transaction.ID)
+		}
+		assert.Equal(t, balance.Balance, replayed, "history of %s", userID)
+
+		// the timestamp is cut to seconds, a second ahead it is past every transaction
+		at, err := bank.service.GetAt(ctx, dto.GetBalanceAt{UserID: userID, Timestamp: time.Now().Add(time.Second).Format(time.RFC3339)})
+		require.NoError(t, err)
+		assert.Equal(t, domain.BalanceFromModel(balance), at, "balance of %s at present", userID)
+	}
+
+	assert.Equal(t, bank.ledger.debets-bank.ledger.credits, total, "sum of balances")
+
+	unreconciled, err := bank.balanceStorage.SelectUnreconciled(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, unreconciled)
+}
+
+func randomOperation(rng *rand.Rand) operation {
+	op := operation{
+		kind:  rng.Intn(3),
+		payee: accounts[rng.Intn(len(accounts))],
+		payer: accounts[rng.Intn(len(accounts))],
+	}
+
+	switch rng.Intn(4) {
+	case 0:
+		// amounts off the kopeck grid exercise rounding
+		op.amount = rng.Float64() * 1000
+	case 1:
+		op.amount = float64(rng.Intn(3))
+	default:
+		op.amount = float64(rng.Intn(100_000)) / 100
+	}
+
+	return op
+}
+
+func TestBalanceService_Invariants(t *testing.T) {
+	for seed := int64(0); seed < 50; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+
+		bank := newMemoryBank()
+		for i := 0; i < 200; i++ {
+			bank.apply(t, randomOperation(rng))
+		}
+
+		bank.check(t)
+		if t.Failed() {
+			t.Fatalf("seed %d", seed)
+		}
+	}
+}
+
+func TestBalanceService_ConcurrentInvariants(t *testing.T) {
+	bank := newMemoryBank()
+
+	var wg sync.WaitGroup
+	for worker := int64(0); worker < 8; worker++ {
+		wg.Add(1)
+		go func(rng *rand.Rand) {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				bank.apply(t, randomOperation(rng))
+			}
+		}(rand.New(rand.NewSource(worker)))
+	}
+	wg.Wait()
+
+	bank.check(t)
+}
+
+// FuzzBalanceService_Operations reads operations from the input, 5 bytes each: the kind, the payee and payer
+// accounts and the amount in kopecks.
+func FuzzBalanceService_Operations(f *testing.F) {
+	f.Add([]byte{opDebet, 0, 0, 0x27, 0x10, opCredit, 0, 0, 0x27, 0x11, opTransfer, 1, 0, 0x13, 0x88})
+	f.Add([]byte{opDebet, 1, 0, 0x00, 0x01, opTransfer, 2, 1, 0x00, 0x01, opTransfer, 1, 2, 0x00, 0x01, opCredit, 1, 0, 0x00, 0x01})
+	f.Add([]byte{opTransfer, 0, 0, 0xff, 0xff, opCredit, 3, 0, 0x00, 0x00})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		bank := newMemoryBank()
+
+		for ; len(data) >= 5; data = data[5:] {
+			bank.apply(t, operation{
+				kind:   int(data[0]) % 3,
+				payee:  accounts[int(data[1])%len(accounts)],
+				payer:  accounts[int(data[2])%len(accounts)],
+				amount: float64(binary.BigEndian.Uint16(data[3:5])) / 100,
+			})
+		}
+
+		bank.check(t)
+	})
+}
@@ -0,0 +1,13 @@
+package service
+
+import (
+	"bank/pkg/apilayer"
+	"context"
+)
+
+// RateProvider converts amounts between currencies, it is implemented by apilayer.Client.
+type RateProvider interface {
+	Convert(ctx context.Context, amount float64, from, to string) (float64, error)
+}
+
+var _ RateProvider = (*apilayer.Client)(nil)
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"bank/internal/domain"
+	"bank/internal/dto"
+	"bank/internal/ratelimit"
+	"context"
+	"github.com/google/uuid"
+)
+
+type rateLimitedBalanceService struct {
+	next    BalanceService
+	limiter *ratelimit.Limiter
+}
+
+// WithRateLimit limits mutations per target account, across every caller and transport.
+// A transfer is charged to the payer, whose money moves.
+func WithRateLimit(balanceService BalanceService, limiter *ratelimit.Limiter) BalanceService {
+	return &rateLimitedBalanceService{next: balanceService, limiter: limiter}
+}
+
+func (service *rateLimitedBalanceService) Get(ctx context.Context, userID uuid.UUID) (domain.Balance, error) {
+	return service.next.Get(ctx, userID)
+}
+
+func (service *rateLimitedBalanceService) GetAt(ctx context.Context, request dto.GetBalanceAt) (domain.Balance, error) {
+	return service.next.GetAt(ctx, request)
+}
+
+func (service *rateLimitedBalanceService) Transfer(ctx context.Context, request dto.Transfer) error {
+	if err := service.limiter.AllowAccount(ctx, request.PayerID); err != nil {
+		return err
+	}
+
+	return service.next.Transfer(ctx, request)
+}
+
+func (service *rateLimitedBalanceService) Debet(ctx context.Context, request dto.Debet) (domain.Balance, error) {
+	if err := service.limiter.AllowAccount(ctx, request.UserID); err != nil {
+		return domain.Balance{}, err
+	}
+
+	return service.next.Debet(ctx, request)
+}
+
+func (service *rateLimitedBalanceService) Credit(ctx context.Context, request dto.Credit) (domain.Balance, error) {
+	if err := service.limiter.AllowAccount(ctx, request.UserID); err != nil {
+		return domain.Balance{}, err
+	}
+
+	return service.next.Credit(ctx, request)
+}
+
+func (service *rateLimitedBalanceService) SelectTransaction(ctx context.Context, request dto.SelectTransaction) ([]domain.Transaction, error) {
+	return service.next.SelectTransaction(ctx, request)
+}
+
+func (service *rateLimitedBalanceService) SelectTransactionAfter(ctx context.Context, userID uuid.UUID, afterID uuid.UUID) ([]domain.Transaction, error) {
+	return service.next.SelectTransactionAfter(ctx, userID, afterID)
+}
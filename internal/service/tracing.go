@@ -0,0 +1,111 @@
+package service
+
+import (
+	"bank/internal/domain"
+	"bank/internal/dto"
+	"bank/internal/tracing"
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedBalanceService struct {
+	next BalanceService
+}
+
+// WithTracing wraps every BalanceService call in a span, so storage queries and commits show up as its children.
+func WithTracing(balanceService BalanceService) BalanceService {
+	return &tracedBalanceService{next: balanceService}
+}
+
+func (service *tracedBalanceService) Get(ctx context.Context, userID uuid.UUID) (domain.Balance, error) {
+	ctx, span := startSpan(ctx, "BalanceService.Get", attribute.String("user_id", userID.String()))
+	defer span.End()
+
+	balance, err := service.next.Get(ctx, userID)
+	recordError(span, err)
+
+	return balance, err
+}
+
+func (service *tracedBalanceService) GetAt(ctx context.Context, request dto.GetBalanceAt) (domain.Balance, error) {
+	ctx, span := startSpan(ctx, "BalanceService.GetAt",
+		attribute.String("user_id", request.UserID.String()),
+		attribute.String("timestamp", request.Timestamp),
+	)
+	defer span.End()
+
+	balance, err := service.next.GetAt(ctx, request)
+	recordError(span, err)
+
+	return balance, err
+}
+
+func (service *tracedBalanceService) Transfer(ctx context.Context, request dto.Transfer) error {
+	ctx, span := startSpan(ctx, "BalanceService.Transfer",
+		attribute.String("payer_id", request.PayerID.String()),
+		attribute.String("payee_id", request.PayeeID.String()),
+	)
+	defer span.End()
+
+	err := service.next.Transfer(ctx, request)
+	recordError(span, err)
+
+	return err
+}
+
+func (service *tracedBalanceService) Debet(ctx context.Context, request dto.Debet) (domain.Balance, error) {
+	ctx, span := startSpan(ctx, "BalanceService.Debet", attribute.String("user_id", request.UserID.String()))
+	defer span.End()
+
+	balance, err := service.next.Debet(ctx, request)
+	recordError(span, err)
+
+	return balance, err
+}
+
+func (service *tracedBalanceService) Credit(ctx context.Context, request dto.Credit) (domain.Balance, error) {
+	ctx, span := startSpan(ctx, "BalanceService.Credit", attribute.String("user_id", request.UserID.String()))
+	defer span.End()
+
+	balance, err := service.next.Credit(ctx, request)
+	recordError(span, err)
+
+	return balance, err
+}
+
+func (service *tracedBalanceService) SelectTransaction(ctx context.Context, request dto.SelectTransaction) ([]domain.Transaction, error) {
+	ctx, span := startSpan(ctx, "BalanceService.SelectTransaction", attribute.String("user_id", request.UserID.String()))
+	defer span.End()
+
+	transactions, err := service.next.SelectTransaction(ctx, request)
+	recordError(span, err)
+
+	return transactions, err
+}
+
+func (service *tracedBalanceService) SelectTransactionAfter(ctx context.Context, userID uuid.UUID, afterID uuid.UUID) ([]domain.Transaction, error) {
+	ctx, span := startSpan(ctx, "BalanceService.SelectTransactionAfter",
+		attribute.String("user_id", userID.String()),
+		attribute.String("after_id", afterID.String()),
+	)
+	defer span.End()
+
+	transactions, err := service.next.SelectTransactionAfter(ctx, userID, afterID)
+	recordError(span, err)
+
+	return transactions, err
+}
+
+func startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"bank/internal/domain"
+	"bank/internal/dto"
+	"bank/internal/model"
+	"bank/internal/storage"
+	"bank/internal/webhook"
+	"bank/pkg/apperror"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/google/uuid"
+	"net"
+	"time"
+)
+
+type WebhookService interface {
+	CreateSubscription(ctx context.Context, request dto.CreateWebhookSubscription) (domain.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error)
+	SelectSubscription(ctx context.Context, request dto.SelectWebhookSubscription) ([]domain.WebhookSubscription, error)
+	DisableSubscription(ctx context.Context, id uuid.UUID) error
+	Replay(ctx context.Context, id uuid.UUID) (int64, error)
+	SelectDelivery(ctx context.Context, request dto.SelectWebhookDelivery) ([]domain.WebhookDelivery, error)
+}
+
+type webhookService struct {
+	webhookStorage storage.WebhookStorage
+}
+
+func NewWebhookService(webhookStorage storage.WebhookStorage) WebhookService {
+	return &webhookService{
+		webhookStorage: webhookStorage,
+	}
+}
+
+func (service *webhookService) CreateSubscription(ctx context.Context, request dto.CreateWebhookSubscription) (domain.WebhookSubscription, error) {
+	// deliveries are sent from inside the network, a subscription must not make them reach internal hosts
+	if err := webhook.CheckURL(ctx, net.DefaultResolver, request.URL); err != nil {
+		return domain.WebhookSubscription{}, apperror.BadRequest.WithError(err).
+			WithMessage("url must be https to a host with public addresses")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return domain.WebhookSubscription{}, apperror.Internal.WithError(err).WithScope("webhookService.CreateSubscription")
+	}
+
+	eventTypes := request.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+
+	subscription := model.WebhookSubscription{
+		ID:         uuid.New(),
+		AccountID:  request.AccountID,
+		URL:        request.URL,
+		Secret:     "whsec_" + hex.EncodeToString(secret),
+		EventTypes: eventTypes,
+		Enabled:    true,
+		CreatedAt:  time.Now(),
+	}
+
+	err := service.webhookStorage.CreateSubscription(ctx, subscription)
+	if err != nil {
+		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+			return domain.WebhookSubscription{}, apperr.WithScope("webhookService.CreateSubscription")
+		}
+
+		return domain.WebhookSubscription{}, err
+	}
+
+	res := domain.WebhookSubscriptionFromModel(subscription)
+	res.Secret = subscription.Secret
+
+	return res, nil
+}
+
+func (service *webhookService) GetSubscription(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error) {
+	subscription, err := service.webhookStorage.GetSubscription(ctx, id)
+	if err != nil {
+		if apperr, ok := apperror.Is(err, apperror.NotFound); ok {
+			return domain.WebhookSubscription{}, apperr.WithMessage("subscription not found")
+		}
+
+		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+			return domain.WebhookSubscription{}, apperr.WithScope("webhookService.GetSubscription")
+		}
+
+		return domain.WebhookSubscription{}, err
+	}
+
+	return domain.WebhookSubscriptionFromModel(subscription), nil
+}
+
+func (service *webhookService) SelectSubscription(ctx context.Context, request dto.SelectWebhookSubscription) ([]domain.WebhookSubscription, error) {
+	subscriptions, err := service.webhookStorage.SelectSubscription(ctx, request.AccountID, request.GetCount(), request.GetOffset())
+	if err != nil {
+		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+			return []domain.WebhookSubscription{}, apperr.WithScope("webhookService.SelectSubscription")
+		}
+
+		return []domain.WebhookSubscription{}, err
+	}
+
+	return domain.WebhookSubscriptionsFromModels(subscriptions), nil
+}
+
+func (service *webhookService) DisableSubscription(ctx context.Context, id uuid.UUID) error {
+	err := service.webhookStorage.DisableSubscription(ctx, id)
+	if err != nil {
+		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+			return apperr.WithScope("webhookService.DisableSubscription")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (service *webhookService) Replay(ctx context.Context, id uuid.UUID) (int64, error) {
+	replayed, err := service.webhookStorage.Replay(ctx, id)
+	if err != nil {
+		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+			return 0, apperr.WithScope("webhookService.Replay")
+		}
+
+		return 0, err
+	}
+
+	return replayed, nil
+}
+
+func (service *webhookService) SelectDelivery(ctx context.Context, request dto.SelectWebhookDelivery) ([]domain.WebhookDelivery, error) {
+	deliveries, err := service.webhookStorage.SelectDelivery(ctx, request.SubscriptionID, request.Status, request.GetCount(), request.GetOffset())
+	if err != nil {
+		if apperr, ok := apperror.Is(err, apperror.Internal); ok {
+			return []domain.WebhookDelivery{}, apperr.WithScope("webhookService.SelectDelivery")
+		}
+
+		return []domain.WebhookDelivery{}, err
+	}
+
+	return domain.WebhookDeliveriesFromModels(deliveries), nil
+}
@@ -0,0 +1,77 @@
+// Package snapshot takes the daily closing balances of the accounts, so the balance at a past moment is read from
+// the nearest snapshot and the transactions after it rather than from the whole history.
+package snapshot
+
+import (
+	"bank/internal/storage"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Job snapshots each UTC day once it has ended and settle has passed, giving transactions stamped before midnight
+// the time to commit.
+type Job struct {
+	transactor      storage.Transactor
+	snapshotStorage storage.SnapshotStorage
+	settle          time.Duration
+	interval        time.Duration
+	now             func() time.Time
+	log             *slog.Logger
+}
+
+func NewJob(transactor storage.Transactor, snapshotStorage storage.SnapshotStorage, settle, interval time.Duration, log *slog.Logger) *Job {
+	return &Job{
+		transactor:      transactor,
+		snapshotStorage: snapshotStorage,
+		settle:          settle,
+		interval:        interval,
+		now:             time.Now,
+		log:             log,
+	}
+}
+
+// Run snapshots the pending days right away and then every interval until ctx is done.
+func (job *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(job.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := job.Snapshot(ctx); err != nil && ctx.Err() == nil {
+			job.log.ErrorContext(ctx, "snapshot balances", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Snapshot takes the days that ended and settled and are not snapshotted yet, oldest first and each in a
+// transaction of its own, so an interrupted run resumes where it stopped. It returns the number of days taken.
+// Days already snapshotted, e.g. by another instance, are left as they are.
+func (job *Job) Snapshot(ctx context.Context) (int, error) {
+	days, err := job.snapshotStorage.Pending(ctx, job.now().Add(-job.settle))
+	if err != nil {
+		return 0, err
+	}
+
+	for i, day := range days {
+		var created int64
+		err = job.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			created, err = job.snapshotStorage.Create(ctx, day)
+
+			return err
+		})
+		if err != nil {
+			return i, err
+		}
+
+		job.log.DebugContext(ctx, "balances snapshotted", slog.String("day", day.Format(time.DateOnly)), slog.Int64("accounts", created))
+	}
+
+	return len(days), nil
+}
@@ -0,0 +1,84 @@
+package snapshot_test
+
+import (
+	"bank/internal/pgtest"
+	"bank/internal/snapshot"
+	"bank/internal/storage"
+	"bank/pkg/logger"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func day(n int) time.Time {
+	return time.Date(2023, time.June, n, 0, 0, 0, 0, time.UTC)
+}
+
+func TestJob_Snapshot(t *testing.T) {
+	failure := errors.New("connection refused")
+
+	tests := []struct {
+		name    string
+		pending []time.Time
+		failOn  time.Time
+		want    int
+		created []time.Time
+		wantErr error
+	}{
+		{
+			name:    "nothing pending",
+			pending: []time.Time{},
+		},
+		{
+			name:    "oldest first",
+			pending: []time.Time{day(14), day(15), day(16)},
+			want:    3,
+			created: []time.Time{day(14), day(15), day(16)},
+		},
+		{
+			name:    "stops at the failed day",
+			pending: []time.Time{day(14), day(15), day(16)},
+			failOn:  day(15),
+			want:    1,
+			created: []time.Time{day(14), day(15)},
+			wantErr: failure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshotStorage := &storage.SnapshotStorageMock{
+				PendingFunc: func(ctx context.Context, until time.Time) ([]time.Time, error) {
+					return tt.pending, nil
+				},
+				CreateFunc: func(ctx context.Context, day time.Time) (int64, error) {
+					if day.Equal(tt.failOn) {
+						return 0, failure
+					}
+
+					return 2, nil
+				},
+			}
+			tx := pgtest.Transactor()
+
+			got, err := snapshot.NewJob(tx, snapshotStorage, 5*time.Minute, time.Hour, logger.New(io.Discard, "info")).Snapshot(context.Background())
+			require.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+
+			var created []time.Time
+			for _, call := range snapshotStorage.CreateCalls() {
+				created = append(created, call.Day)
+			}
+			assert.Equal(t, tt.created, created)
+			assert.Len(t, tx.WithTransactionCalls(), len(tt.created))
+
+			calls := snapshotStorage.PendingCalls()
+			require.Len(t, calls, 1)
+			assert.WithinDuration(t, time.Now().Add(-5*time.Minute), calls[0].Until, time.Minute)
+		})
+	}
+}
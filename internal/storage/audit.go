@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"bank/internal/model"
+	"bank/pkg/apperror"
+	"bank/pkg/postgres"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// auditLockKey serializes appends to the audit chain, so two transactions never link to the same previous entry.
+const auditLockKey = 7_202_305_10
+
+var (
+	errNoTransaction  = errors.New("audit entries are created in a transaction")
+	errRepeatableRead = errors.New("audit entries are not created at repeatable read")
+)
+
+type AuditFilter struct {
+	AccountID uuid.UUID
+	Actor     string
+	Operation string
+	RequestID string
+	From      time.Time
+	To        time.Time
+	AfterID   int64
+	Order     string
+	Count     uint64
+	Offset    uint64
+}
+
+//go:generate moq -out audit_mock.go . AuditStorage
+type AuditStorage interface {
+	// Create must run in a transaction, which holds the lock on the chain until it ends. At repeatable read the
+	// snapshot may predate the entry written while waiting for the lock, forking the chain, so that level is
+	// refused; serializable transactions fail on the conflict instead and are retried.
+	Create(ctx context.Context, audit model.Audit) (model.Audit, error)
+	Select(ctx context.Context, filter AuditFilter) ([]model.Audit, error)
+}
+
+type auditStorage struct {
+	client postgres.Client
+}
+
+func NewAuditStorage(client postgres.Client) AuditStorage {
+	return &auditStorage{client: client}
+}
+
+func (storage *auditStorage) Create(ctx context.Context, audit model.Audit) (model.Audit, error) {
+	options, ok := txOptions(ctx)
+	if !ok {
+		return audit, apperror.Internal.WithError(errNoTransaction)
+	}
+	if options.IsoLevel == pgx.RepeatableRead {
+		return audit, apperror.Internal.WithError(errRepeatableRead)
+	}
+
+	tx := conn(ctx, storage.client)
+
+	_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", auditLockKey)
+	if err != nil {
+		return audit, apperror.Internal.WithError(err)
+	}
+
+	q, args, err := psql.
+		Select("hash").
+		From("audit").
+		OrderBy("id DESC").
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return audit, apperror.Internal.WithError(err)
+	}
+
+	err = tx.QueryRow(ctx, q, args...).Scan(&audit.PrevHash)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return audit, apperror.Internal.WithError(err)
+	}
+
+	// postgres keeps microseconds, the digest must be computed over what is actually stored
+	audit.CreatedAt = audit.CreatedAt.UTC().Truncate(time.Microsecond)
+	audit.Hash = audit.Digest()
+
+	builder := psql.
+		Insert("audit").
+		Columns(
+			"actor",
+			"source_ip",
+			"request_id",
+			"operation",
+			"account_id",
+			"balance_before",
+			"balance_after",
+			"payload_hash",
+			"prev_hash",
+			"hash",
+			"created_at",
+		).
+		Values(
+			audit.Actor,
+			audit.SourceIP,
+			audit.RequestID,
+			audit.Operation,
+			audit.AccountID,
+			audit.BalanceBefore,
+			audit.BalanceAfter,
+			audit.PayloadHash,
+			audit.PrevHash,
+			audit.Hash,
+			audit.CreatedAt,
+		).
+		Suffix("RETURNING id")
+
+	q, args, err = builder.ToSql()
+	if err != nil {
+		return audit, apperror.Internal.WithError(err)
+	}
+
+	err = tx.QueryRow(ctx, q, args...).Scan(&audit.ID)
+	if err != nil {
+		return audit, apperror.Internal.WithError(err)
+	}
+
+	return audit, nil
+}
+
+func (storage *auditStorage) Select(ctx context.Context, filter AuditFilter) ([]model.Audit, error) {
+	builder := psql.
+		Select(
+			"id",
+			"actor",
+			"source_ip",
+			"request_id",
+			"operation",
+			"account_id",
+			"balance_before",
+			"balance_after",
+			"payload_hash",
+			"prev_hash",
+			"hash",
+			"created_at",
+		).
+		From("audit").
+		Offset(filter.Offset)
+
+	if filter.AccountID != uuid.Nil {
+		builder = builder.Where(squirrel.Eq{"account_id": filter.AccountID})
+	}
+
+	if filter.Actor != "" {
+		builder = builder.Where(squirrel.Eq{"actor": filter.Actor})
+	}
+
+	if filter.Operation != "" {
+		builder = builder.Where(squirrel.Eq{"operation": filter.Operation})
+	}
+
+	if filter.RequestID != "" {
+		builder = builder.Where(squirrel.Eq{"request_id": filter.RequestID})
+	}
+
+	if !filter.From.IsZero() {
+		builder = builder.Where(squirrel.GtOrEq{"created_at": filter.From})
+	}
+
+	if !filter.To.IsZero() {
+		builder = builder.Where(squirrel.Lt{"created_at": filter.To})
+	}
+
+	if filter.AfterID != 0 {
+		builder = builder.Where(squirrel.Gt{"id": filter.AfterID})
+	}
+
+	if filter.Count != 0 {
+		builder = builder.Limit(filter.Count)
+	}
+
+	order := "asc"
+	if filter.Order == "desc" {
+		order = "desc"
+	}
+
+	builder = builder.OrderBy(fmt.Sprintf("id %s", order))
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return []model.Audit{}, apperror.Internal.WithError(err)
+	}
+
+	var audits []model.Audit
+	err = reader(ctx, storage.client).Select(ctx, &audits, q, args...)
+	if err != nil {
+		return []model.Audit{}, apperror.Internal.WithError(err)
+	}
+
+	return audits, nil
+}
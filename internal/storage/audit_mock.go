@@ -0,0 +1,132 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package storage
+
+import (
+	"bank/internal/model"
+	"context"
+	"sync"
+)
+
+// Ensure, that AuditStorageMock does implement AuditStorage.
+// If this is not the case, regenerate this file with moq.
+var _ AuditStorage = &AuditStorageMock{}
+
+// AuditStorageMock is a mock implementation of AuditStorage.
+//
+//	func TestSomethingThatUsesAuditStorage(t *testing.T) {
+//
+//		// make and configure a mocked AuditStorage
+//		mockedAuditStorage := &AuditStorageMock{
+//			CreateFunc: func(ctx context.Context, audit model.Audit) (model.Audit, error) {
+//				panic("mock out the Create method")
+//			},
+//			SelectFunc: func(ctx context.Context, filter AuditFilter) ([]model.Audit, error) {
+//				panic("mock out the Select method")
+//			},
+//		}
+//
+//		// use mockedAuditStorage in code that requires AuditStorage
+//		// and then make assertions.
+//
+//	}
+type AuditStorageMock struct {
+	// CreateFunc mocks the Create method.
+	CreateFunc func(ctx context.Context, audit model.Audit) (model.Audit, error)
+
+	// SelectFunc mocks the Select method.
+	SelectFunc func(ctx context.Context, filter AuditFilter) ([]model.Audit, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Create holds details about calls to the Create method.
+		Create []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Audit is the audit argument value.
+			Audit model.Audit
+		}
+		// Select holds details about calls to the Select method.
+		Select []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Filter is the filter argument value.
+			Filter AuditFilter
+		}
+	}
+	lockCreate sync.RWMutex
+	lockSelect sync.RWMutex
+}
+
+// Create calls CreateFunc.
+func (mock *AuditStorageMock) Create(ctx context.Context, audit model.Audit) (model.Audit, error) {
+	if mock.CreateFunc == nil {
+		panic("AuditStorageMock.CreateFunc: method is nil but AuditStorage.Create was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Audit model.Audit
+	}{
+		Ctx:   ctx,
+		Audit: audit,
+	}
+	mock.lockCreate.Lock()
+	mock.calls.Create = append(mock.calls.Create, callInfo)
+	mock.lockCreate.Unlock()
+	return mock.CreateFunc(ctx, audit)
+}
+
+// CreateCalls gets all the calls that were made to Create.
+// Check the length with:
+//
+//	len(mockedAuditStorage.CreateCalls())
+func (mock *AuditStorageMock) CreateCalls() []struct {
+	Ctx   context.Context
+	Audit model.Audit
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Audit model.Audit
+	}
+	mock.lockCreate.RLock()
+	calls = mock.calls.Create
+	mock.lockCreate.RUnlock()
+	return calls
+}
+
+// Select calls SelectFunc.
+func (mock *AuditStorageMock) Select(ctx context.Context, filter AuditFilter) ([]model.Audit, error) {
+	if mock.SelectFunc == nil {
+		panic("AuditStorageMock.SelectFunc: method is nil but AuditStorage.Select was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		Filter AuditFilter
+	}{
+		Ctx:    ctx,
+		Filter: filter,
+	}
+	mock.lockSelect.Lock()
+	mock.calls.Select = append(mock.calls.Select, callInfo)
+	mock.lockSelect.Unlock()
+	return mock.SelectFunc(ctx, filter)
+}
+
+// SelectCalls gets all the calls that were made to Select.
+// Check the length with:
+//
+//	len(mockedAuditStorage.SelectCalls())
+func (mock *AuditStorageMock) SelectCalls() []struct {
+	Ctx    context.Context
+	Filter AuditFilter
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Filter AuditFilter
+	}
+	mock.lockSelect.RLock()
+	calls = mock.calls.Select
+	mock.lockSelect.RUnlock()
+	return calls
+}
@@ -13,11 +13,12 @@ import (
 
 //go:generate moq -out balance_mock.go . BalanceStorage
 type BalanceStorage interface {
-	WithTransaction(ctx context.Context, fn func(tx postgres.Client) error) error
 	Create(ctx context.Context, balance model.Balance) error
 	Update(ctx context.Context, balance model.Balance) error
-	AtomicUpdate(ctx context.Context, tx postgres.Client, balance model.Balance) error
 	Get(ctx context.Context, userID uuid.UUID) (model.Balance, error)
+	GetForUpdate(ctx context.Context, userID uuid.UUID) (model.Balance, error)
+	SetFrozen(ctx context.Context, userID uuid.UUID, frozen bool) error
+	SelectUnreconciled(ctx context.Context) ([]model.Reconciliation, error)
 }
 
 type balanceStorage struct {
@@ -28,31 +29,6 @@ func NewBalanceStorage(client postgres.Client) BalanceStorage {
 	return &balanceStorage{client: client}
 }
 
-func (storage *balanceStorage) WithTransaction(ctx context.Context, fn func(tx postgres.Client) error) error {
-	tx, err := storage.client.Begin(ctx)
-	if err != nil {
-		return apperror.Internal.WithError(err)
-	}
-
-	defer func() {
-		if p := recover(); p != nil {
-			_ = tx.Rollback(ctx)
-			panic(p)
-		} else if err != nil {
-			_ = tx.Rollback(ctx)
-		} else {
-			err = tx.Commit(ctx)
-			if err != nil {
-				panic(p)
-			}
-		}
-	}()
-
-	err = fn(postgres.Txw{Tx: tx})
-
-	return err
-}
-
 func (storage *balanceStorage) Create(ctx context.Context, balance model.Balance) error {
 	builder := psql.
 		Insert("balance").
@@ -70,7 +46,7 @@ func (storage *balanceStorage) Create(ctx context.Context, balance model.Balance
 		return apperror.Internal.WithError(err)
 	}
 
-	_, err = storage.client.Exec(ctx, q, args...)
+	_, err = conn(ctx, storage.client).Exec(ctx, q, args...)
 	if err != nil {
 		return apperror.Internal.WithError(err)
 	}
@@ -79,10 +55,6 @@ func (storage *balanceStorage) Create(ctx context.Context, balance model.Balance
 }
 
 func (storage *balanceStorage) Update(ctx context.Context, balance model.Balance) error {
-	return storage.AtomicUpdate(ctx, storage.client, balance)
-}
-
-func (storage *balanceStorage) AtomicUpdate(ctx context.Context, tx postgres.Client, balance model.Balance) error {
 	builder := psql.
 		Update("balance").
 		Set("balance", balance.Balance).
@@ -93,7 +65,7 @@ func (storage *balanceStorage) AtomicUpdate(ctx context.Context, tx postgres.Cli
 		return apperror.Internal.WithError(err)
 	}
 
-	_, err = tx.Exec(ctx, q, args...)
+	_, err = conn(ctx, storage.client).Exec(ctx, q, args...)
 	if err != nil {
 		return apperror.Internal.WithError(err)
 	}
@@ -102,21 +74,38 @@ func (storage *balanceStorage) AtomicUpdate(ctx context.Context, tx postgres.Cli
 }
 
 func (storage *balanceStorage) Get(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+	return storage.get(ctx, userID, false)
+}
+
+// GetForUpdate locks the balance row until the transaction of ctx ends, so the read-modify-write of concurrent
+// transactions is serialized. Outside of a transaction the lock is released as soon as the row is read.
+func (storage *balanceStorage) GetForUpdate(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+	return storage.get(ctx, userID, true)
+}
+
+func (storage *balanceStorage) get(ctx context.Context, userID uuid.UUID, forUpdate bool) (model.Balance, error) {
 	builder := psql.
 		Select(
 			"user_id",
 			"balance",
+			"frozen",
 		).
 		From("balance").
 		Where(squirrel.Eq{"user_id": userID})
 
+	client := reader(ctx, storage.client)
+	if forUpdate {
+		builder = builder.Suffix("FOR UPDATE")
+		client = conn(ctx, storage.client)
+	}
+
 	q, args, err := builder.ToSql()
 	if err != nil {
 		return model.Balance{}, apperror.Internal.WithError(err)
 	}
 
 	var balance model.Balance
-	err = storage.client.Get(ctx, &balance, q, args...)
+	err = client.Get(ctx, &balance, q, args...)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return model.Balance{}, apperror.NotFound.WithError(err)
@@ -127,3 +116,53 @@ func (storage *balanceStorage) Get(ctx context.Context, userID uuid.UUID) (model
 
 	return balance, nil
 }
+
+func (storage *balanceStorage) SetFrozen(ctx context.Context, userID uuid.UUID, frozen bool) error {
+	builder := psql.
+		Update("balance").
+		Set("frozen", frozen).
+		Where(squirrel.Eq{"user_id": userID})
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	tag, err := conn(ctx, storage.client).Exec(ctx, q, args...)
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apperror.NotFound
+	}
+
+	return nil
+}
+
+// SelectUnreconciled returns the balances that differ from the sum of their transactions.
+func (storage *balanceStorage) SelectUnreconciled(ctx context.Context) ([]model.Reconciliation, error) {
+	builder := psql.
+		Select(
+			"b.user_id",
+			"b.balance",
+			"COALESCE(SUM(t.amount), 0) AS ledger",
+		).
+		From("balance b").
+		LeftJoin("transaction_history t ON t.payee_id = b.user_id").
+		GroupBy("b.user_id", "b.balance").
+		Having("b.balance <> COALESCE(SUM(t.amount), 0)").
+		OrderBy("b.user_id")
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return []model.Reconciliation{}, apperror.Internal.WithError(err)
+	}
+
+	var reconciliations []model.Reconciliation
+	err = reader(ctx, storage.client).Select(ctx, &reconciliations, q, args...)
+	if err != nil {
+		return []model.Reconciliation{}, apperror.Internal.WithError(err)
+	}
+
+	return reconciliations, nil
+}
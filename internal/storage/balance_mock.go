@@ -5,7 +5,6 @@ package storage
 
 import (
 	"bank/internal/model"
-	"bank/pkg/postgres"
 	"context"
 	"github.com/google/uuid"
 	"sync"
@@ -21,21 +20,24 @@ var _ BalanceStorage = &BalanceStorageMock{}
 //
 //		// make and configure a mocked BalanceStorage
 //		mockedBalanceStorage := &BalanceStorageMock{
-//			AtomicUpdateFunc: func(ctx context.Context, tx postgres.Client, balance model.Balance) error {
-//				panic("mock out the AtomicUpdate method")
-//			},
 //			CreateFunc: func(ctx context.Context, balance model.Balance) error {
 //				panic("mock out the Create method")
 //			},
 //			GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
 //				panic("mock out the Get method")
 //			},
+//			GetForUpdateFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+//				panic("mock out the GetForUpdate method")
+//			},
+//			SelectUnreconciledFunc: func(ctx context.Context) ([]model.Reconciliation, error) {
+//				panic("mock out the SelectUnreconciled method")
+//			},
+//			SetFrozenFunc: func(ctx context.Context, userID uuid.UUID, frozen bool) error {
+//				panic("mock out the SetFrozen method")
+//			},
 //			UpdateFunc: func(ctx context.Context, balance model.Balance) error {
 //				panic("mock out the Update method")
 //			},
-//			WithTransactionFunc: func(ctx context.Context, fn func(tx postgres.Client) error) error {
-//				panic("mock out the WithTransaction method")
-//			},
 //		}
 //
 //		// use mockedBalanceStorage in code that requires BalanceStorage
@@ -43,32 +45,26 @@ var _ BalanceStorage = &BalanceStorageMock{}
 //
 //	}
 type BalanceStorageMock struct {
-	// AtomicUpdateFunc mocks the AtomicUpdate method.
-	AtomicUpdateFunc func(ctx context.Context, tx postgres.Client, balance model.Balance) error
-
 	// CreateFunc mocks the Create method.
 	CreateFunc func(ctx context.Context, balance model.Balance) error
 
 	// GetFunc mocks the Get method.
 	GetFunc func(ctx context.Context, userID uuid.UUID) (model.Balance, error)
 
+	// GetForUpdateFunc mocks the GetForUpdate method.
+	GetForUpdateFunc func(ctx context.Context, userID uuid.UUID) (model.Balance, error)
+
+	// SelectUnreconciledFunc mocks the SelectUnreconciled method.
+	SelectUnreconciledFunc func(ctx context.Context) ([]model.Reconciliation, error)
+
+	// SetFrozenFunc mocks the SetFrozen method.
+	SetFrozenFunc func(ctx context.Context, userID uuid.UUID, frozen bool) error
+
 	// UpdateFunc mocks the Update method.
 	UpdateFunc func(ctx context.Context, balance model.Balance) error
 
-	// WithTransactionFunc mocks the WithTransaction method.
-	WithTransactionFunc func(ctx context.Context, fn func(tx postgres.Client) error) error
-
 	// calls tracks calls to the methods.
 	calls struct {
-		// AtomicUpdate holds details about calls to the AtomicUpdate method.
-		AtomicUpdate []struct {
-			// Ctx is the ctx argument value.
-			Ctx context.Context
-			// Tx is the tx argument value.
-			Tx postgres.Client
-			// Balance is the balance argument value.
-			Balance model.Balance
-		}
 		// Create holds details about calls to the Create method.
 		Create []struct {
 			// Ctx is the ctx argument value.
@@ -83,6 +79,27 @@ type BalanceStorageMock struct {
 			// UserID is the userID argument value.
 			UserID uuid.UUID
 		}
+		// GetForUpdate holds details about calls to the GetForUpdate method.
+		GetForUpdate []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID uuid.UUID
+		}
+		// SelectUnreconciled holds details about calls to the SelectUnreconciled method.
+		SelectUnreconciled []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// SetFrozen holds details about calls to the SetFrozen method.
+		SetFrozen []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID uuid.UUID
+			// Frozen is the frozen argument value.
+			Frozen bool
+		}
 		// Update holds details about calls to the Update method.
 		Update []struct {
 			// Ctx is the ctx argument value.
@@ -90,59 +107,13 @@ type BalanceStorageMock struct {
 			// Balance is the balance argument value.
 			Balance model.Balance
 		}
-		// WithTransaction holds details about calls to the WithTransaction method.
-		WithTransaction []struct {
-			// Ctx is the ctx argument value.
-			Ctx context.Context
-			// Fn is the fn argument value.
-			Fn func(tx postgres.Client) error
-		}
 	}
-	lockAtomicUpdate    sync.RWMutex
-	lockCreate          sync.RWMutex
-	lockGet             sync.RWMutex
-	lockUpdate          sync.RWMutex
-	lockWithTransaction sync.RWMutex
-}
-
-// AtomicUpdate calls AtomicUpdateFunc.
-func (mock *BalanceStorageMock) AtomicUpdate(ctx context.Context, tx postgres.Client, balance model.Balance) error {
-	if mock.AtomicUpdateFunc == nil {
-		panic("BalanceStorageMock.AtomicUpdateFunc: method is nil but BalanceStorage.AtomicUpdate was just called")
-	}
-	callInfo := struct {
-		Ctx     context.Context
-		Tx      postgres.Client
-		Balance model.Balance
-	}{
-		Ctx:     ctx,
-		Tx:      tx,
-		Balance: balance,
-	}
-	mock.lockAtomicUpdate.Lock()
-	mock.calls.AtomicUpdate = append(mock.calls.AtomicUpdate, callInfo)
-	mock.lockAtomicUpdate.Unlock()
-	return mock.AtomicUpdateFunc(ctx, tx, balance)
-}
-
-// AtomicUpdateCalls gets all the calls that were made to AtomicUpdate.
-// Check the length with:
-//
-//	len(mockedBalanceStorage.AtomicUpdateCalls())
-func (mock *BalanceStorageMock) AtomicUpdateCalls() []struct {
-	Ctx     context.Context
-	Tx      postgres.Client
-	Balance model.Balance
-} {
-	var calls []struct {
-		Ctx     context.Context
-		Tx      postgres.Client
-		Balance model.Balance
-	}
-	mock.lockAtomicUpdate.RLock()
-	calls = mock.calls.AtomicUpdate
-	mock.lockAtomicUpdate.RUnlock()
-	return calls
+	lockCreate             sync.RWMutex
+	lockGet                sync.RWMutex
+	lockGetForUpdate       sync.RWMutex
+	lockSelectUnreconciled sync.RWMutex
+	lockSetFrozen          sync.RWMutex
+	lockUpdate             sync.RWMutex
 }
 
 // Create calls CreateFunc.
@@ -217,6 +188,114 @@ func (mock *BalanceStorageMock) GetCalls() []struct {
 	return calls
 }
 
+// GetForUpdate calls GetForUpdateFunc.
+func (mock *BalanceStorageMock) GetForUpdate(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+	if mock.GetForUpdateFunc == nil {
+		panic("BalanceStorageMock.GetForUpdateFunc: method is nil but BalanceStorage.GetForUpdate was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserID uuid.UUID
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+	}
+	mock.lockGetForUpdate.Lock()
+	mock.calls.GetForUpdate = append(mock.calls.GetForUpdate, callInfo)
+	mock.lockGetForUpdate.Unlock()
+	return mock.GetForUpdateFunc(ctx, userID)
+}
+
+// GetForUpdateCalls gets all the calls that were made to GetForUpdate.
+// Check the length with:
+//
+//	len(mockedBalanceStorage.GetForUpdateCalls())
+func (mock *BalanceStorageMock) GetForUpdateCalls() []struct {
+	Ctx    context.Context
+	UserID uuid.UUID
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID uuid.UUID
+	}
+	mock.lockGetForUpdate.RLock()
+	calls = mock.calls.GetForUpdate
+	mock.lockGetForUpdate.RUnlock()
+	return calls
+}
+
+// SelectUnreconciled calls SelectUnreconciledFunc.
+func (mock *BalanceStorageMock) SelectUnreconciled(ctx context.Context) ([]model.Reconciliation, error) {
+	if mock.SelectUnreconciledFunc == nil {
+		panic("BalanceStorageMock.SelectUnreconciledFunc: method is nil but BalanceStorage.SelectUnreconciled was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockSelectUnreconciled.Lock()
+	mock.calls.SelectUnreconciled = append(mock.calls.SelectUnreconciled, callInfo)
+	mock.lockSelectUnreconciled.Unlock()
+	return mock.SelectUnreconciledFunc(ctx)
+}
+
+// SelectUnreconciledCalls gets all the calls that were made to SelectUnreconciled.
+// Check the length with:
+//
+//	len(mockedBalanceStorage.SelectUnreconciledCalls())
+func (mock *BalanceStorageMock) SelectUnreconciledCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockSelectUnreconciled.RLock()
+	calls = mock.calls.SelectUnreconciled
+	mock.lockSelectUnreconciled.RUnlock()
+	return calls
+}
+
+// SetFrozen calls SetFrozenFunc.
+func (mock *BalanceStorageMock) SetFrozen(ctx context.Context, userID uuid.UUID, frozen bool) error {
+	if mock.SetFrozenFunc == nil {
+		panic("BalanceStorageMock.SetFrozenFunc: method is nil but BalanceStorage.SetFrozen was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserID uuid.UUID
+		Frozen bool
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+		Frozen: frozen,
+	}
+	mock.lockSetFrozen.Lock()
+	mock.calls.SetFrozen = append(mock.calls.SetFrozen, callInfo)
+	mock.lockSetFrozen.Unlock()
+	return mock.SetFrozenFunc(ctx, userID, frozen)
+}
+
+// SetFrozenCalls gets all the calls that were made to SetFrozen.
+// Check the length with:
+//
+//	len(mockedBalanceStorage.SetFrozenCalls())
+func (mock *BalanceStorageMock) SetFrozenCalls() []struct {
+	Ctx    context.Context
+	UserID uuid.UUID
+	Frozen bool
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID uuid.UUID
+		Frozen bool
+	}
+	mock.lockSetFrozen.RLock()
+	calls = mock.calls.SetFrozen
+	mock.lockSetFrozen.RUnlock()
+	return calls
+}
+
 // Update calls UpdateFunc.
 func (mock *BalanceStorageMock) Update(ctx context.Context, balance model.Balance) error {
 	if mock.UpdateFunc == nil {
@@ -252,39 +331,3 @@ func (mock *BalanceStorageMock) UpdateCalls() []struct {
 	mock.lockUpdate.RUnlock()
 	return calls
 }
-
-// WithTransaction calls WithTransactionFunc.
-func (mock *BalanceStorageMock) WithTransaction(ctx context.Context, fn func(tx postgres.Client) error) error {
-	if mock.WithTransactionFunc == nil {
-		panic("BalanceStorageMock.WithTransactionFunc: method is nil but BalanceStorage.WithTransaction was just called")
-	}
-	callInfo := struct {
-		Ctx context.Context
-		Fn  func(tx postgres.Client) error
-	}{
-		Ctx: ctx,
-		Fn:  fn,
-	}
-	mock.lockWithTransaction.Lock()
-	mock.calls.WithTransaction = append(mock.calls.WithTransaction, callInfo)
-	mock.lockWithTransaction.Unlock()
-	return mock.WithTransactionFunc(ctx, fn)
-}
-
-// WithTransactionCalls gets all the calls that were made to WithTransaction.
-// Check the length with:
-//
-//	len(mockedBalanceStorage.WithTransactionCalls())
-func (mock *BalanceStorageMock) WithTransactionCalls() []struct {
-	Ctx context.Context
-	Fn  func(tx postgres.Client) error
-} {
-	var calls []struct {
-		Ctx context.Context
-		Fn  func(tx postgres.Client) error
-	}
-	mock.lockWithTransaction.RLock()
-	calls = mock.calls.WithTransaction
-	mock.lockWithTransaction.RUnlock()
-	return calls
-}
@@ -0,0 +1,225 @@
+package storage_test
+
+import (
+	"bank/internal/model"
+	"bank/internal/pgtest"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"bank/pkg/logger"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestBalanceStorage_CreateGet(t *testing.T) {
+	ctx := context.Background()
+	balanceStorage := storage.NewBalanceStorage(pgtest.Pool(t))
+
+	balance := model.Balance{UserID: uuid.New(), Balance: 1050}
+	require.NoError(t, balanceStorage.Create(ctx, balance))
+
+	got, err := balanceStorage.Get(ctx, balance.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, balance, got)
+
+	_, err = balanceStorage.Get(ctx, uuid.New())
+	assert.ErrorIs(t, err, apperror.NotFound)
+
+	assert.ErrorIs(t, balanceStorage.Create(ctx, balance), apperror.Internal, "duplicate user id")
+}
+
+func TestBalanceStorage_Update(t *testing.T) {
+	ctx := context.Background()
+	balanceStorage := storage.NewBalanceStorage(pgtest.Pool(t))
+
+	balance := model.Balance{UserID: uuid.New(), Balance: 100}
+	require.NoError(t, balanceStorage.Create(ctx, balance))
+
+	balance.Balance = 250
+	require.NoError(t, balanceStorage.Update(ctx, balance))
+
+	got, err := balanceStorage.Get(ctx, balance.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(250), got.Balance)
+}
+
+func TestBalanceStorage_SetFrozen(t *testing.T) {
+	ctx := context.Background()
+	balanceStorage := storage.NewBalanceStorage(pgtest.Pool(t))
+
+	userID := uuid.New()
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: userID}))
+
+	require.NoError(t, balanceStorage.SetFrozen(ctx, userID, true))
+	got, err := balanceStorage.Get(ctx, userID)
+	require.NoError(t, err)
+	assert.True(t, got.Frozen)
+
+	require.NoError(t, balanceStorage.SetFrozen(ctx, userID, false))
+	got, err = balanceStorage.Get(ctx, userID)
+	require.NoError(t, err)
+	assert.False(t, got.Frozen)
+
+	assert.ErrorIs(t, balanceStorage.SetFrozen(ctx, uuid.New(), true), apperror.NotFound)
+}
+
+func TestTransactor_WithTransaction(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name    string
+		fn      func(ctx context.Context) error
+		panics  bool
+		wantErr error
+		want    int64
+	}{
+		{
+			name: "commit",
+			fn:   func(ctx context.Context) error { return nil },
+			want: 500,
+		},
+		{
+			name:    "rollback on error",
+			fn:      func(ctx context.Context) error { return errFailed },
+			wantErr: errFailed,
+			want:    100,
+		},
+		{
+			name:   "rollback on panic",
+			fn:     func(ctx context.Context) error { panic("boom") },
+			panics: true,
+			want:   100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			pool := pgtest.Pool(t)
+			transactor := storage.NewTransactor(pool, storage.RetryPolicy{}, logger.New(io.Discard, "info"))
+			balanceStorage := storage.NewBalanceStorage(pool)
+
+			userID := uuid.New()
+			require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: userID, Balance: 100}))
+
+			run := func() error {
+				return transactor.WithTransaction(ctx, func(ctx context.Context) error {
+					if err := balanceStorage.Update(ctx, model.Balance{UserID: userID, Balance: 500}); err != nil {
+						return err
+					}
+
+					return tt.fn(ctx)
+				})
+			}
+
+			if tt.panics {
+				assert.Panics(t, func() { _ = run() })
+			} else {
+				assert.ErrorIs(t, run(), tt.wantErr)
+			}
+
+			got, err := balanceStorage.Get(ctx, userID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Balance)
+		})
+	}
+}
+
+func TestTransactor_Savepoint(t *testing.T) {
+	ctx := context.Background()
+	pool := pgtest.Pool(t)
+	transactor := storage.NewTransactor(pool, storage.RetryPolicy{}, logger.New(io.Discard, "info"), storage.WithIsolation(pgx.RepeatableRead))
+	balanceStorage := storage.NewBalanceStorage(pool)
+
+	userID := uuid.New()
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: userID, Balance: 100}))
+
+	err := transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, balanceStorage.Update(ctx, model.Balance{UserID: userID, Balance: 200}))
+
+		err := transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			require.NoError(t, balanceStorage.Update(ctx, model.Balance{UserID: userID, Balance: 300}))
+			return errors.New("failed")
+		})
+		require.Error(t, err)
+
+		got, err := balanceStorage.Get(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(200), got.Balance, "the savepoint is rolled back")
+
+		err = transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			return nil
+		}, storage.WithIsolation(pgx.Serializable))
+		assert.ErrorIs(t, err, apperror.Internal, "a savepoint at another isolation level")
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	got, err := balanceStorage.Get(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), got.Balance)
+}
+
+func TestBalanceStorage_GetForUpdate(t *testing.T) {
+	ctx := context.Background()
+	pool := pgtest.Pool(t)
+	transactor := storage.NewTransactor(pool, storage.RetryPolicy{}, logger.New(io.Discard, "info"))
+	balanceStorage := storage.NewBalanceStorage(pool)
+
+	userID := uuid.New()
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: userID, Balance: 100}))
+
+	err := transactor.WithTransaction(ctx, func(txCtx context.Context) error {
+		locked, err := balanceStorage.GetForUpdate(txCtx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), locked.Balance)
+
+		// a plain read is not blocked by the row lock
+		_, err = balanceStorage.Get(ctx, userID)
+		require.NoError(t, err)
+
+		// a locking read of another transaction waits for the lock until its context ends
+		waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+
+		err = transactor.WithTransaction(waitCtx, func(ctx context.Context) error {
+			_, err := balanceStorage.GetForUpdate(ctx, userID)
+			return err
+		})
+		assert.ErrorIs(t, err, apperror.Internal)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBalanceStorage_SelectUnreconciled(t *testing.T) {
+	ctx := context.Background()
+	pool := newPool(t)
+	balanceStorage := storage.NewBalanceStorage(pool)
+	transactionStorage := storage.NewTransactionStorage(pool)
+
+	reconciled, drifted, empty := uuid.New(), uuid.New(), uuid.New()
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: reconciled, Balance: 300}))
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: drifted, Balance: 1000}))
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: empty}))
+
+	for _, transaction := range []model.Transaction{
+		newTransaction(reconciled, 500),
+		newTransaction(reconciled, -200),
+		newTransaction(drifted, 900),
+	} {
+		require.NoError(t, transactionStorage.Create(ctx, transaction))
+	}
+
+	got, err := balanceStorage.SelectUnreconciled(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Reconciliation{{UserID: drifted, Balance: 1000, Ledger: 900}}, got)
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"bank/internal/model"
+	"bank/internal/storage"
+	"context"
+	"github.com/google/uuid"
+	"time"
+)
+
+type auditStorage struct {
+	store *Store
+}
+
+func NewAuditStorage(store *Store) storage.AuditStorage {
+	return &auditStorage{store: store}
+}
+
+// Create links audit to the last entry, transactions of the store already apply one after another.
+func (storage *auditStorage) Create(ctx context.Context, audit model.Audit) (model.Audit, error) {
+	err := storage.store.write(ctx, func(tx *Tx) error {
+		audit.PrevHash = ""
+		if n := len(tx.state.audits); n != 0 {
+			audit.PrevHash = tx.state.audits[n-1].Hash
+		}
+
+		// keep the precision of postgres, so the chain verifies the same way on both storages
+		audit.CreatedAt = audit.CreatedAt.UTC().Truncate(time.Microsecond)
+		audit.Hash = audit.Digest()
+		audit.ID = storage.store.next()
+
+		tx.state.audits = append(tx.state.audits, audit)
+
+		return nil
+	})
+
+	return audit, err
+}
+
+func (storage *auditStorage) Select(ctx context.Context, filter storage.AuditFilter) ([]model.Audit, error) {
+	data, err := storage.store.read(ctx)
+	if err != nil {
+		return []model.Audit{}, err
+	}
+
+	audits := []model.Audit{}
+	for _, audit := range data.audits {
+		if filter.AccountID != uuid.Nil && audit.AccountID != filter.AccountID ||
+			filter.Actor != "" && audit.Actor != filter.Actor ||
+			filter.Operation != "" && audit.Operation != filter.Operation ||
+			filter.RequestID != "" && audit.RequestID != filter.RequestID ||
+			!filter.From.IsZero() && audit.CreatedAt.Before(filter.From) ||
+			!filter.To.IsZero() && !audit.CreatedAt.Before(filter.To) ||
+			audit.ID <= filter.AfterID {
+			continue
+		}
+
+		audits = append(audits, audit)
+	}
+
+	if filter.Order == "desc" {
+		for i, j := 0, len(audits)-1; i < j; i, j = i+1, j-1 {
+			audits[i], audits[j] = audits[j], audits[i]
+		}
+	}
+
+	offset := min(filter.Offset, uint64(len(audits)))
+	audits = audits[offset:]
+
+	if filter.Count != 0 && filter.Count < uint64(len(audits)) {
+		audits = audits[:filter.Count]
+	}
+
+	return audits, nil
+}
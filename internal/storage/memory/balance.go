@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"bank/internal/domain"
+	"bank/internal/model"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"slices"
+)
+
+type balanceStorage struct {
+	store *Store
+}
+
+func NewBalanceStorage(store *Store) storage.BalanceStorage {
+	return &balanceStorage{store: store}
+}
+
+func (storage *balanceStorage) Create(ctx context.Context, balance model.Balance) error {
+	return storage.store.write(ctx, func(tx *Tx) error {
+		if _, ok := tx.state.balances[balance.UserID]; ok {
+			return apperror.Internal.WithError(fmt.Errorf("duplicate balance %s", balance.UserID))
+		}
+
+		tx.writeBalances()[balance.UserID] = balance
+
+		return nil
+	})
+}
+
+func (storage *balanceStorage) Update(ctx context.Context, balance model.Balance) error {
+	return storage.store.write(ctx, func(tx *Tx) error {
+		current, ok := tx.state.balances[balance.UserID]
+		if !ok {
+			return nil
+		}
+
+		current.Balance = balance.Balance
+		tx.writeBalances()[balance.UserID] = current
+
+		activity := domain.BalanceFromModel(current)
+		tx.activities = append(tx.activities, domain.Activity{
+			Kind:      domain.ActivityBalance,
+			AccountID: current.UserID,
+			Balance:   &activity,
+		})
+
+		return nil
+	})
+}
+
+func (storage *balanceStorage) Get(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+	data, err := storage.store.read(ctx)
+	if err != nil {
+		return model.Balance{}, err
+	}
+
+	balance, ok := data.balances[userID]
+	if !ok {
+		return model.Balance{}, apperror.NotFound
+	}
+
+	return balance, nil
+}
+
+// GetForUpdate needs no row lock, transactions of the store apply one after another.
+func (storage *balanceStorage) GetForUpdate(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+	return storage.Get(ctx, userID)
+}
+
+func (storage *balanceStorage) SetFrozen(ctx context.Context, userID uuid.UUID, frozen bool) error {
+	return storage.store.write(ctx, func(tx *Tx) error {
+		balance, ok := tx.state.balances[userID]
+		if !ok {
+			return apperror.NotFound
+		}
+
+		balance.Frozen = frozen
+		tx.writeBalances()[userID] = balance
+
+		return nil
+	})
+}
+
+// SelectUnreconciled returns the balances that differ from the sum of their transactions.
+func (storage *balanceStorage) SelectUnreconciled(ctx context.Context) ([]model.Reconciliation, error) {
+	data, err := storage.store.read(ctx)
+	if err != nil {
+		return []model.Reconciliation{}, err
+	}
+
+	ledgers := make(map[uuid.UUID]int64, len(data.balances))
+	for _, transaction := range data.transactions {
+		ledgers[transaction.PayeeID] += transaction.Amount
+	}
+
+	reconciliations := []model.Reconciliation{}
+	for userID, balance := range data.balances {
+		if balance.Balance != ledgers[userID] {
+			reconciliations = append(reconciliations, model.Reconciliation{
+				UserID:  userID,
+				Balance: balance.Balance,
+				Ledger:  ledgers[userID],
+			})
+		}
+	}
+
+	slices.SortFunc(reconciliations, func(a, b model.Reconciliation) int {
+		return compareUUID(a.UserID, b.UserID)
+	})
+
+	return reconciliations, nil
+}
@@ -0,0 +1,221 @@
+// Package memory implements the storages in process memory, so the service runs without Postgres in tests and
+// demos. Webhook and shared rate limit storages are not implemented.
+package memory
+
+import (
+	"bank/internal/domain"
+	"bank/internal/metrics"
+	"bank/internal/model"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	errTxClosed = errors.New("transaction is closed")
+	errForeign  = errors.New("transaction belongs to another store")
+)
+
+// state is a version of the data. A committed state is never modified: transactions write to a copy of the
+// tables they change and replace the committed state with it.
+type state struct {
+	balances     map[uuid.UUID]model.Balance
+	transactions []model.Transaction
+	audits       []model.Audit
+	events       []model.Event
+	snapshots    []model.Snapshot
+	snapshotDays []time.Time
+}
+
+// Store holds the data shared by the memory storages of one database.
+type Store struct {
+	// mu is held by the writing transaction, so transactions apply one after another.
+	mu        sync.Mutex
+	committed atomic.Pointer[state]
+	sequence  int64
+	onCommit  func(activity domain.Activity)
+}
+
+func NewStore() *Store {
+	store := &Store{}
+	store.committed.Store(&state{balances: make(map[uuid.UUID]model.Balance)})
+
+	return store
+}
+
+// OnCommit sets fn to receive the balance updates and new transactions of every committed transaction, like the
+// balance_activity notifications of the database.
+func (store *Store) OnCommit(fn func(activity domain.Activity)) *Store {
+	store.onCommit = fn
+	return store
+}
+
+// Tx is a transaction of the store, carried by the context passed to WithTransaction callbacks. The storages
+// read and write its uncommitted state when called with that context.
+type Tx struct {
+	store      *Store
+	state      *state
+	activities []domain.Activity
+	closed     bool
+
+	// set once the table is copied from the committed state
+	balances  bool
+	events    bool
+	snapshots bool
+}
+
+type txKey struct{}
+
+var _ storage.Transactor = (*Store)(nil)
+
+// WithTransaction runs fn on a copy-on-write version of the data and commits it if fn succeeds. An error, a panic
+// or the end of ctx rolls it back, leaving the data as it was. Readers outside the transaction see the committed
+// data until it ends. Nested calls run fn in a savepoint; transactions of the store apply one after another, so
+// they are serializable whatever isolation level opts ask for.
+func (store *Store) WithTransaction(ctx context.Context, fn func(ctx context.Context) error, _ ...storage.TxOption) (err error) {
+	if tx, ok := ctx.Value(txKey{}).(*Tx); ok {
+		if err = store.check(tx); err != nil {
+			return err
+		}
+
+		return tx.savepoint(ctx, fn)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if err = ctx.Err(); err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	tx := &Tx{store: store, state: store.committed.Load().clone()}
+
+	defer func() {
+		tx.closed = true
+
+		if p := recover(); p != nil {
+			metrics.ObserveTransaction(metrics.Rollback)
+			panic(p)
+		}
+
+		if err == nil && ctx.Err() != nil {
+			err = apperror.Internal.WithError(ctx.Err())
+		}
+		if err != nil {
+			metrics.ObserveTransaction(metrics.Rollback)
+			return
+		}
+
+		store.committed.Store(tx.state)
+		metrics.ObserveTransaction(metrics.Commit)
+
+		if store.onCommit != nil {
+			for _, activity := range tx.activities {
+				store.onCommit(activity)
+			}
+		}
+	}()
+
+	return fn(context.WithValue(ctx, txKey{}, tx))
+}
+
+// savepoint runs fn and restores the state of tx if it fails. Changes made by fn copy the tables again, so the
+// saved version stays intact.
+func (tx *Tx) savepoint(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	saved, balances, events, snapshots, activities := tx.state.clone(), tx.balances, tx.events, tx.snapshots, len(tx.activities)
+	tx.balances, tx.events, tx.snapshots = false, false, false
+
+	defer func() {
+		p := recover()
+		if p != nil || err != nil {
+			tx.state, tx.balances, tx.events, tx.snapshots, tx.activities = saved, balances, events, snapshots, tx.activities[:activities]
+		}
+		if p != nil {
+			panic(p)
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// read returns the data visible to ctx: the uncommitted state of its transaction, the committed state outside of
+// one.
+func (store *Store) read(ctx context.Context) (*state, error) {
+	if tx, ok := ctx.Value(txKey{}).(*Tx); ok {
+		if err := store.check(tx); err != nil {
+			return nil, err
+		}
+
+		return tx.state, nil
+	}
+
+	return store.committed.Load(), nil
+}
+
+// write runs fn in the transaction of ctx, or in a transaction of its own outside of one.
+func (store *Store) write(ctx context.Context, fn func(tx *Tx) error) error {
+	return store.WithTransaction(ctx, func(ctx context.Context) error {
+		return fn(ctx.Value(txKey{}).(*Tx))
+	})
+}
+
+func (store *Store) check(tx *Tx) error {
+	if tx.store != store {
+		return apperror.Internal.WithError(errForeign)
+	}
+	if tx.closed {
+		return apperror.Internal.WithError(errTxClosed)
+	}
+
+	return nil
+}
+
+// next returns the next value of the store sequence, used for the ids the database would generate. Like a
+// database sequence it is not rolled back.
+func (store *Store) next() int64 {
+	store.sequence++
+	return store.sequence
+}
+
+func (s *state) clone() *state {
+	c := *s
+	return &c
+}
+
+// The tables below are copied before the first change of a row in a transaction. Appending to the slices needs
+// no copy: a committed state never reads past its own length, and the next transaction starts after the current
+// one ended.
+
+func (tx *Tx) writeBalances() map[uuid.UUID]model.Balance {
+	if !tx.balances {
+		tx.state.balances = maps.Clone(tx.state.balances)
+		tx.balances = true
+	}
+
+	return tx.state.balances
+}
+
+func (tx *Tx) writeEvents() []model.Event {
+	if !tx.events {
+		tx.state.events = slices.Clone(tx.state.events)
+		tx.events = true
+	}
+
+	return tx.state.events
+}
+
+func (tx *Tx) writeSnapshots() []model.Snapshot {
+	if !tx.snapshots {
+		tx.state.snapshots = slices.Clone(tx.state.snapshots)
+		tx.snapshots = true
+	}
+
+	return tx.state.snapshots
+}
@@ -0,0 +1,562 @@
+package memory_test
+
+import (
+	"bank/internal/domain"
+	"bank/internal/dto"
+	"bank/internal/model"
+	"bank/internal/storage"
+	"bank/internal/storage/memory"
+	"bank/pkg/apperror"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var (
+	alice = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	bob   = uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+)
+
+type storages struct {
+	store       *memory.Store
+	balance     storage.BalanceStorage
+	transaction storage.TransactionStorage
+	audit       storage.AuditStorage
+	outbox      storage.OutboxStorage
+	activities  []domain.Activity
+}
+
+func newStorages(t *testing.T) *storages {
+	t.Helper()
+
+	s := &storages{}
+	s.store = memory.NewStore().OnCommit(func(activity domain.Activity) {
+		s.activities = append(s.activities, activity)
+	})
+	s.balance = memory.NewBalanceStorage(s.store)
+	s.transaction = memory.NewTransactionStorage(s.store)
+	s.audit = memory.NewAuditStorage(s.store)
+	s.outbox = memory.NewOutboxStorage(s.store)
+
+	ctx := context.Background()
+	require.NoError(t, s.balance.Create(ctx, model.Balance{UserID: alice, Balance: 100}))
+	require.NoError(t, s.balance.Create(ctx, model.Balance{UserID: bob}))
+
+	return s
+}
+
+// transfer moves amount from alice to bob in the transaction of ctx.
+func (s *storages) transfer(ctx context.Context, amount int64) error {
+	payer, err := s.balance.GetForUpdate(ctx, alice)
+	if err != nil {
+		return err
+	}
+
+	payee, err := s.balance.GetForUpdate(ctx, bob)
+	if err != nil {
+		return err
+	}
+
+	payer.Balance -= amount
+	payee.Balance += amount
+
+	if err = s.balance.Update(ctx, payer); err != nil {
+		return err
+	}
+	if err = s.balance.Update(ctx, payee); err != nil {
+		return err
+	}
+
+	return s.transaction.Create(ctx, model.Transaction{ID: uuid.New(), PayeeID: bob, PayerID: &payer.UserID, Type: domain.Transfer, Amount: amount, CreatedAt: time.Now()})
+}
+
+func (s *storages) balances(t *testing.T) (int64, int64) {
+	t.Helper()
+
+	payer, err := s.balance.Get(context.Background(), alice)
+	require.NoError(t, err)
+
+	payee, err := s.balance.Get(context.Background(), bob)
+	require.NoError(t, err)
+
+	return payer.Balance, payee.Balance
+}
+
+func TestStore_WithTransaction(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name    string
+		fn      func(s *storages, ctx context.Context, cancel context.CancelFunc) error
+		wantErr error
+		panics  bool
+		commit  bool
+	}{
+		{
+			name: "commit",
+			fn: func(s *storages, ctx context.Context, _ context.CancelFunc) error {
+				return s.transfer(ctx, 30)
+			},
+			commit: true,
+		},
+		{
+			name: "error rolls back",
+			fn: func(s *storages, ctx context.Context, _ context.CancelFunc) error {
+				if err := s.transfer(ctx, 30); err != nil {
+					return err
+				}
+
+				return errFailed
+			},
+			wantErr: errFailed,
+		},
+		{
+			name: "panic rolls back",
+			fn: func(s *storages, ctx context.Context, _ context.CancelFunc) error {
+				if err := s.transfer(ctx, 30); err != nil {
+					return err
+				}
+
+				panic("boom")
+			},
+			panics: true,
+		},
+		{
+			name: "canceled context rolls back",
+			fn: func(s *storages, ctx context.Context, cancel context.CancelFunc) error {
+				if err := s.transfer(ctx, 30); err != nil {
+					return err
+				}
+				cancel()
+
+				return nil
+			},
+			wantErr: apperror.Internal,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newStorages(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			run := func() error {
+				return s.store.WithTransaction(ctx, func(ctx context.Context) error {
+					return test.fn(s, ctx, cancel)
+				})
+			}
+
+			if test.panics {
+				assert.PanicsWithValue(t, "boom", func() { _ = run() })
+			} else {
+				err := run()
+				if test.wantErr != nil {
+					assert.ErrorIs(t, err, test.wantErr)
+				} else {
+					assert.NoError(t, err)
+				}
+			}
+
+			transactions, err := s.transaction.Select(context.Background(), bob, dto.SelectTransactionFilter{})
+			require.NoError(t, err)
+
+			payer, payee := s.balances(t)
+			if test.commit {
+				assert.Equal(t, int64(70), payer)
+				assert.Equal(t, int64(30), payee)
+				assert.Len(t, transactions, 1)
+				assert.Len(t, s.activities, 3)
+			} else {
+				assert.Equal(t, int64(100), payer)
+				assert.Equal(t, int64(0), payee)
+				assert.Empty(t, transactions)
+				assert.Empty(t, s.activities)
+			}
+		})
+	}
+}
+
+func TestStore_Isolation(t *testing.T) {
+	s := newStorages(t)
+	ctx := context.Background()
+
+	err := s.store.WithTransaction(ctx, func(txCtx context.Context) error {
+		require.NoError(t, s.transfer(txCtx, 40))
+
+		inside, err := s.balance.Get(txCtx, alice)
+		require.NoError(t, err)
+		assert.Equal(t, int64(60), inside.Balance, "the transaction reads its own writes")
+
+		payer, payee := s.balances(t)
+		assert.Equal(t, int64(100), payer, "readers outside see the committed data")
+		assert.Equal(t, int64(0), payee)
+
+		unreconciled, err := s.balance.SelectUnreconciled(ctx)
+		require.NoError(t, err)
+		assert.Len(t, unreconciled, 1, "alice has no transactions yet")
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	payer, payee := s.balances(t)
+	assert.Equal(t, int64(60), payer)
+	assert.Equal(t, int64(40), payee)
+}
+
+func TestStore_Savepoint(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name   string
+		inner  error
+		payer  int64
+		payee  int64
+		count  int
+		events int
+	}{
+		{
+			name:   "released",
+			payer:  50,
+			payee:  50,
+			count:  2,
+			events: 6,
+		},
+		{
+			name:   "rolled back",
+			inner:  errFailed,
+			payer:  70,
+			payee:  30,
+			count:  1,
+			events: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newStorages(t)
+
+			err := s.store.WithTransaction(context.Background(), func(ctx context.Context) error {
+				require.NoError(t, s.transfer(ctx, 30))
+
+				err := s.store.WithTransaction(ctx, func(ctx context.Context) error {
+					require.NoError(t, s.transfer(ctx, 20))
+					return test.inner
+				})
+				assert.ErrorIs(t, err, test.inner)
+
+				return nil
+			})
+			require.NoError(t, err)
+
+			transactions, err := s.transaction.Select(context.Background(), bob, dto.SelectTransactionFilter{})
+			require.NoError(t, err)
+
+			payer, payee := s.balances(t)
+			assert.Equal(t, test.payer, payer)
+			assert.Equal(t, test.payee, payee)
+			assert.Len(t, transactions, test.count)
+			assert.Len(t, s.activities, test.events)
+		})
+	}
+}
+
+func TestStore_ClosedTransaction(t *testing.T) {
+	s := newStorages(t)
+	ctx := context.Background()
+
+	var leaked context.Context
+	require.NoError(t, s.store.WithTransaction(ctx, func(ctx context.Context) error {
+		leaked = ctx
+		return nil
+	}))
+
+	_, err := s.balance.Get(leaked, alice)
+	assert.ErrorIs(t, err, apperror.Internal)
+
+	err = s.balance.Update(leaked, model.Balance{UserID: alice, Balance: 1})
+	assert.ErrorIs(t, err, apperror.Internal)
+
+	err = memory.NewStore().WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := s.balance.Get(ctx, alice)
+		return err
+	})
+	assert.ErrorIs(t, err, apperror.Internal, "a transaction of another store")
+}
+
+func TestBalanceStorage(t *testing.T) {
+	s := newStorages(t)
+	ctx := context.Background()
+
+	err := s.balance.Create(ctx, model.Balance{UserID: alice})
+	assert.ErrorIs(t, err, apperror.Internal)
+
+	_, err = s.balance.Get(ctx, uuid.New())
+	assert.ErrorIs(t, err, apperror.NotFound)
+
+	require.NoError(t, s.balance.SetFrozen(ctx, alice, true))
+	balance, err := s.balance.Get(ctx, alice)
+	require.NoError(t, err)
+	assert.True(t, balance.Frozen)
+
+	assert.ErrorIs(t, s.balance.SetFrozen(ctx, uuid.New(), true), apperror.NotFound)
+
+	require.NoError(t, s.balance.Update(ctx, model.Balance{UserID: alice, Balance: 5}))
+	balance, err = s.balance.Get(ctx, alice)
+	require.NoError(t, err)
+	assert.Equal(t, model.Balance{UserID: alice, Balance: 5, Frozen: true}, balance, "update keeps the frozen flag")
+
+	unreconciled, err := s.balance.SelectUnreconciled(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Reconciliation{{UserID: alice, Balance: 5}}, unreconciled)
+}
+
+func TestTransactionStorage(t *testing.T) {
+	s := newStorages(t)
+	ctx := context.Background()
+
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+	transactions := []model.Transaction{
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), PayeeID: alice, Type: domain.Debet, Amount: 300, CreatedAt: now},
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), PayeeID: alice, Type: domain.Credit, Amount: -100, CreatedAt: now.Add(time.Minute)},
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000003"), PayeeID: bob, Type: domain.Debet, Amount: 50, CreatedAt: now.Add(time.Minute)},
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000004"), PayeeID: alice, Type: domain.Debet, Amount: 200, CreatedAt: now.Add(2 * time.Minute)},
+	}
+	for _, transaction := range transactions {
+		require.NoError(t, s.transaction.Create(ctx, transaction))
+	}
+
+	tests := []struct {
+		name   string
+		filter dto.SelectTransactionFilter
+		want   []model.Transaction
+	}{
+		{
+			name: "unsorted",
+			want: []model.Transaction{transactions[0], transactions[1], transactions[3]},
+		},
+		{
+			name:   "amount desc",
+			filter: dto.SelectTransactionFilter{Sort: "amount", Order: "desc"},
+			want:   []model.Transaction{transactions[0], transactions[3], transactions[1]},
+		},
+		{
+			name:   "created_at desc",
+			filter: dto.SelectTransactionFilter{Sort: "created_at", Order: "desc"},
+			want:   []model.Transaction{transactions[3], transactions[1], transactions[0]},
+		},
+		{
+			name:   "amount asc",
+			filter: dto.SelectTransactionFilter{Sort: "amount", Order: "asc"},
+			want:   []model.Transaction{transactions[1], transactions[3], transactions[0]},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := s.transaction.Select(ctx, alice, test.filter)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+
+	filter := dto.SelectTransactionFilter{Sort: "amount", Order: "asc"}
+	filter.Count, filter.Offset = 1, 1
+	got, err := s.transaction.Select(ctx, alice, filter)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Transaction{transactions[3]}, got)
+
+	filter.Offset = 10
+	got, err = s.transaction.Select(ctx, alice, filter)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	got, err = s.transaction.SelectAfter(ctx, alice, transactions[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Transaction{transactions[1], transactions[3]}, got)
+
+	_, err = s.transaction.SelectAfter(ctx, alice, uuid.New())
+	assert.True(t, errors.Is(err, apperror.NotFound), "an unknown transaction cannot be resumed from")
+
+	_, err = s.transaction.SelectAfter(ctx, bob, transactions[0].ID)
+	assert.True(t, errors.Is(err, apperror.NotFound), "nor one of another account")
+
+	sum, err := s.transaction.Sum(ctx, alice, now.Add(time.Minute), now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), sum)
+}
+
+func TestSnapshotStorage(t *testing.T) {
+	s := newStorages(t)
+	snapshots := memory.NewSnapshotStorage(s.store)
+	ctx := context.Background()
+
+	day := time.Date(2023, time.June, 14, 0, 0, 0, 0, time.UTC)
+	for i, transaction := range []model.Transaction{
+		{PayeeID: alice, Amount: 300, CreatedAt: day.Add(10 * time.Hour)},
+		{PayeeID: bob, Amount: 50, CreatedAt: day.Add(23 * time.Hour)},
+		{PayeeID: alice, Amount: -100, CreatedAt: day.Add(24 * time.Hour)},
+		{PayeeID: alice, Amount: 40, CreatedAt: day.Add(72 * time.Hour)},
+	} {
+		transaction.ID = uuid.New()
+		transaction.Type = domain.Debet
+		require.NoError(t, s.transaction.Create(ctx, transaction), i)
+	}
+
+	pending, err := snapshots.Pending(ctx, day.AddDate(0, 0, 2).Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{day, day.AddDate(0, 0, 1)}, pending)
+
+	for _, pendingDay := range pending {
+		_, err = snapshots.Create(ctx, pendingDay)
+		require.NoError(t, err)
+	}
+
+	created, err := snapshots.Create(ctx, day)
+	require.NoError(t, err)
+	assert.Zero(t, created, "a snapshotted day is not taken again")
+
+	pending, err = snapshots.Pending(ctx, day.AddDate(0, 0, 2).Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	snapshot, err := snapshots.GetBefore(ctx, alice, day.AddDate(0, 0, 5))
+	require.NoError(t, err)
+	assert.Equal(t, day.AddDate(0, 0, 1), snapshot.Day)
+	assert.Equal(t, int64(200), snapshot.Balance)
+
+	snapshot, err = snapshots.GetBefore(ctx, bob, day.AddDate(0, 0, 5))
+	require.NoError(t, err)
+	assert.Equal(t, day, snapshot.Day, "days without transactions have no snapshot")
+	assert.Equal(t, int64(50), snapshot.Balance)
+
+	_, err = snapshots.GetBefore(ctx, alice, day.Add(23*time.Hour))
+	assert.True(t, errors.Is(err, apperror.NotFound))
+
+	// the row stamped exactly at the close of the day is left out of its snapshot and counted by the sum from it
+	snapshot, err = snapshots.GetBefore(ctx, alice, day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, day, snapshot.Day)
+	assert.Equal(t, int64(300), snapshot.Balance)
+
+	sum, err := s.transaction.Sum(ctx, alice, snapshot.Close(), snapshot.Close())
+	require.NoError(t, err)
+	assert.Equal(t, int64(-100), sum)
+
+	// a backdated row drops the snapshots of its payee from its day on, the next one adds up everything since
+	backdated := model.Transaction{ID: uuid.New(), PayeeID: alice, Type: domain.Debet, Amount: 5, CreatedAt: day.Add(time.Hour)}
+	require.NoError(t, s.transaction.Create(ctx, backdated))
+
+	_, err = snapshots.GetBefore(ctx, alice, day.AddDate(0, 0, 5))
+	assert.True(t, errors.Is(err, apperror.NotFound))
+
+	snapshot, err = snapshots.GetBefore(ctx, bob, day.AddDate(0, 0, 5))
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), snapshot.Balance, "other accounts keep their snapshots")
+
+	pending, err = snapshots.Pending(ctx, day.AddDate(0, 0, 4).Add(time.Minute))
+	require.NoError(t, err)
+	for _, pendingDay := range pending {
+		_, err = snapshots.Create(ctx, pendingDay)
+		require.NoError(t, err)
+	}
+
+	snapshot, err = snapshots.GetBefore(ctx, alice, day.AddDate(0, 0, 5))
+	require.NoError(t, err)
+	assert.Equal(t, day.AddDate(0, 0, 3), snapshot.Day)
+	assert.Equal(t, int64(245), snapshot.Balance)
+}
+
+func TestAuditStorage(t *testing.T) {
+	s := newStorages(t)
+	ctx := context.Background()
+
+	var created []model.Audit
+	for i, operation := range []string{domain.Debet, domain.Credit, domain.Debet} {
+		accountID := alice
+		if i == 1 {
+			accountID = bob
+		}
+
+		err := s.store.WithTransaction(ctx, func(ctx context.Context) error {
+			audit, err := s.audit.Create(ctx, model.Audit{Actor: "test", Operation: operation, AccountID: accountID, CreatedAt: time.Now()})
+			created = append(created, audit)
+			return err
+		})
+		require.NoError(t, err)
+	}
+
+	// a rolled back entry does not join the chain
+	_ = s.store.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := s.audit.Create(ctx, model.Audit{Operation: domain.Credit, AccountID: alice, CreatedAt: time.Now()})
+		require.NoError(t, err)
+		return errors.New("rollback")
+	})
+
+	audits, err := s.audit.Select(ctx, storage.AuditFilter{})
+	require.NoError(t, err)
+	require.Equal(t, created, audits)
+
+	prevHash := ""
+	for _, audit := range audits {
+		assert.Equal(t, prevHash, audit.PrevHash)
+		assert.Equal(t, audit.Digest(), audit.Hash)
+		prevHash = audit.Hash
+	}
+
+	audits, err = s.audit.Select(ctx, storage.AuditFilter{AccountID: alice, Order: "desc", Count: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []model.Audit{created[2]}, audits)
+
+	audits, err = s.audit.Select(ctx, storage.AuditFilter{AfterID: created[0].ID, Operation: domain.Debet})
+	require.NoError(t, err)
+	assert.Equal(t, []model.Audit{created[2]}, audits)
+}
+
+func TestOutboxStorage_Claim(t *testing.T) {
+	s := newStorages(t)
+	ctx := context.Background()
+
+	for _, eventType := range []string{domain.EventDebet, domain.EventCredit, domain.EventTransfer} {
+		require.NoError(t, s.outbox.Create(ctx, model.Event{EventID: uuid.New(), Type: eventType}))
+	}
+
+	claimed, err := s.outbox.Claim(ctx, 2, time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []string{domain.EventDebet, domain.EventCredit}, eventTypes(claimed))
+
+	other, err := s.outbox.Claim(ctx, 10, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []string{domain.EventTransfer}, eventTypes(other), "leased events are skipped")
+
+	require.NoError(t, s.outbox.MarkPublished(ctx, []int64{claimed[0].ID, other[0].ID}))
+
+	again, err := s.outbox.Claim(ctx, 10, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, again, "the credit is leased until it expires")
+
+	require.NoError(t, s.outbox.Create(ctx, model.Event{EventID: uuid.New(), Type: domain.EventDebet}))
+
+	expiring, err := s.outbox.Claim(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, expiring, 1)
+
+	again, err = s.outbox.Claim(ctx, 10, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, again, 1, "an expired lease is claimed again")
+	assert.Equal(t, expiring[0].ID, again[0].ID)
+}
+
+func eventTypes(events []model.Event) []string {
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+
+	return types
+}
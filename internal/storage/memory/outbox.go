@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"bank/internal/model"
+	"bank/internal/storage"
+	"context"
+	"time"
+)
+
+type outboxStorage struct {
+	store *Store
+}
+
+func NewOutboxStorage(store *Store) storage.OutboxStorage {
+	return &outboxStorage{store: store}
+}
+
+func (storage *outboxStorage) Create(ctx context.Context, event model.Event) error {
+	return storage.store.write(ctx, func(tx *Tx) error {
+		event.ID = storage.store.next()
+		event.PublishedAt = nil
+		tx.state.events = append(tx.state.events, event)
+
+		return nil
+	})
+}
+
+// Claim leases up to limit pending events, oldest first, by pushing their lease into the future. Leased events are
+// skipped until their lease expires.
+func (storage *outboxStorage) Claim(ctx context.Context, limit uint64, lease time.Duration) ([]model.Event, error) {
+	var claimed []model.Event
+	err := storage.store.write(ctx, func(tx *Tx) error {
+		claimed = []model.Event{}
+		now := time.Now()
+		leasedUntil := now.Add(lease)
+
+		events := tx.writeEvents()
+		for i := range events {
+			if uint64(len(claimed)) == limit {
+				break
+			}
+			if events[i].PublishedAt != nil || events[i].LeasedUntil != nil && events[i].LeasedUntil.After(now) {
+				continue
+			}
+
+			events[i].LeasedUntil = &leasedUntil
+			claimed = append(claimed, events[i])
+		}
+
+		return nil
+	})
+	if err != nil {
+		return []model.Event{}, err
+	}
+
+	return claimed, nil
+}
+
+func (storage *outboxStorage) MarkPublished(ctx context.Context, ids []int64) error {
+	published := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		published[id] = struct{}{}
+	}
+
+	return storage.store.write(ctx, func(tx *Tx) error {
+		now := time.Now()
+
+		events := tx.writeEvents()
+		for i := range events {
+			if _, ok := published[events[i].ID]; ok {
+				events[i].PublishedAt = &now
+			}
+		}
+
+		return nil
+	})
+}
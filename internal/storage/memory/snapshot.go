@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"bank/internal/model"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"context"
+	"github.com/google/uuid"
+	"slices"
+	"time"
+)
+
+type snapshotStorage struct {
+	store *Store
+}
+
+func NewSnapshotStorage(store *Store) storage.SnapshotStorage {
+	return &snapshotStorage{store: store}
+}
+
+func (storage *snapshotStorage) Pending(ctx context.Context, until time.Time) ([]time.Time, error) {
+	data, err := storage.store.read(ctx)
+	if err != nil {
+		return []time.Time{}, err
+	}
+
+	var from time.Time
+	if len(data.snapshotDays) != 0 {
+		from = slices.MaxFunc(data.snapshotDays, time.Time.Compare).AddDate(0, 0, 1)
+	} else {
+		for _, transaction := range data.transactions {
+			if day := startOfDay(transaction.CreatedAt); from.IsZero() || day.Before(from) {
+				from = day
+			}
+		}
+		if from.IsZero() {
+			return []time.Time{}, nil
+		}
+	}
+
+	days := []time.Time{}
+	for day := from; !day.After(startOfDay(until).AddDate(0, 0, -1)); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+
+	return days, nil
+}
+
+func (storage *snapshotStorage) Create(ctx context.Context, day time.Time) (int64, error) {
+	day = startOfDay(day)
+
+	var created int64
+	err := storage.store.write(ctx, func(tx *Tx) error {
+		if slices.ContainsFunc(tx.state.snapshotDays, day.Equal) {
+			return nil
+		}
+
+		var order []uuid.UUID
+		for _, transaction := range tx.state.transactions {
+			if !startOfDay(transaction.CreatedAt).Equal(day) {
+				continue
+			}
+			if _, ok := tx.state.balances[transaction.PayeeID]; !ok || slices.Contains(order, transaction.PayeeID) {
+				continue
+			}
+			order = append(order, transaction.PayeeID)
+		}
+
+		// the closing balance is the previous snapshot plus the transactions since it closed
+		now := time.Now()
+		for _, userID := range order {
+			previous, _ := latest(tx.state, userID, day)
+			balance := previous.Balance
+			for _, transaction := range tx.state.transactions {
+				if transaction.PayeeID == userID && (previous.Day.IsZero() || !transaction.CreatedAt.Before(previous.Close())) &&
+					transaction.CreatedAt.Before(day.AddDate(0, 0, 1)) {
+					balance += transaction.Amount
+				}
+			}
+
+			tx.state.snapshots = append(tx.state.snapshots, model.Snapshot{
+				UserID:    userID,
+				Day:       day,
+				Balance:   balance,
+				CreatedAt: now,
+			})
+		}
+		tx.state.snapshotDays = append(tx.state.snapshotDays, day)
+		created = int64(len(order))
+
+		return nil
+	})
+
+	return created, err
+}
+
+func (storage *snapshotStorage) GetBefore(ctx context.Context, userID uuid.UUID, at time.Time) (model.Snapshot, error) {
+	data, err := storage.store.read(ctx)
+	if err != nil {
+		return model.Snapshot{}, err
+	}
+
+	snapshot, ok := latest(data, userID, startOfDay(at))
+	if !ok {
+		return model.Snapshot{}, apperror.NotFound
+	}
+
+	return snapshot, nil
+}
+
+// latest returns the last snapshot of userID taken for a day before day.
+func latest(data *state, userID uuid.UUID, day time.Time) (model.Snapshot, bool) {
+	var found model.Snapshot
+	var ok bool
+	for _, snapshot := range data.snapshots {
+		if snapshot.UserID == userID && snapshot.Day.Before(day) && (!ok || snapshot.Day.After(found.Day)) {
+			found, ok = snapshot, true
+		}
+	}
+
+	return found, ok
+}
+
+// startOfDay returns the UTC midnight that starts the day of t.
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"bank/internal/domain"
+	"bank/internal/model"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"bank/pkg/sort"
+	"bytes"
+	"cmp"
+	"context"
+	"github.com/google/uuid"
+	"slices"
+	"time"
+)
+
+type transactionStorage struct {
+	store *Store
+}
+
+func NewTransactionStorage(store *Store) storage.TransactionStorage {
+	return &transactionStorage{store: store}
+}
+
+func (storage *transactionStorage) Create(ctx context.Context, transaction model.Transaction) error {
+	return storage.store.write(ctx, func(tx *Tx) error {
+		tx.state.transactions = append(tx.state.transactions, transaction)
+
+		// the snapshots of the payee from the day of a backdated row no longer add up, like the
+		// invalidate_balance_snapshot trigger drops them
+		day := startOfDay(transaction.CreatedAt)
+		stale := func(snapshot model.Snapshot) bool {
+			return snapshot.UserID == transaction.PayeeID && !snapshot.Day.Before(day)
+		}
+		if slices.ContainsFunc(tx.state.snapshots, stale) {
+			tx.state.snapshots = slices.DeleteFunc(tx.writeSnapshots(), stale)
+		}
+
+		activity := domain.TransactionFromModel(transaction)
+		tx.activities = append(tx.activities, domain.Activity{
+			Kind:        domain.ActivityTransaction,
+			AccountID:   transaction.PayeeID,
+			Transaction: &activity,
+		})
+
+		return nil
+	})
+}
+
+func (storage *transactionStorage) Select(ctx context.Context, userID uuid.UUID, filter sort.Filter) ([]model.Transaction, error) {
+	data, err := storage.store.read(ctx)
+	if err != nil {
+		return []model.Transaction{}, err
+	}
+
+	transactions := selectBy(data, func(transaction model.Transaction) bool {
+		return transaction.PayeeID == userID
+	})
+
+	if filter.GetSort() != "" {
+		slices.SortStableFunc(transactions, func(a, b model.Transaction) int {
+			var c int
+			switch filter.GetSort() {
+			case "amount":
+				c = cmp.Compare(a.Amount, b.Amount)
+			case "created_at":
+				c = a.CreatedAt.Compare(b.CreatedAt)
+			}
+
+			if filter.GetOrder() == "desc" {
+				return -c
+			}
+
+			return c
+		})
+	}
+
+	offset := min(filter.GetOffset(), uint64(len(transactions)))
+	transactions = transactions[offset:]
+
+	if count := filter.GetCount(); count != 0 && count < uint64(len(transactions)) {
+		transactions = transactions[:count]
+	}
+
+	return transactions, nil
+}
+
+func (storage *transactionStorage) SelectAfter(ctx context.Context, userID uuid.UUID, afterID uuid.UUID) ([]model.Transaction, error) {
+	data, err := storage.store.read(ctx)
+	if err != nil {
+		return []model.Transaction{}, err
+	}
+
+	i := slices.IndexFunc(data.transactions, func(transaction model.Transaction) bool {
+		return transaction.ID == afterID && transaction.PayeeID == userID
+	})
+	if i < 0 {
+		return []model.Transaction{}, apperror.NotFound
+	}
+
+	after := data.transactions[i]
+	transactions := selectBy(data, func(transaction model.Transaction) bool {
+		return transaction.PayeeID == userID && compareTransaction(transaction, after) > 0
+	})
+	slices.SortFunc(transactions, compareTransaction)
+
+	return transactions, nil
+}
+
+func (storage *transactionStorage) Sum(ctx context.Context, userID uuid.UUID, from, to time.Time) (int64, error) {
+	data, err := storage.store.read(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var sum int64
+	for _, transaction := range data.transactions {
+		if transaction.PayeeID == userID && !transaction.CreatedAt.Before(from) && !transaction.CreatedAt.After(to) {
+			sum += transaction.Amount
+		}
+	}
+
+	return sum, nil
+}
+
+func selectBy(data *state, match func(transaction model.Transaction) bool) []model.Transaction {
+	transactions := []model.Transaction{}
+	for _, transaction := range data.transactions {
+		if match(transaction) {
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	return transactions
+}
+
+// compareTransaction orders transactions by (created_at, id).
+func compareTransaction(a, b model.Transaction) int {
+	if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+		return c
+	}
+
+	return compareUUID(a.ID, b.ID)
+}
+
+func compareUUID(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
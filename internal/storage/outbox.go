@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"bank/internal/model"
+	"bank/pkg/apperror"
+	"bank/pkg/postgres"
+	"context"
+	"github.com/Masterminds/squirrel"
+	"time"
+)
+
+//go:generate moq -out outbox_mock.go . OutboxStorage
+type OutboxStorage interface {
+	Create(ctx context.Context, event model.Event) error
+	Claim(ctx context.Context, limit uint64, lease time.Duration) ([]model.Event, error)
+	MarkPublished(ctx context.Context, ids []int64) error
+}
+
+type outboxStorage struct {
+	client postgres.Client
+}
+
+func NewOutboxStorage(client postgres.Client) OutboxStorage {
+	return &outboxStorage{client: client}
+}
+
+func (storage *outboxStorage) Create(ctx context.Context, event model.Event) error {
+	builder := psql.
+		Insert("outbox").
+		Columns(
+			"event_id",
+			"type",
+			"account_ids",
+			"payload",
+			"created_at",
+		).
+		Values(
+			event.EventID,
+			event.Type,
+			event.AccountIDs,
+			event.Payload,
+			event.CreatedAt,
+		)
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	_, err = conn(ctx, storage.client).Exec(ctx, q, args...)
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	return nil
+}
+
+// Claim leases up to limit pending events, oldest first, by pushing their lease into the future. Events leased by
+// another relay are skipped until their lease expires, which lets several instances share one outbox and publishes
+// the events of a crashed or failed relay again.
+func (storage *outboxStorage) Claim(ctx context.Context, limit uint64, lease time.Duration) ([]model.Event, error) {
+	const q = `
+WITH claimed AS (
+    UPDATE outbox
+        SET leased_until = NOW() + $2 * INTERVAL '1 millisecond'
+        WHERE id IN (SELECT id
+                     FROM outbox
+                     WHERE published_at IS NULL
+                       AND (leased_until IS NULL OR leased_until <= NOW())
+                     ORDER BY id
+                     LIMIT $1 FOR UPDATE SKIP LOCKED)
+        RETURNING id, event_id, type, account_ids, payload, created_at, published_at, leased_until)
+SELECT *
+FROM claimed
+ORDER BY id`
+
+	var events []model.Event
+	err := conn(ctx, storage.client).Select(ctx, &events, q, limit, lease.Milliseconds())
+	if err != nil {
+		return []model.Event{}, apperror.Internal.WithError(err)
+	}
+
+	return events, nil
+}
+
+func (storage *outboxStorage) MarkPublished(ctx context.Context, ids []int64) error {
+	builder := psql.
+		Update("outbox").
+		Set("published_at", time.Now()).
+		Where(squirrel.Eq{"id": ids})
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	_, err = conn(ctx, storage.client).Exec(ctx, q, args...)
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package storage
+
+import (
+	"bank/internal/model"
+	"context"
+	"sync"
+	"time"
+)
+
+// Ensure, that OutboxStorageMock does implement OutboxStorage.
+// If this is not the case, regenerate this file with moq.
+var _ OutboxStorage = &OutboxStorageMock{}
+
+// OutboxStorageMock is a mock implementation of OutboxStorage.
+//
+//	func TestSomethingThatUsesOutboxStorage(t *testing.T) {
+//
+//		// make and configure a mocked OutboxStorage
+//		mockedOutboxStorage := &OutboxStorageMock{
+//			ClaimFunc: func(ctx context.Context, limit uint64, lease time.Duration) ([]model.Event, error) {
+//				panic("mock out the Claim method")
+//			},
+//			CreateFunc: func(ctx context.Context, event model.Event) error {
+//				panic("mock out the Create method")
+//			},
+//			MarkPublishedFunc: func(ctx context.Context, ids []int64) error {
+//				panic("mock out the MarkPublished method")
+//			},
+//		}
+//
+//		// use mockedOutboxStorage in code that requires OutboxStorage
+//		// and then make assertions.
+//
+//	}
+type OutboxStorageMock struct {
+	// ClaimFunc mocks the Claim method.
+	ClaimFunc func(ctx context.Context, limit uint64, lease time.Duration) ([]model.Event, error)
+
+	// CreateFunc mocks the Create method.
+	CreateFunc func(ctx context.Context, event model.Event) error
+
+	// MarkPublishedFunc mocks the MarkPublished method.
+	MarkPublishedFunc func(ctx context.Context, ids []int64) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Claim holds details about calls to the Claim method.
+		Claim []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Limit is the limit argument value.
+			Limit uint64
+			// Lease is the lease argument value.
+			Lease time.Duration
+		}
+		// Create holds details about calls to the Create method.
+		Create []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Event is the event argument value.
+			Event model.Event
+		}
+		// MarkPublished holds details about calls to the MarkPublished method.
+		MarkPublished []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Ids is the ids argument value.
+			Ids []int64
+		}
+	}
+	lockClaim         sync.RWMutex
+	lockCreate        sync.RWMutex
+	lockMarkPublished sync.RWMutex
+}
+
+// Claim calls ClaimFunc.
+func (mock *OutboxStorageMock) Claim(ctx context.Context, limit uint64, lease time.Duration) ([]model.Event, error) {
+	if mock.ClaimFunc == nil {
+		panic("OutboxStorageMock.ClaimFunc: method is nil but OutboxStorage.Claim was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Limit uint64
+		Lease time.Duration
+	}{
+		Ctx:   ctx,
+		Limit: limit,
+		Lease: lease,
+	}
+	mock.lockClaim.Lock()
+	mock.calls.Claim = append(mock.calls.Claim, callInfo)
+	mock.lockClaim.Unlock()
+	return mock.ClaimFunc(ctx, limit, lease)
+}
+
+// ClaimCalls gets all the calls that were made to Claim.
+// Check the length with:
+//
+//	len(mockedOutboxStorage.ClaimCalls())
+func (mock *OutboxStorageMock) ClaimCalls() []struct {
+	Ctx   context.Context
+	Limit uint64
+	Lease time.Duration
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Limit uint64
+		Lease time.Duration
+	}
+	mock.lockClaim.RLock()
+	calls = mock.calls.Claim
+	mock.lockClaim.RUnlock()
+	return calls
+}
+
+// Create calls CreateFunc.
+func (mock *OutboxStorageMock) Create(ctx context.Context, event model.Event) error {
+	if mock.CreateFunc == nil {
+		panic("OutboxStorageMock.CreateFunc: method is nil but OutboxStorage.Create was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Event model.Event
+	}{
+		Ctx:   ctx,
+		Event: event,
+	}
+	mock.lockCreate.Lock()
+	mock.calls.Create = append(mock.calls.Create, callInfo)
+	mock.lockCreate.Unlock()
+	return mock.CreateFunc(ctx, event)
+}
+
+// CreateCalls gets all the calls that were made to Create.
+// Check the length with:
+//
+//	len(mockedOutboxStorage.CreateCalls())
+func (mock *OutboxStorageMock) CreateCalls() []struct {
+	Ctx   context.Context
+	Event model.Event
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Event model.Event
+	}
+	mock.lockCreate.RLock()
+	calls = mock.calls.Create
+	mock.lockCreate.RUnlock()
+	return calls
+}
+
+// MarkPublished calls MarkPublishedFunc.
+func (mock *OutboxStorageMock) MarkPublished(ctx context.Context, ids []int64) error {
+	if mock.MarkPublishedFunc == nil {
+		panic("OutboxStorageMock.MarkPublishedFunc: method is nil but OutboxStorage.MarkPublished was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Ids []int64
+	}{
+		Ctx: ctx,
+		Ids: ids,
+	}
+	mock.lockMarkPublished.Lock()
+	mock.calls.MarkPublished = append(mock.calls.MarkPublished, callInfo)
+	mock.lockMarkPublished.Unlock()
+	return mock.MarkPublishedFunc(ctx, ids)
+}
+
+// MarkPublishedCalls gets all the calls that were made to MarkPublished.
+// Check the length with:
+//
+//	len(mockedOutboxStorage.MarkPublishedCalls())
+func (mock *OutboxStorageMock) MarkPublishedCalls() []struct {
+	Ctx context.Context
+	Ids []int64
+} {
+	var calls []struct {
+		Ctx context.Context
+		Ids []int64
+	}
+	mock.lockMarkPublished.RLock()
+	calls = mock.calls.MarkPublished
+	mock.lockMarkPublished.RUnlock()
+	return calls
+}
@@ -0,0 +1,52 @@
+package storage_test
+
+import (
+	"bank/internal/domain"
+	"bank/internal/model"
+	"bank/internal/pgtest"
+	"bank/internal/storage"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestOutboxStorage_Claim(t *testing.T) {
+	ctx := context.Background()
+	outboxStorage := storage.NewOutboxStorage(pgtest.Pool(t))
+
+	for _, eventType := range []string{domain.EventDebet, domain.EventCredit, domain.EventTransfer} {
+		require.NoError(t, outboxStorage.Create(ctx, model.Event{EventID: uuid.New(), Type: eventType, Payload: []byte(`{}`), CreatedAt: clock}))
+	}
+
+	claimed, err := outboxStorage.Claim(ctx, 2, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, domain.EventDebet, claimed[0].Type)
+	assert.Equal(t, domain.EventCredit, claimed[1].Type)
+	require.NotNil(t, claimed[0].LeasedUntil)
+
+	other, err := outboxStorage.Claim(ctx, 10, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, other, 1, "leased events are skipped")
+	assert.Equal(t, domain.EventTransfer, other[0].Type)
+
+	require.NoError(t, outboxStorage.MarkPublished(ctx, []int64{claimed[0].ID, other[0].ID}))
+
+	again, err := outboxStorage.Claim(ctx, 10, time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, again, "the credit is leased until it expires")
+
+	require.NoError(t, outboxStorage.Create(ctx, model.Event{EventID: uuid.New(), Type: domain.EventDebet, Payload: []byte(`{}`), CreatedAt: clock}))
+
+	expiring, err := outboxStorage.Claim(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, expiring, 1)
+
+	again, err = outboxStorage.Claim(ctx, 10, time.Hour)
+	require.NoError(t, err)
+	require.Len(t, again, 1, "an expired lease is claimed again")
+	assert.Equal(t, expiring[0].ID, again[0].ID)
+}
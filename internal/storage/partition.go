@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"bank/internal/model"
+	"bank/pkg/apperror"
+	"bank/pkg/postgres"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"strings"
+	"time"
+)
+
+//go:generate moq -out partition_mock.go . PartitionStorage
+type PartitionStorage interface {
+	// Create makes sure the transaction table has a partition for every month from from to to.
+	Create(ctx context.Context, from, to time.Time) error
+	// Select returns the partitions of the transaction table and of its archive, oldest first, without the default
+	// partition.
+	Select(ctx context.Context) ([]model.Partition, error)
+	// Unpartitioned returns the UTC months, oldest first, of the rows kept in the default partition for lack of a
+	// partition of their own.
+	Unpartitioned(ctx context.Context) ([]time.Time, error)
+	// Archive detaches partition from the transaction table and attaches it to transaction_archive, in
+	// tablespace unless that is empty. It should run in a transaction, so a failure leaves the partition in place.
+	Archive(ctx context.Context, partition model.Partition, tablespace string) (model.Partition, error)
+}
+
+// archivePrefix replaces the transaction_ prefix of archived partitions, so their names stay clear of those of the
+// hot partitions. A late row of an archived month is kept in the default partition.
+const archivePrefix = "transaction_archive_"
+
+type partitionStorage struct {
+	client postgres.Client
+}
+
+func NewPartitionStorage(client postgres.Client) PartitionStorage {
+	return &partitionStorage{client: client}
+}
+
+func (storage *partitionStorage) Create(ctx context.Context, from, to time.Time) error {
+	const q = `
+SELECT create_transaction_partition(month AT TIME ZONE 'UTC')
+FROM GENERATE_SERIES(DATE_TRUNC('month', $1::TIMESTAMPTZ AT TIME ZONE 'UTC'), $2::TIMESTAMPTZ AT TIME ZONE 'UTC',
+                     INTERVAL '1 month') AS month`
+
+	_, err := conn(ctx, storage.client).Exec(ctx, q, from, to)
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	return nil
+}
+
+func (storage *partitionStorage) Select(ctx context.Context) ([]model.Partition, error) {
+	// the bounds are only available as the text of the partition expression: FOR VALUES FROM ('...') TO ('...')
+	const q = `
+SELECT c.relname                                                                            AS name,
+       SUBSTRING(PG_GET_EXPR(c.relpartbound, c.oid) FROM 'FROM \(''([^'']+)''\)')::TIMESTAMPTZ AS "from",
+       SUBSTRING(PG_GET_EXPR(c.relpartbound, c.oid) FROM 'TO \(''([^'']+)''\)')::TIMESTAMPTZ   AS "to",
+       i.inhparent = 'transaction_archive'::REGCLASS                                        AS archived
+FROM pg_inherits i
+         JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent IN ('transaction'::REGCLASS, 'transaction_archive'::REGCLASS)
+  AND PG_GET_EXPR(c.relpartbound, c.oid) <> 'DEFAULT'
+ORDER BY "from", archived DESC`
+
+	var partitions []model.Partition
+	err := conn(ctx, storage.client).Select(ctx, &partitions, q)
+	if err != nil {
+		return []model.Partition{}, apperror.Internal.WithError(err)
+	}
+
+	return partitions, nil
+}
+
+func (storage *partitionStorage) Unpartitioned(ctx context.Context) ([]time.Time, error) {
+	const q = `
+SELECT DISTINCT DATE_TRUNC('month', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS month
+FROM transaction_default
+ORDER BY month`
+
+	var months []time.Time
+	err := conn(ctx, storage.client).Select(ctx, &months, q)
+	if err != nil {
+		return []time.Time{}, apperror.Internal.WithError(err)
+	}
+
+	return months, nil
+}
+
+func (storage *partitionStorage) Archive(ctx context.Context, partition model.Partition, tablespace string) (model.Partition, error) {
+	if partition.Archived {
+		return partition, nil
+	}
+
+	archived := model.Partition{
+		Name:     archivePrefix + strings.TrimPrefix(partition.Name, "transaction_"),
+		From:     partition.From,
+		To:       partition.To,
+		Archived: true,
+	}
+
+	name, archivedName := pgx.Identifier{partition.Name}.Sanitize(), pgx.Identifier{archived.Name}.Sanitize()
+
+	_, err := conn(ctx, storage.client).Exec(ctx, "ALTER TABLE transaction DETACH PARTITION "+name)
+	if err != nil {
+		return partition, apperror.Internal.WithError(err)
+	}
+
+	// the foreign keys cloned to a partition are named after it, they are looked up rather than guessed
+	var foreignKeys []string
+	err = conn(ctx, storage.client).Select(ctx, &foreignKeys,
+		"SELECT conname FROM pg_constraint WHERE conrelid = TO_REGCLASS(QUOTE_IDENT($1)) AND contype = 'f' ORDER BY conname", partition.Name)
+	if err != nil {
+		return partition, apperror.Internal.WithError(err)
+	}
+
+	var statements []string
+	// cold rows are neither checked against the balances nor announced
+	for _, foreignKey := range foreignKeys {
+		statements = append(statements, "ALTER TABLE "+name+" DROP CONSTRAINT "+pgx.Identifier{foreignKey}.Sanitize())
+	}
+	statements = append(statements,
+		"DROP TRIGGER IF EXISTS transaction_activity ON "+name,
+		"DROP TRIGGER IF EXISTS transaction_balance_snapshot ON "+name,
+		"ALTER TABLE "+name+" RENAME TO "+archivedName,
+	)
+	if tablespace != "" {
+		statements = append(statements, "ALTER TABLE "+archivedName+" SET TABLESPACE "+pgx.Identifier{tablespace}.Sanitize())
+	}
+	statements = append(statements, fmt.Sprintf("ALTER TABLE transaction_archive ATTACH PARTITION %s FOR VALUES FROM (%s) TO (%s)",
+		archivedName, timestamp(partition.From), timestamp(partition.To)))
+
+	for _, statement := range statements {
+		if _, err = conn(ctx, storage.client).Exec(ctx, statement); err != nil {
+			return partition, apperror.Internal.WithError(err)
+		}
+	}
+
+	return archived, nil
+}
+
+// timestamp quotes t as a timestamptz literal, DDL takes no parameters.
+func timestamp(t time.Time) string {
+	return "'" + t.UTC().Format("2006-01-02 15:04:05") + "+00'"
+}
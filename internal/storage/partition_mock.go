@@ -0,0 +1,233 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package storage
+
+import (
+	"bank/internal/model"
+	"context"
+	"sync"
+	"time"
+)
+
+// Ensure, that PartitionStorageMock does implement PartitionStorage.
+// If this is not the case, regenerate this file with moq.
+var _ PartitionStorage = &PartitionStorageMock{}
+
+// PartitionStorageMock is a mock implementation of PartitionStorage.
+//
+//	func TestSomethingThatUsesPartitionStorage(t *testing.T) {
+//
+//		// make and configure a mocked PartitionStorage
+//		mockedPartitionStorage := &PartitionStorageMock{
+//			ArchiveFunc: func(ctx context.Context, partition model.Partition, tablespace string) (model.Partition, error) {
+//				panic("mock out the Archive method")
+//			},
+//			CreateFunc: func(ctx context.Context, from time.Time, to time.Time) error {
+//				panic("mock out the Create method")
+//			},
+//			SelectFunc: func(ctx context.Context) ([]model.Partition, error) {
+//				panic("mock out the Select method")
+//			},
+//			UnpartitionedFunc: func(ctx context.Context) ([]time.Time, error) {
+//				panic("mock out the Unpartitioned method")
+//			},
+//		}
+//
+//		// use mockedPartitionStorage in code that requires PartitionStorage
+//		// and then make assertions.
+//
+//	}
+type PartitionStorageMock struct {
+	// ArchiveFunc mocks the Archive method.
+	ArchiveFunc func(ctx context.Context, partition model.Partition, tablespace string) (model.Partition, error)
+
+	// CreateFunc mocks the Create method.
+	CreateFunc func(ctx context.Context, from time.Time, to time.Time) error
+
+	// SelectFunc mocks the Select method.
+	SelectFunc func(ctx context.Context) ([]model.Partition, error)
+
+	// UnpartitionedFunc mocks the Unpartitioned method.
+	UnpartitionedFunc func(ctx context.Context) ([]time.Time, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Archive holds details about calls to the Archive method.
+		Archive []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Partition is the partition argument value.
+			Partition model.Partition
+			// Tablespace is the tablespace argument value.
+			Tablespace string
+		}
+		// Create holds details about calls to the Create method.
+		Create []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// From is the from argument value.
+			From time.Time
+			// To is the to argument value.
+			To time.Time
+		}
+		// Select holds details about calls to the Select method.
+		Select []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Unpartitioned holds details about calls to the Unpartitioned method.
+		Unpartitioned []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+	}
+	lockArchive       sync.RWMutex
+	lockCreate        sync.RWMutex
+	lockSelect        sync.RWMutex
+	lockUnpartitioned sync.RWMutex
+}
+
+// Archive calls ArchiveFunc.
+func (mock *PartitionStorageMock) Archive(ctx context.Context, partition model.Partition, tablespace string) (model.Partition, error) {
+	if mock.ArchiveFunc == nil {
+		panic("PartitionStorageMock.ArchiveFunc: method is nil but PartitionStorage.Archive was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		Partition  model.Partition
+		Tablespace string
+	}{
+		Ctx:        ctx,
+		Partition:  partition,
+		Tablespace: tablespace,
+	}
+	mock.lockArchive.Lock()
+	mock.calls.Archive = append(mock.calls.Archive, callInfo)
+	mock.lockArchive.Unlock()
+	return mock.ArchiveFunc(ctx, partition, tablespace)
+}
+
+// ArchiveCalls gets all the calls that were made to Archive.
+// Check the length with:
+//
+//	len(mockedPartitionStorage.ArchiveCalls())
+func (mock *PartitionStorageMock) ArchiveCalls() []struct {
+	Ctx        context.Context
+	Partition  model.Partition
+	Tablespace string
+} {
+	var calls []struct {
+		Ctx        context.Context
+		Partition  model.Partition
+		Tablespace string
+	}
+	mock.lockArchive.RLock()
+	calls = mock.calls.Archive
+	mock.lockArchive.RUnlock()
+	return calls
+}
+
+// Create calls CreateFunc.
+func (mock *PartitionStorageMock) Create(ctx context.Context, from time.Time, to time.Time) error {
+	if mock.CreateFunc == nil {
+		panic("PartitionStorageMock.CreateFunc: method is nil but PartitionStorage.Create was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		From time.Time
+		To   time.Time
+	}{
+		Ctx:  ctx,
+		From: from,
+		To:   to,
+	}
+	mock.lockCreate.Lock()
+	mock.calls.Create = append(mock.calls.Create, callInfo)
+	mock.lockCreate.Unlock()
+	return mock.CreateFunc(ctx, from, to)
+}
+
+// CreateCalls gets all the calls that were made to Create.
+// Check the length with:
+//
+//	len(mockedPartitionStorage.CreateCalls())
+func (mock *PartitionStorageMock) CreateCalls() []struct {
+	Ctx  context.Context
+	From time.Time
+	To   time.Time
+} {
+	var calls []struct {
+		Ctx  context.Context
+		From time.Time
+		To   time.Time
+	}
+	mock.lockCreate.RLock()
+	calls = mock.calls.Create
+	mock.lockCreate.RUnlock()
+	return calls
+}
+
+// Select calls SelectFunc.
+func (mock *PartitionStorageMock) Select(ctx context.Context) ([]model.Partition, error) {
+	if mock.SelectFunc == nil {
+		panic("PartitionStorageMock.SelectFunc: method is nil but PartitionStorage.Select was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockSelect.Lock()
+	mock.calls.Select = append(mock.calls.Select, callInfo)
+	mock.lockSelect.Unlock()
+	return mock.SelectFunc(ctx)
+}
+
+// SelectCalls gets all the calls that were made to Select.
+// Check the length with:
+//
+//	len(mockedPartitionStorage.SelectCalls())
+func (mock *PartitionStorageMock) SelectCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockSelect.RLock()
+	calls = mock.calls.Select
+	mock.lockSelect.RUnlock()
+	return calls
+}
+
+// Unpartitioned calls UnpartitionedFunc.
+func (mock *PartitionStorageMock) Unpartitioned(ctx context.Context) ([]time.Time, error) {
+	if mock.UnpartitionedFunc == nil {
+		panic("PartitionStorageMock.UnpartitionedFunc: method is nil but PartitionStorage.Unpartitioned was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockUnpartitioned.Lock()
+	mock.calls.Unpartitioned = append(mock.calls.Unpartitioned, callInfo)
+	mock.lockUnpartitioned.Unlock()
+	return mock.UnpartitionedFunc(ctx)
+}
+
+// UnpartitionedCalls gets all the calls that were made to Unpartitioned.
+// Check the length with:
+//
+//	len(mockedPartitionStorage.UnpartitionedCalls())
+func (mock *PartitionStorageMock) UnpartitionedCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockUnpartitioned.RLock()
+	calls = mock.calls.Unpartitioned
+	mock.lockUnpartitioned.RUnlock()
+	return calls
+}
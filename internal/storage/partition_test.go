@@ -0,0 +1,136 @@
+package storage_test
+
+import (
+	"bank/internal/dto"
+	"bank/internal/model"
+	"bank/internal/storage"
+	"bank/pkg/logger"
+	"context"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestPartitionStorage_Create(t *testing.T) {
+	ctx := context.Background()
+	partitionStorage := storage.NewPartitionStorage(newPool(t))
+
+	from := time.Date(2023, 1, 31, 23, 0, 0, 0, time.UTC)
+	require.NoError(t, partitionStorage.Create(ctx, from, from.AddDate(0, 2, 0)))
+	require.NoError(t, partitionStorage.Create(ctx, from, from), "existing partitions are kept")
+
+	partitions, err := partitionStorage.Select(ctx)
+	require.NoError(t, err)
+
+	var names []string
+	for _, partition := range partitions {
+		if partition.From.Year() == 2023 && partition.From.Month() <= time.June {
+			names = append(names, partition.Name)
+		}
+	}
+	assert.Equal(t, []string{"transaction_2023_01", "transaction_2023_02", "transaction_2023_03", "transaction_2023_06"}, names)
+
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), partitions[0].From.UTC())
+	assert.Equal(t, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), partitions[0].To.UTC())
+}
+
+func TestPartitionStorage_Archive(t *testing.T) {
+	ctx := context.Background()
+	pool := newPool(t)
+	balanceStorage := storage.NewBalanceStorage(pool)
+	transactionStorage := storage.NewTransactionStorage(pool)
+	partitionStorage := storage.NewPartitionStorage(pool)
+
+	userID := uuid.New()
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: userID}))
+
+	old := newTransaction(userID, 100)
+	require.NoError(t, transactionStorage.Create(ctx, old))
+
+	partitions, err := partitionStorage.Select(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "transaction_2023_06", partitions[0].Name)
+
+	var archived model.Partition
+	err = storage.NewTransactor(pool, storage.RetryPolicy{}, logger.New(io.Discard, "info")).WithTransaction(ctx, func(ctx context.Context) error {
+		archived, err = partitionStorage.Archive(ctx, partitions[0], "")
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.Partition{Name: "transaction_archive_2023_06", From: partitions[0].From, To: partitions[0].To, Archived: true}, archived)
+
+	partitions, err = partitionStorage.Select(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, archived.Name, partitions[0].Name)
+	assert.True(t, partitions[0].Archived)
+
+	var foreignKeys int
+	require.NoError(t, pool.QueryRow(ctx,
+		"SELECT COUNT(*) FROM pg_constraint WHERE conrelid = 'transaction_archive_2023_06'::REGCLASS AND contype = 'f'").Scan(&foreignKeys))
+	assert.Zero(t, foreignKeys, "archived rows no longer reference the balances")
+
+	// a late row of the archived month lands in the default partition, the history spans both
+	late := newTransaction(userID, 200)
+	require.NoError(t, transactionStorage.Create(ctx, late))
+
+	got, err := transactionStorage.Select(ctx, userID, dto.SelectTransactionFilter{Sort: "created_at", Order: "asc"})
+	require.NoError(t, err)
+	assertTransactions(t, []model.Transaction{old, late}, got)
+
+	got, err = transactionStorage.SelectAfter(ctx, userID, old.ID)
+	require.NoError(t, err)
+	assertTransactions(t, []model.Transaction{late}, got)
+
+	months, err := partitionStorage.Unpartitioned(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{partitions[0].From.UTC()}, utc(months))
+
+	reconciliations, err := balanceStorage.SelectUnreconciled(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []model.Reconciliation{{UserID: userID, Balance: 0, Ledger: 300}}, reconciliations)
+}
+
+func TestPartitionStorage_Default(t *testing.T) {
+	ctx := context.Background()
+	pool := newPool(t)
+	balanceStorage := storage.NewBalanceStorage(pool)
+	transactionStorage := storage.NewTransactionStorage(pool)
+	partitionStorage := storage.NewPartitionStorage(pool)
+
+	userID := uuid.New()
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: userID}))
+
+	// a backdated row of a month without a partition
+	backdated := newTransaction(userID, 100)
+	backdated.CreatedAt = time.Date(2021, 3, 15, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, transactionStorage.Create(ctx, backdated))
+
+	months, err := partitionStorage.Unpartitioned(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)}, utc(months))
+
+	require.NoError(t, partitionStorage.Create(ctx, backdated.CreatedAt, backdated.CreatedAt))
+
+	months, err = partitionStorage.Unpartitioned(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, months, "the rows of the month moved to its partition")
+
+	partitions, err := partitionStorage.Select(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "transaction_2021_03", partitions[0].Name)
+
+	got, err := transactionStorage.Select(ctx, userID, dto.SelectTransactionFilter{})
+	require.NoError(t, err)
+	assertTransactions(t, []model.Transaction{backdated}, got)
+}
+
+func utc(times []time.Time) []time.Time {
+	for i := range times {
+		times[i] = times[i].UTC()
+	}
+
+	return times
+}
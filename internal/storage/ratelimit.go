@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"bank/pkg/apperror"
+	"bank/pkg/postgres"
+	"context"
+)
+
+//go:generate moq -out ratelimit_mock.go . RateLimitStorage
+type RateLimitStorage interface {
+	Take(ctx context.Context, key string, rate float64, burst int) (allowed bool, tokens float64, err error)
+	Sweep(ctx context.Context) (int64, error)
+}
+
+type rateLimitStorage struct {
+	client postgres.Client
+}
+
+func NewRateLimitStorage(client postgres.Client) RateLimitStorage {
+	return &rateLimitStorage{client: client}
+}
+
+// Take refills the bucket at key and consumes a token in one statement, so concurrent instances
+// sharing the table never hand out the same token twice. It returns the tokens left afterwards.
+func (storage *rateLimitStorage) Take(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+	const q = `
+INSERT INTO rate_limit_bucket AS b (key, tokens, allowed, rate, burst, updated_at)
+VALUES ($1, $3::float8 - 1, true, $2, $3, now())
+ON CONFLICT (key) DO UPDATE SET
+    tokens     = LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * $2::float8)
+                 - CASE WHEN LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * $2::float8) >= 1 THEN 1 ELSE 0 END,
+    allowed    = LEAST($3::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * $2::float8) >= 1,
+    rate       = $2,
+    burst      = $3,
+    updated_at = now()
+RETURNING allowed, tokens`
+
+	var (
+		allowed bool
+		tokens  float64
+	)
+	err := conn(ctx, storage.client).QueryRow(ctx, q, key, rate, burst).Scan(&allowed, &tokens)
+	if err != nil {
+		return false, 0, apperror.Internal.WithError(err)
+	}
+
+	return allowed, tokens, nil
+}
+
+// Sweep deletes buckets that have refilled completely, they are equal to new ones.
+func (storage *rateLimitStorage) Sweep(ctx context.Context) (int64, error) {
+	const q = `
+DELETE FROM rate_limit_bucket
+WHERE tokens + EXTRACT(EPOCH FROM now() - updated_at)::float8 * rate >= burst`
+
+	tag, err := conn(ctx, storage.client).Exec(ctx, q)
+	if err != nil {
+		return 0, apperror.Internal.WithError(err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,137 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// Ensure, that RateLimitStorageMock does implement RateLimitStorage.
+// If this is not the case, regenerate this file with moq.
+var _ RateLimitStorage = &RateLimitStorageMock{}
+
+// RateLimitStorageMock is a mock implementation of RateLimitStorage.
+//
+//	func TestSomethingThatUsesRateLimitStorage(t *testing.T) {
+//
+//		// make and configure a mocked RateLimitStorage
+//		mockedRateLimitStorage := &RateLimitStorageMock{
+//			SweepFunc: func(ctx context.Context) (int64, error) {
+//				panic("mock out the Sweep method")
+//			},
+//			TakeFunc: func(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+//				panic("mock out the Take method")
+//			},
+//		}
+//
+//		// use mockedRateLimitStorage in code that requires RateLimitStorage
+//		// and then make assertions.
+//
+//	}
+type RateLimitStorageMock struct {
+	// SweepFunc mocks the Sweep method.
+	SweepFunc func(ctx context.Context) (int64, error)
+
+	// TakeFunc mocks the Take method.
+	TakeFunc func(ctx context.Context, key string, rate float64, burst int) (bool, float64, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Sweep holds details about calls to the Sweep method.
+		Sweep []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Take holds details about calls to the Take method.
+		Take []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Key is the key argument value.
+			Key string
+			// Rate is the rate argument value.
+			Rate float64
+			// Burst is the burst argument value.
+			Burst int
+		}
+	}
+	lockSweep sync.RWMutex
+	lockTake  sync.RWMutex
+}
+
+// Sweep calls SweepFunc.
+func (mock *RateLimitStorageMock) Sweep(ctx context.Context) (int64, error) {
+	if mock.SweepFunc == nil {
+		panic("RateLimitStorageMock.SweepFunc: method is nil but RateLimitStorage.Sweep was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockSweep.Lock()
+	mock.calls.Sweep = append(mock.calls.Sweep, callInfo)
+	mock.lockSweep.Unlock()
+	return mock.SweepFunc(ctx)
+}
+
+// SweepCalls gets all the calls that were made to Sweep.
+// Check the length with:
+//
+//	len(mockedRateLimitStorage.SweepCalls())
+func (mock *RateLimitStorageMock) SweepCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockSweep.RLock()
+	calls = mock.calls.Sweep
+	mock.lockSweep.RUnlock()
+	return calls
+}
+
+// Take calls TakeFunc.
+func (mock *RateLimitStorageMock) Take(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+	if mock.TakeFunc == nil {
+		panic("RateLimitStorageMock.TakeFunc: method is nil but RateLimitStorage.Take was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Key   string
+		Rate  float64
+		Burst int
+	}{
+		Ctx:   ctx,
+		Key:   key,
+		Rate:  rate,
+		Burst: burst,
+	}
+	mock.lockTake.Lock()
+	mock.calls.Take = append(mock.calls.Take, callInfo)
+	mock.lockTake.Unlock()
+	return mock.TakeFunc(ctx, key, rate, burst)
+}
+
+// TakeCalls gets all the calls that were made to Take.
+// Check the length with:
+//
+//	len(mockedRateLimitStorage.TakeCalls())
+func (mock *RateLimitStorageMock) TakeCalls() []struct {
+	Ctx   context.Context
+	Key   string
+	Rate  float64
+	Burst int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Key   string
+		Rate  float64
+		Burst int
+	}
+	mock.lockTake.RLock()
+	calls = mock.calls.Take
+	mock.lockTake.RUnlock()
+	return calls
+}
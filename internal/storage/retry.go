@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"bank/internal/metrics"
+	"bank/pkg/apperror"
+	"bank/pkg/backoff"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SQLSTATEs of the failures that go away when the transaction runs again.
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// budgetRefill is the share of a retry token a committed transaction earns back, so retries stay below about
+// a tenth of the transactions once the budget is spent.
+const budgetRefill = 0.1
+
+// RetryPolicy runs a transaction again when Postgres aborts it with a serialization failure or a deadlock. The
+// zero value does not retry.
+type RetryPolicy struct {
+	// MaxAttempts bounds the runs of one transaction, the first included.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled for every further one up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Budget is the number of retries the transactor may make in a row. Each retry spends one, each commit earns
+	// back a tenth, so a database that keeps aborting transactions is not hammered with retries.
+	Budget int
+}
+
+// budget is a token bucket shared by the transactions of a transactor.
+type budget struct {
+	mu     sync.Mutex
+	tokens float64
+	max    float64
+}
+
+func newBudget(size int) *budget {
+	return &budget{tokens: float64(size), max: float64(size)}
+}
+
+// spend takes a token for a retry, it returns false when none is left.
+func (budget *budget) spend() bool {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	if budget.tokens < 1 {
+		return false
+	}
+	budget.tokens--
+
+	return true
+}
+
+func (budget *budget) refill() {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	budget.tokens = min(budget.tokens+budgetRefill, budget.max)
+}
+
+// retry runs attempt until it succeeds, fails with an error that is not worth retrying, or the policy or the
+// budget give up. It returns the error of the last attempt.
+func (transactor *transactor) retry(ctx context.Context, attempt func() error) error {
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil {
+			transactor.budget.refill()
+			return nil
+		}
+
+		code, ok := retryable(err)
+		if !ok || n >= transactor.retryPolicy.MaxAttempts {
+			return err
+		}
+
+		if !transactor.budget.spend() {
+			transactor.log.WarnContext(ctx, "transaction retry budget exhausted", slog.String("code", code), slog.Int("attempt", n))
+			return err
+		}
+
+		// the jitter keeps transactions that collided from colliding again
+		delay := backoff.Backoff{Base: transactor.retryPolicy.Backoff, Max: transactor.retryPolicy.MaxBackoff}.Next(n)
+		metrics.ObserveTransactionRetry(code)
+		transactor.log.InfoContext(ctx, "retrying transaction",
+			slog.String("code", code), slog.Int("attempt", n), slog.Duration("delay", delay))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryable returns the SQLSTATE of err when it is a serialization failure or a deadlock. The storages keep the
+// driver error in apperror.Error, which does not unwrap, so it is looked up by hand.
+func retryable(err error) (string, bool) {
+	for err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			return pgErr.Code, pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+		}
+
+		var apperr apperror.Error
+		if !errors.As(err, &apperr) {
+			return "", false
+		}
+		err = apperr.Err
+	}
+
+	return "", false
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"bank/internal/metrics"
+	"bank/pkg/postgres"
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Router is the primary database with a read replica next to it. It runs every query on the primary, except those
+// of the read-only storage methods: they go to the replica while it is healthy, unless they run in a transaction
+// or their context asks to read its own writes.
+type Router struct {
+	postgres.Client
+	replica postgres.Client
+	healthy atomic.Bool
+	log     *slog.Logger
+}
+
+// NewRouter returns a Router that reads from the primary until Watch finds the replica answering, so a replica
+// that is down at startup only costs the reads it would have taken.
+func NewRouter(primary, replica postgres.Client, log *slog.Logger) *Router {
+	metrics.SetReplicaHealthy(false)
+
+	return &Router{Client: primary, replica: replica, log: log}
+}
+
+// Watch pings the replica right away and then every interval until ctx is done, sending reads to the primary
+// while it does not answer within the interval.
+func (router *Router) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for checked := false; ; checked = true {
+		pingCtx, cancel := context.WithTimeout(ctx, interval)
+		_, err := router.replica.Exec(pingCtx, "SELECT 1")
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		healthy := err == nil
+		metrics.SetReplicaHealthy(healthy)
+		if router.healthy.Swap(healthy) != healthy || !checked && !healthy {
+			if healthy {
+				router.log.InfoContext(ctx, "replica is healthy, reading from it")
+			} else {
+				router.log.WarnContext(ctx, "replica is unhealthy, reading from the primary", slog.Any("error", err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Healthy reports whether reads go to the replica.
+func (router *Router) Healthy() bool {
+	return router.healthy.Load()
+}
+
+type readYourWritesKey struct{}
+
+// WithReadYourWrites makes the storages read from the primary with ctx, so a caller sees its own writes before
+// they reach the replica.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+// ReadYourWrites reports whether ctx reads from the primary, see WithReadYourWrites.
+func ReadYourWrites(ctx context.Context) bool {
+	value, _ := ctx.Value(readYourWritesKey{}).(bool)
+	return value
+}
+
+// reader returns the connection for the queries of a read-only method: the transaction of ctx, the replica of
+// client when it is a Router that may serve ctx, and client otherwise.
+func reader(ctx context.Context, client postgres.Client) postgres.Client {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return postgres.Txw{Tx: state.tx}
+	}
+
+	if router, ok := client.(*Router); ok && router.Healthy() && !ReadYourWrites(ctx) {
+		return router.replica
+	}
+
+	return client
+}
@@ -0,0 +1,130 @@
+package storage_test
+
+import (
+	"bank/internal/model"
+	"bank/internal/storage"
+	"bank/pkg/logger"
+	"bank/pkg/postgres"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClient counts the reads it serves and fails its pings while down is set.
+type fakeClient struct {
+	postgres.Client
+	reads atomic.Int32
+	down  atomic.Bool
+}
+
+func (client *fakeClient) Get(context.Context, any, string, ...any) error {
+	client.reads.Add(1)
+	return nil
+}
+
+func (client *fakeClient) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	if client.down.Load() {
+		return pgconn.CommandTag{}, errors.New("connection refused")
+	}
+
+	return pgconn.CommandTag{}, nil
+}
+
+func TestRouter(t *testing.T) {
+	tests := []struct {
+		name        string
+		get         func(balanceStorage storage.BalanceStorage) (model.Balance, error)
+		wantReplica bool
+	}{
+		{
+			name: "read",
+			get: func(balanceStorage storage.BalanceStorage) (model.Balance, error) {
+				return balanceStorage.Get(context.Background(), uuid.New())
+			},
+			wantReplica: true,
+		},
+		{
+			name: "read your writes",
+			get: func(balanceStorage storage.BalanceStorage) (model.Balance, error) {
+				return balanceStorage.Get(storage.WithReadYourWrites(context.Background()), uuid.New())
+			},
+		},
+		{
+			name: "locking read",
+			get: func(balanceStorage storage.BalanceStorage) (model.Balance, error) {
+				return balanceStorage.GetForUpdate(context.Background(), uuid.New())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, replica := &fakeClient{}, &fakeClient{}
+			balanceStorage := storage.NewBalanceStorage(watch(t, storage.NewRouter(primary, replica, logger.New(io.Discard, "info"))))
+
+			_, err := tt.get(balanceStorage)
+			assert.NoError(t, err)
+
+			if tt.wantReplica {
+				assert.Equal(t, int32(1), replica.reads.Load())
+				assert.Zero(t, primary.reads.Load())
+			} else {
+				assert.Equal(t, int32(1), primary.reads.Load())
+				assert.Zero(t, replica.reads.Load())
+			}
+		})
+	}
+}
+
+// watch runs the Watch of router until the test ends, once it found the replica healthy.
+func watch(t *testing.T, router *storage.Router) *storage.Router {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go router.Watch(ctx, time.Millisecond)
+
+	require.Eventually(t, router.Healthy, time.Second, time.Millisecond)
+
+	return router
+}
+
+func TestRouter_Watch(t *testing.T) {
+	primary, replica := &fakeClient{}, &fakeClient{}
+	router := watch(t, storage.NewRouter(primary, replica, logger.New(io.Discard, "info")))
+
+	replica.down.Store(true)
+	assert.Eventually(t, func() bool { return !router.Healthy() }, time.Second, time.Millisecond, "unhealthy replica")
+
+	_, err := storage.NewBalanceStorage(router).Get(context.Background(), uuid.New())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), primary.reads.Load(), "reads fall back to the primary")
+
+	replica.down.Store(false)
+	assert.Eventually(t, router.Healthy, time.Second, time.Millisecond, "recovered replica")
+}
+
+func TestRouter_ReplicaDownAtStartup(t *testing.T) {
+	primary, replica := &fakeClient{}, &fakeClient{}
+	replica.down.Store(true)
+
+	router := storage.NewRouter(primary, replica, logger.New(io.Discard, "info"))
+	assert.False(t, router.Healthy(), "the replica is not read before it answered")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go router.Watch(ctx, time.Millisecond)
+
+	_, err := storage.NewBalanceStorage(router).Get(context.Background(), uuid.New())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), primary.reads.Load())
+	assert.Zero(t, replica.reads.Load())
+
+	replica.down.Store(false)
+	assert.Eventually(t, router.Healthy, time.Second, time.Millisecond, "replica up after startup")
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"bank/internal/model"
+	"bank/pkg/apperror"
+	"bank/pkg/postgres"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+//go:generate moq -out snapshot_mock.go . SnapshotStorage
+type SnapshotStorage interface {
+	// Pending returns the UTC days, oldest first, that ended by until and are not snapshotted yet, starting from
+	// the day after the last snapshotted one or, before the first snapshot, from the day of the first transaction.
+	Pending(ctx context.Context, until time.Time) ([]time.Time, error)
+	// Create stores the closing balances of day for the accounts with transactions on it and marks the day as
+	// snapshotted. It should run in a transaction; running it again for the same day changes nothing. A transaction
+	// stamped with a snapshotted day drops the snapshots of its payee from that day on, the next snapshot of the
+	// payee adds up everything since the one before.
+	Create(ctx context.Context, day time.Time) (int64, error)
+	// GetBefore returns the latest snapshot of userID that closed by at, NotFound if there is none.
+	GetBefore(ctx context.Context, userID uuid.UUID, at time.Time) (model.Snapshot, error)
+}
+
+type snapshotStorage struct {
+	client postgres.Client
+}
+
+func NewSnapshotStorage(client postgres.Client) SnapshotStorage {
+	return &snapshotStorage{client: client}
+}
+
+func (storage *snapshotStorage) Pending(ctx context.Context, until time.Time) ([]time.Time, error) {
+	const q = `
+SELECT day::DATE AS day
+FROM GENERATE_SERIES(
+             COALESCE((SELECT MAX(day) + 1 FROM balance_snapshot_day),
+                      (SELECT MIN(created_at) AT TIME ZONE 'UTC' FROM transaction_history)::DATE)::TIMESTAMP,
+             (($1::TIMESTAMPTZ AT TIME ZONE 'UTC')::DATE - 1)::TIMESTAMP,
+             INTERVAL '1 day') AS day`
+
+	var days []time.Time
+	err := conn(ctx, storage.client).Select(ctx, &days, q, until)
+	if err != nil {
+		return []time.Time{}, apperror.Internal.WithError(err)
+	}
+
+	return days, nil
+}
+
+func (storage *snapshotStorage) Create(ctx context.Context, day time.Time) (int64, error) {
+	// rows committing for an ended day drop the snapshots they invalidate under the shared lock, see the
+	// invalidate_balance_snapshot trigger, so they are either seen by the statement below or drop its snapshots
+	_, err := conn(ctx, storage.client).Exec(ctx, "SELECT PG_ADVISORY_XACT_LOCK(HASHTEXT('balance_snapshot'))")
+	if err != nil {
+		return 0, apperror.Internal.WithError(err)
+	}
+
+	// each closing balance is the one of the previous snapshot plus the transactions since it closed, which are more
+	// than those of the day when the snapshots in between were dropped
+	const q = `
+INSERT INTO balance_snapshot (user_id, day, balance)
+SELECT a.payee_id, $1::DATE, COALESCE(s.balance, 0) + t.amount
+FROM (SELECT DISTINCT payee_id
+      FROM transaction_history
+      WHERE created_at >= $1::DATE::TIMESTAMP AT TIME ZONE 'UTC'
+        AND created_at < ($1::DATE + 1)::TIMESTAMP AT TIME ZONE 'UTC') a
+         JOIN balance b ON b.user_id = a.payee_id
+         LEFT JOIN LATERAL (SELECT day, balance
+                            FROM balance_snapshot
+                            WHERE user_id = a.payee_id
+                              AND day < $1::DATE
+                            ORDER BY day DESC
+                            LIMIT 1) s ON TRUE
+         CROSS JOIN LATERAL (SELECT SUM(amount) AS amount
+                             FROM transaction_history
+                             WHERE payee_id = a.payee_id
+                               AND created_at >= COALESCE((s.day + 1)::TIMESTAMP AT TIME ZONE 'UTC', '-infinity')
+                               AND created_at < ($1::DATE + 1)::TIMESTAMP AT TIME ZONE 'UTC') t
+ON CONFLICT (user_id, day) DO NOTHING`
+
+	tag, err := conn(ctx, storage.client).Exec(ctx, q, day)
+	if err != nil {
+		return 0, apperror.Internal.WithError(err)
+	}
+
+	_, err = conn(ctx, storage.client).Exec(ctx,
+		"INSERT INTO balance_snapshot_day (day) VALUES ($1::DATE) ON CONFLICT (day) DO NOTHING", day)
+	if err != nil {
+		return 0, apperror.Internal.WithError(err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (storage *snapshotStorage) GetBefore(ctx context.Context, userID uuid.UUID, at time.Time) (model.Snapshot, error) {
+	builder := psql.
+		Select(
+			"user_id",
+			"day",
+			"balance",
+			"created_at",
+		).
+		From("balance_snapshot").
+		Where("user_id = ?", userID).
+		Where("day < (?::TIMESTAMPTZ AT TIME ZONE 'UTC')::DATE", at).
+		OrderBy("day DESC").
+		Limit(1)
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return model.Snapshot{}, apperror.Internal.WithError(err)
+	}
+
+	var snapshot model.Snapshot
+	err = reader(ctx, storage.client).Get(ctx, &snapshot, q, args...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Snapshot{}, apperror.NotFound.WithError(err)
+		}
+
+		return model.Snapshot{}, apperror.Internal.WithError(err)
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,190 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package storage
+
+import (
+	"bank/internal/model"
+	"context"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// Ensure, that SnapshotStorageMock does implement SnapshotStorage.
+// If this is not the case, regenerate this file with moq.
+var _ SnapshotStorage = &SnapshotStorageMock{}
+
+// SnapshotStorageMock is a mock implementation of SnapshotStorage.
+//
+//	func TestSomethingThatUsesSnapshotStorage(t *testing.T) {
+//
+//		// make and configure a mocked SnapshotStorage
+//		mockedSnapshotStorage := &SnapshotStorageMock{
+//			CreateFunc: func(ctx context.Context, day time.Time) (int64, error) {
+//				panic("mock out the Create method")
+//			},
+//			GetBeforeFunc: func(ctx context.Context, userID uuid.UUID, at time.Time) (model.Snapshot, error) {
+//				panic("mock out the GetBefore method")
+//			},
+//			PendingFunc: func(ctx context.Context, until time.Time) ([]time.Time, error) {
+//				panic("mock out the Pending method")
+//			},
+//		}
+//
+//		// use mockedSnapshotStorage in code that requires SnapshotStorage
+//		// and then make assertions.
+//
+//	}
+type SnapshotStorageMock struct {
+	// CreateFunc mocks the Create method.
+	CreateFunc func(ctx context.Context, day time.Time) (int64, error)
+
+	// GetBeforeFunc mocks the GetBefore method.
+	GetBeforeFunc func(ctx context.Context, userID uuid.UUID, at time.Time) (model.Snapshot, error)
+
+	// PendingFunc mocks the Pending method.
+	PendingFunc func(ctx context.Context, until time.Time) ([]time.Time, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Create holds details about calls to the Create method.
+		Create []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Day is the day argument value.
+			Day time.Time
+		}
+		// GetBefore holds details about calls to the GetBefore method.
+		GetBefore []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID uuid.UUID
+			// At is the at argument value.
+			At time.Time
+		}
+		// Pending holds details about calls to the Pending method.
+		Pending []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Until is the until argument value.
+			Until time.Time
+		}
+	}
+	lockCreate    sync.RWMutex
+	lockGetBefore sync.RWMutex
+	lockPending   sync.RWMutex
+}
+
+// Create calls CreateFunc.
+func (mock *SnapshotStorageMock) Create(ctx context.Context, day time.Time) (int64, error) {
+	if mock.CreateFunc == nil {
+		panic("SnapshotStorageMock.CreateFunc: method is nil but SnapshotStorage.Create was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Day time.Time
+	}{
+		Ctx: ctx,
+		Day: day,
+	}
+	mock.lockCreate.Lock()
+	mock.calls.Create = append(mock.calls.Create, callInfo)
+	mock.lockCreate.Unlock()
+	return mock.CreateFunc(ctx, day)
+}
+
+// CreateCalls gets all the calls that were made to Create.
+// Check the length with:
+//
+//	len(mockedSnapshotStorage.CreateCalls())
+func (mock *SnapshotStorageMock) CreateCalls() []struct {
+	Ctx context.Context
+	Day time.Time
+} {
+	var calls []struct {
+		Ctx context.Context
+		Day time.Time
+	}
+	mock.lockCreate.RLock()
+	calls = mock.calls.Create
+	mock.lockCreate.RUnlock()
+	return calls
+}
+
+// GetBefore calls GetBeforeFunc.
+func (mock *SnapshotStorageMock) GetBefore(ctx context.Context, userID uuid.UUID, at time.Time) (model.Snapshot, error) {
+	if mock.GetBeforeFunc == nil {
+		panic("SnapshotStorageMock.GetBeforeFunc: method is nil but SnapshotStorage.GetBefore was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserID uuid.UUID
+		At     time.Time
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+		At:     at,
+	}
+	mock.lockGetBefore.Lock()
+	mock.calls.GetBefore = append(mock.calls.GetBefore, callInfo)
+	mock.lockGetBefore.Unlock()
+	return mock.GetBeforeFunc(ctx, userID, at)
+}
+
+// GetBeforeCalls gets all the calls that were made to GetBefore.
+// Check the length with:
+//
+//	len(mockedSnapshotStorage.GetBeforeCalls())
+func (mock *SnapshotStorageMock) GetBeforeCalls() []struct {
+	Ctx    context.Context
+	UserID uuid.UUID
+	At     time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID uuid.UUID
+		At     time.Time
+	}
+	mock.lockGetBefore.RLock()
+	calls = mock.calls.GetBefore
+	mock.lockGetBefore.RUnlock()
+	return calls
+}
+
+// Pending calls PendingFunc.
+func (mock *SnapshotStorageMock) Pending(ctx context.Context, until time.Time) ([]time.Time, error) {
+	if mock.PendingFunc == nil {
+		panic("SnapshotStorageMock.PendingFunc: method is nil but SnapshotStorage.Pending was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Until time.Time
+	}{
+		Ctx:   ctx,
+		Until: until,
+	}
+	mock.lockPending.Lock()
+	mock.calls.Pending = append(mock.calls.Pending, callInfo)
+	mock.lockPending.Unlock()
+	return mock.PendingFunc(ctx, until)
+}
+
+// PendingCalls gets all the calls that were made to Pending.
+// Check the length with:
+//
+//	len(mockedSnapshotStorage.PendingCalls())
+func (mock *SnapshotStorageMock) PendingCalls() []struct {
+	Ctx   context.Context
+	Until time.Time
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Until time.Time
+	}
+	mock.lockPending.RLock()
+	calls = mock.calls.Pending
+	mock.lockPending.RUnlock()
+	return calls
+}
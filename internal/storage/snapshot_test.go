@@ -0,0 +1,113 @@
+package storage_test
+
+import (
+	"bank/internal/domain"
+	"bank/internal/model"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"bank/pkg/logger"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestSnapshotStorage(t *testing.T) {
+	ctx := context.Background()
+	pool := newPool(t)
+	balanceStorage := storage.NewBalanceStorage(pool)
+	transactionStorage := storage.NewTransactionStorage(pool)
+	snapshotStorage := storage.NewSnapshotStorage(pool)
+	transactor := storage.NewTransactor(pool, storage.RetryPolicy{}, logger.New(io.Discard, "info"))
+
+	userID, otherID := uuid.New(), uuid.New()
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: userID}))
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: otherID}))
+
+	day := time.Date(2023, time.June, 14, 0, 0, 0, 0, time.UTC)
+	for _, transaction := range []model.Transaction{
+		{PayeeID: userID, Amount: 300, CreatedAt: day.Add(10 * time.Hour)},
+		{PayeeID: otherID, Amount: 50, CreatedAt: day.Add(24*time.Hour - time.Microsecond)},
+		{PayeeID: userID, Amount: -100, CreatedAt: day.Add(24 * time.Hour)},
+		{PayeeID: userID, Amount: 40, CreatedAt: day.Add(72 * time.Hour)},
+	} {
+		transaction.ID, transaction.Type = uuid.New(), domain.Debet
+		require.NoError(t, transactionStorage.Create(ctx, transaction))
+	}
+
+	until := day.AddDate(0, 0, 2).Add(time.Minute)
+	pending, err := snapshotStorage.Pending(ctx, until)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{day, day.AddDate(0, 0, 1)}, pending)
+
+	for _, pendingDay := range pending {
+		require.NoError(t, transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			_, err := snapshotStorage.Create(ctx, pendingDay)
+			return err
+		}))
+	}
+
+	created, err := snapshotStorage.Create(ctx, day)
+	require.NoError(t, err)
+	assert.Zero(t, created, "a snapshotted day is not taken again")
+
+	pending, err = snapshotStorage.Pending(ctx, until)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	snapshot, err := snapshotStorage.GetBefore(ctx, userID, day.AddDate(0, 0, 5))
+	require.NoError(t, err)
+	assert.True(t, day.AddDate(0, 0, 1).Equal(snapshot.Day))
+	assert.Equal(t, int64(200), snapshot.Balance)
+
+	snapshot, err = snapshotStorage.GetBefore(ctx, otherID, day.AddDate(0, 0, 5))
+	require.NoError(t, err)
+	assert.True(t, day.Equal(snapshot.Day), "days without transactions have no snapshot")
+	assert.Equal(t, int64(50), snapshot.Balance)
+
+	_, err = snapshotStorage.GetBefore(ctx, userID, day.Add(23*time.Hour))
+	assert.True(t, errors.Is(err, apperror.NotFound))
+
+	sum, err := transactionStorage.Sum(ctx, userID, snapshot.Close(), day.AddDate(0, 0, 5))
+	require.NoError(t, err)
+	assert.Equal(t, int64(-60), sum)
+
+	// the row stamped exactly at the close of the day is left out of its snapshot and counted by the sum from it
+	snapshot, err = snapshotStorage.GetBefore(ctx, userID, day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.True(t, day.Equal(snapshot.Day))
+	assert.Equal(t, int64(300), snapshot.Balance)
+
+	sum, err = transactionStorage.Sum(ctx, userID, snapshot.Close(), snapshot.Close())
+	require.NoError(t, err)
+	assert.Equal(t, int64(-100), sum)
+
+	// a backdated row drops the snapshots of its payee from its day on, the next one adds up everything since
+	backdated := model.Transaction{ID: uuid.New(), PayeeID: userID, Type: domain.Debet, Amount: 5, CreatedAt: day.Add(time.Hour)}
+	require.NoError(t, transactionStorage.Create(ctx, backdated))
+
+	_, err = snapshotStorage.GetBefore(ctx, userID, day.AddDate(0, 0, 5))
+	assert.True(t, errors.Is(err, apperror.NotFound))
+
+	snapshot, err = snapshotStorage.GetBefore(ctx, otherID, day.AddDate(0, 0, 5))
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), snapshot.Balance, "other accounts keep their snapshots")
+
+	pending, err = snapshotStorage.Pending(ctx, day.AddDate(0, 0, 4).Add(time.Minute))
+	require.NoError(t, err)
+	for _, pendingDay := range pending {
+		require.NoError(t, transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			_, err := snapshotStorage.Create(ctx, pendingDay)
+			return err
+		}))
+	}
+
+	snapshot, err = snapshotStorage.GetBefore(ctx, userID, day.AddDate(0, 0, 5))
+	require.NoError(t, err)
+	assert.True(t, day.AddDate(0, 0, 3).Equal(snapshot.Day))
+	assert.Equal(t, int64(245), snapshot.Balance)
+}
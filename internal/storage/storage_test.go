@@ -0,0 +1,25 @@
+package storage_test
+
+import (
+	"bank/internal/pgtest"
+	"bank/internal/storage"
+	"bank/pkg/postgres"
+	"context"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Run(m))
+}
+
+// newPool is pgtest.Pool with the transaction partition of the month newTransaction dates its transactions in.
+func newPool(t *testing.T) postgres.Pool {
+	t.Helper()
+
+	pool := pgtest.Pool(t)
+	require.NoError(t, storage.NewPartitionStorage(pool).Create(context.Background(), clock, clock))
+
+	return pool
+}
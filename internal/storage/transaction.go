@@ -11,13 +11,18 @@ import (
 	"github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"time"
 )
 
 //go:generate moq -out transaction_mock.go . TransactionStorage
 type TransactionStorage interface {
 	Create(ctx context.Context, transaction model.Transaction) error
-	AtomicCreate(ctx context.Context, tx postgres.Client, transaction model.Transaction) error
 	Select(ctx context.Context, userID uuid.UUID, filter sort.Filter) ([]model.Transaction, error)
+	// SelectAfter returns the transactions of userID committed after the transaction afterID, oldest first, and
+	// NotFound when afterID is not a transaction of userID, so the caller knows it cannot resume from it.
+	SelectAfter(ctx context.Context, userID uuid.UUID, afterID uuid.UUID) ([]model.Transaction, error)
+	// Sum adds up the amounts of the transactions of userID created from from, inclusive, up to and including to.
+	Sum(ctx context.Context, userID uuid.UUID, from, to time.Time) (int64, error)
 }
 
 type transactionStorage struct {
@@ -29,10 +34,6 @@ func NewTransactionStorage(client postgres.Client) TransactionStorage {
 }
 
 func (storage *transactionStorage) Create(ctx context.Context, transaction model.Transaction) error {
-	return storage.AtomicCreate(ctx, storage.client, transaction)
-}
-
-func (storage *transactionStorage) AtomicCreate(ctx context.Context, tx postgres.Client, transaction model.Transaction) error {
 	builder := psql.
 		Insert("transaction").
 		Columns(
@@ -59,7 +60,7 @@ func (storage *transactionStorage) AtomicCreate(ctx context.Context, tx postgres
 		return apperror.Internal.WithError(err)
 	}
 
-	_, err = tx.Exec(ctx, q, args...)
+	_, err = conn(ctx, storage.client).Exec(ctx, q, args...)
 	if err != nil {
 		return apperror.Internal.WithError(err)
 	}
@@ -67,6 +68,7 @@ func (storage *transactionStorage) AtomicCreate(ctx context.Context, tx postgres
 	return nil
 }
 
+// Select reads transaction_history, the hot partitions together with the archived ones.
 func (storage *transactionStorage) Select(ctx context.Context, userID uuid.UUID, filter sort.Filter) ([]model.Transaction, error) {
 	builder := psql.
 		Select(
@@ -78,7 +80,7 @@ func (storage *transactionStorage) Select(ctx context.Context, userID uuid.UUID,
 			"comment",
 			"created_at",
 		).
-		From("transaction").
+		From("transaction_history").
 		Where(squirrel.Eq{"payee_id": userID}).
 		Offset(filter.GetOffset())
 
@@ -98,7 +100,7 @@ func (storage *transactionStorage) Select(ctx context.Context, userID uuid.UUID,
 	}
 
 	var transactions []model.Transaction
-	err = storage.client.Select(ctx, &transactions, q, args...)
+	err = reader(ctx, storage.client).Select(ctx, &transactions, q, args...)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return []model.Transaction{}, apperror.NotFound.WithError(err)
@@ -109,3 +111,66 @@ func (storage *transactionStorage) Select(ctx context.Context, userID uuid.UUID,
 
 	return transactions, nil
 }
+
+func (storage *transactionStorage) SelectAfter(ctx context.Context, userID uuid.UUID, afterID uuid.UUID) ([]model.Transaction, error) {
+	var after time.Time
+	err := reader(ctx, storage.client).Get(ctx, &after,
+		"SELECT created_at FROM transaction_history WHERE id = $1 AND payee_id = $2", afterID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []model.Transaction{}, apperror.NotFound.WithError(err)
+		}
+
+		return []model.Transaction{}, apperror.Internal.WithError(err)
+	}
+
+	builder := psql.
+		Select(
+			"id",
+			"payee_id",
+			"payer_id",
+			"type",
+			"amount",
+			"comment",
+			"created_at",
+		).
+		From("transaction_history").
+		Where(squirrel.Eq{"payee_id": userID}).
+		Where("(created_at, id) > (?, ?)", after, afterID).
+		OrderBy("created_at", "id")
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return []model.Transaction{}, apperror.Internal.WithError(err)
+	}
+
+	var transactions []model.Transaction
+	err = reader(ctx, storage.client).Select(ctx, &transactions, q, args...)
+	if err != nil {
+		return []model.Transaction{}, apperror.Internal.WithError(err)
+	}
+
+	return transactions, nil
+}
+
+func (storage *transactionStorage) Sum(ctx context.Context, userID uuid.UUID, from, to time.Time) (int64, error) {
+	builder := psql.
+		Select("COALESCE(SUM(amount), 0)::BIGINT").
+		From("transaction_history").
+		Where(squirrel.Eq{"payee_id": userID}).
+		Where(squirrel.GtOrEq{"created_at": from}).
+		Where(squirrel.LtOrEq{"created_at": to})
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return 0, apperror.Internal.WithError(err)
+	}
+
+	var sum int64
+	err = reader(ctx, storage.client).Get(ctx, &sum, q, args...)
+	if err != nil {
+		return 0, apperror.Internal.WithError(err)
+	}
+
+	return sum, nil
+}
@@ -5,11 +5,11 @@ package storage
 
 import (
 	"bank/internal/model"
-	"bank/pkg/postgres"
 	"bank/pkg/sort"
 	"context"
 	"github.com/google/uuid"
 	"sync"
+	"time"
 )
 
 // Ensure, that TransactionStorageMock does implement TransactionStorage.
@@ -22,15 +22,18 @@ var _ TransactionStorage = &TransactionStorageMock{}
 //
 //		// make and configure a mocked TransactionStorage
 //		mockedTransactionStorage := &TransactionStorageMock{
-//			AtomicCreateFunc: func(ctx context.Context, tx postgres.Client, transaction model.Transaction) error {
-//				panic("mock out the AtomicCreate method")
-//			},
 //			CreateFunc: func(ctx context.Context, transaction model.Transaction) error {
 //				panic("mock out the Create method")
 //			},
 //			SelectFunc: func(ctx context.Context, userID uuid.UUID, filter sort.Filter) ([]model.Transaction, error) {
 //				panic("mock out the Select method")
 //			},
+//			SelectAfterFunc: func(ctx context.Context, userID uuid.UUID, afterID uuid.UUID) ([]model.Transaction, error) {
+//				panic("mock out the SelectAfter method")
+//			},
+//			SumFunc: func(ctx context.Context, userID uuid.UUID, from time.Time, to time.Time) (int64, error) {
+//				panic("mock out the Sum method")
+//			},
 //		}
 //
 //		// use mockedTransactionStorage in code that requires TransactionStorage
@@ -38,26 +41,20 @@ var _ TransactionStorage = &TransactionStorageMock{}
 //
 //	}
 type TransactionStorageMock struct {
-	// AtomicCreateFunc mocks the AtomicCreate method.
-	AtomicCreateFunc func(ctx context.Context, tx postgres.Client, transaction model.Transaction) error
-
 	// CreateFunc mocks the Create method.
 	CreateFunc func(ctx context.Context, transaction model.Transaction) error
 
 	// SelectFunc mocks the Select method.
 	SelectFunc func(ctx context.Context, userID uuid.UUID, filter sort.Filter) ([]model.Transaction, error)
 
+	// SelectAfterFunc mocks the SelectAfter method.
+	SelectAfterFunc func(ctx context.Context, userID uuid.UUID, afterID uuid.UUID) ([]model.Transaction, error)
+
+	// SumFunc mocks the Sum method.
+	SumFunc func(ctx context.Context, userID uuid.UUID, from time.Time, to time.Time) (int64, error)
+
 	// calls tracks calls to the methods.
 	calls struct {
-		// AtomicCreate holds details about calls to the AtomicCreate method.
-		AtomicCreate []struct {
-			// Ctx is the ctx argument value.
-			Ctx context.Context
-			// Tx is the tx argument value.
-			Tx postgres.Client
-			// Transaction is the transaction argument value.
-			Transaction model.Transaction
-		}
 		// Create holds details about calls to the Create method.
 		Create []struct {
 			// Ctx is the ctx argument value.
@@ -74,50 +71,31 @@ type TransactionStorageMock struct {
 			// Filter is the filter argument value.
 			Filter sort.Filter
 		}
+		// SelectAfter holds details about calls to the SelectAfter method.
+		SelectAfter []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID uuid.UUID
+			// AfterID is the afterID argument value.
+			AfterID uuid.UUID
+		}
+		// Sum holds details about calls to the Sum method.
+		Sum []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// UserID is the userID argument value.
+			UserID uuid.UUID
+			// From is the from argument value.
+			From time.Time
+			// To is the to argument value.
+			To time.Time
+		}
 	}
-	lockAtomicCreate sync.RWMutex
-	lockCreate       sync.RWMutex
-	lockSelect       sync.RWMutex
-}
-
-// AtomicCreate calls AtomicCreateFunc.
-func (mock *TransactionStorageMock) AtomicCreate(ctx context.Context, tx postgres.Client, transaction model.Transaction) error {
-	if mock.AtomicCreateFunc == nil {
-		panic("TransactionStorageMock.AtomicCreateFunc: method is nil but TransactionStorage.AtomicCreate was just called")
-	}
-	callInfo := struct {
-		Ctx         context.Context
-		Tx          postgres.Client
-		Transaction model.Transaction
-	}{
-		Ctx:         ctx,
-		Tx:          tx,
-		Transaction: transaction,
-	}
-	mock.lockAtomicCreate.Lock()
-	mock.calls.AtomicCreate = append(mock.calls.AtomicCreate, callInfo)
-	mock.lockAtomicCreate.Unlock()
-	return mock.AtomicCreateFunc(ctx, tx, transaction)
-}
-
-// AtomicCreateCalls gets all the calls that were made to AtomicCreate.
-// Check the length with:
-//
-//	len(mockedTransactionStorage.AtomicCreateCalls())
-func (mock *TransactionStorageMock) AtomicCreateCalls() []struct {
-	Ctx         context.Context
-	Tx          postgres.Client
-	Transaction model.Transaction
-} {
-	var calls []struct {
-		Ctx         context.Context
-		Tx          postgres.Client
-		Transaction model.Transaction
-	}
-	mock.lockAtomicCreate.RLock()
-	calls = mock.calls.AtomicCreate
-	mock.lockAtomicCreate.RUnlock()
-	return calls
+	lockCreate      sync.RWMutex
+	lockSelect      sync.RWMutex
+	lockSelectAfter sync.RWMutex
+	lockSum         sync.RWMutex
 }
 
 // Create calls CreateFunc.
@@ -195,3 +173,87 @@ func (mock *TransactionStorageMock) SelectCalls() []struct {
 	mock.lockSelect.RUnlock()
 	return calls
 }
+
+// SelectAfter calls SelectAfterFunc.
+func (mock *TransactionStorageMock) SelectAfter(ctx context.Context, userID uuid.UUID, afterID uuid.UUID) ([]model.Transaction, error) {
+	if mock.SelectAfterFunc == nil {
+		panic("TransactionStorageMock.SelectAfterFunc: method is nil but TransactionStorage.SelectAfter was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		UserID  uuid.UUID
+		AfterID uuid.UUID
+	}{
+		Ctx:     ctx,
+		UserID:  userID,
+		AfterID: afterID,
+	}
+	mock.lockSelectAfter.Lock()
+	mock.calls.SelectAfter = append(mock.calls.SelectAfter, callInfo)
+	mock.lockSelectAfter.Unlock()
+	return mock.SelectAfterFunc(ctx, userID, afterID)
+}
+
+// SelectAfterCalls gets all the calls that were made to SelectAfter.
+// Check the length with:
+//
+//	len(mockedTransactionStorage.SelectAfterCalls())
+func (mock *TransactionStorageMock) SelectAfterCalls() []struct {
+	Ctx     context.Context
+	UserID  uuid.UUID
+	AfterID uuid.UUID
+} {
+	var calls []struct {
+		Ctx     context.Context
+		UserID  uuid.UUID
+		AfterID uuid.UUID
+	}
+	mock.lockSelectAfter.RLock()
+	calls = mock.calls.SelectAfter
+	mock.lockSelectAfter.RUnlock()
+	return calls
+}
+
+// Sum calls SumFunc.
+func (mock *TransactionStorageMock) Sum(ctx context.Context, userID uuid.UUID, from time.Time, to time.Time) (int64, error) {
+	if mock.SumFunc == nil {
+		panic("TransactionStorageMock.SumFunc: method is nil but TransactionStorage.Sum was just called")
+	}
+	callInfo := struct {
+		Ctx    context.Context
+		UserID uuid.UUID
+		From   time.Time
+		To     time.Time
+	}{
+		Ctx:    ctx,
+		UserID: userID,
+		From:   from,
+		To:     to,
+	}
+	mock.lockSum.Lock()
+	mock.calls.Sum = append(mock.calls.Sum, callInfo)
+	mock.lockSum.Unlock()
+	return mock.SumFunc(ctx, userID, from, to)
+}
+
+// SumCalls gets all the calls that were made to Sum.
+// Check the length with:
+//
+//	len(mockedTransactionStorage.SumCalls())
+func (mock *TransactionStorageMock) SumCalls() []struct {
+	Ctx    context.Context
+	UserID uuid.UUID
+	From   time.Time
+	To     time.Time
+} {
+	var calls []struct {
+		Ctx    context.Context
+		UserID uuid.UUID
+		From   time.Time
+		To     time.Time
+	}
+	mock.lockSum.RLock()
+	calls = mock.calls.Sum
+	mock.lockSum.RUnlock()
+	return calls
+}
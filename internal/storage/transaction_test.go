@@ -0,0 +1,152 @@
+package storage_test
+
+import (
+	"bank/internal/domain"
+	"bank/internal/dto"
+	"bank/internal/model"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"bank/pkg/logger"
+	"bank/pkg/sort"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+var clock = time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newTransaction returns a debet or credit of userID, each one a second later than the previous one.
+func newTransaction(userID uuid.UUID, amount int64) model.Transaction {
+	clock = clock.Add(time.Second)
+
+	transactionType := domain.Debet
+	if amount < 0 {
+		transactionType = domain.Credit
+	}
+
+	return model.Transaction{
+		ID:        uuid.New(),
+		PayeeID:   userID,
+		Type:      transactionType,
+		Amount:    amount,
+		Comment:   "comment",
+		CreatedAt: clock,
+	}
+}
+
+func TestTransactionStorage_Select(t *testing.T) {
+	ctx := context.Background()
+	pool := newPool(t)
+	balanceStorage := storage.NewBalanceStorage(pool)
+	transactionStorage := storage.NewTransactionStorage(pool)
+
+	userID, otherID := uuid.New(), uuid.New()
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: userID}))
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: otherID}))
+
+	first, second, third := newTransaction(userID, 300), newTransaction(userID, -100), newTransaction(userID, 200)
+	transfer := newTransaction(userID, 50)
+	transfer.Type, transfer.PayerID = domain.Transfer, &otherID
+
+	for _, transaction := range []model.Transaction{first, second, third, transfer, newTransaction(otherID, 1000)} {
+		require.NoError(t, transactionStorage.Create(ctx, transaction))
+	}
+
+	tests := []struct {
+		name   string
+		filter dto.SelectTransactionFilter
+		want   []model.Transaction
+	}{
+		{
+			name: "default order",
+			want: []model.Transaction{first, second, third, transfer},
+		},
+		{
+			name:   "by amount descending",
+			filter: dto.SelectTransactionFilter{Sort: "amount", Order: "desc"},
+			want:   []model.Transaction{first, third, transfer, second},
+		},
+		{
+			name:   "page",
+			filter: dto.SelectTransactionFilter{Pagination: sort.Pagination{Count: 2, Offset: 1}},
+			want:   []model.Transaction{second, third},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := transactionStorage.Select(ctx, userID, tt.filter)
+			require.NoError(t, err)
+			assertTransactions(t, tt.want, got)
+		})
+	}
+}
+
+func TestTransactionStorage_SelectAfter(t *testing.T) {
+	ctx := context.Background()
+	pool := newPool(t)
+	balanceStorage := storage.NewBalanceStorage(pool)
+	transactionStorage := storage.NewTransactionStorage(pool)
+
+	userID := uuid.New()
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: userID}))
+
+	transactions := []model.Transaction{newTransaction(userID, 100), newTransaction(userID, 200), newTransaction(userID, -50)}
+	for _, transaction := range transactions {
+		require.NoError(t, transactionStorage.Create(ctx, transaction))
+	}
+
+	got, err := transactionStorage.SelectAfter(ctx, userID, transactions[0].ID)
+	require.NoError(t, err)
+	assertTransactions(t, transactions[1:], got)
+
+	got, err = transactionStorage.SelectAfter(ctx, userID, transactions[2].ID)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = transactionStorage.SelectAfter(ctx, userID, uuid.New())
+	assert.ErrorIs(t, err, apperror.NotFound, "an unknown transaction cannot be resumed from")
+
+	_, err = transactionStorage.SelectAfter(ctx, uuid.New(), transactions[0].ID)
+	assert.ErrorIs(t, err, apperror.NotFound, "nor one of another account")
+}
+
+func TestTransactionStorage_CreateInTransaction(t *testing.T) {
+	ctx := context.Background()
+	pool := newPool(t)
+	balanceStorage := storage.NewBalanceStorage(pool)
+	transactionStorage := storage.NewTransactionStorage(pool)
+
+	userID := uuid.New()
+	require.NoError(t, balanceStorage.Create(ctx, model.Balance{UserID: userID}))
+
+	errRollback := errors.New("rollback")
+	err := storage.NewTransactor(pool, storage.RetryPolicy{}, logger.New(io.Discard, "info")).WithTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, transactionStorage.Create(ctx, newTransaction(userID, 100)))
+		return errRollback
+	})
+	require.ErrorIs(t, err, errRollback)
+
+	got, err := transactionStorage.Select(ctx, userID, dto.SelectTransactionFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, got, "a rolled back insert is not visible")
+
+	// the payee must exist
+	assert.Error(t, transactionStorage.Create(ctx, newTransaction(uuid.New(), 100)))
+}
+
+// assertTransactions compares transactions ignoring the time zone postgres returns timestamps in.
+func assertTransactions(t *testing.T, want, got []model.Transaction) {
+	t.Helper()
+
+	for i := range got {
+		got[i].CreatedAt = got[i].CreatedAt.UTC()
+	}
+
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"bank/internal/metrics"
+	"bank/pkg/apperror"
+	"bank/pkg/postgres"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+)
+
+// TxOption changes the options a transaction is started with.
+type TxOption func(options *pgx.TxOptions)
+
+// WithIsolation starts the transaction at level instead of the default of the Transactor.
+func WithIsolation(level pgx.TxIsoLevel) TxOption {
+	return func(options *pgx.TxOptions) {
+		options.IsoLevel = level
+	}
+}
+
+// ReadOnly starts a transaction that may not write.
+func ReadOnly() TxOption {
+	return func(options *pgx.TxOptions) {
+		options.AccessMode = pgx.ReadOnly
+	}
+}
+
+// Transactor runs functions in a transaction carried by their context, so every storage method called with that
+// context takes part in it.
+//
+//go:generate moq -out transactor_mock.go . Transactor
+type Transactor interface {
+	// WithTransaction commits the transaction if fn succeeds and rolls it back on an error or a panic. Called with
+	// a context that already carries a transaction, it runs fn in a savepoint of it instead: a failed fn undoes
+	// only its own changes, and the outer transaction decides about the rest.
+	//
+	// A transaction aborted by a serialization failure or a deadlock may be run again, so fn must not leave
+	// anything behind but its database writes: values it computes are to be reset by every run.
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error
+}
+
+type txKey struct{}
+
+type txState struct {
+	tx      pgx.Tx
+	options pgx.TxOptions
+}
+
+type transactor struct {
+	pool        postgres.Pool
+	options     pgx.TxOptions
+	retryPolicy RetryPolicy
+	budget      *budget
+	log         *slog.Logger
+}
+
+// NewTransactor starts transactions on pool with the defaults set by opts, read committed and read write unless
+// changed, and runs them again as retryPolicy allows.
+func NewTransactor(pool postgres.Pool, retryPolicy RetryPolicy, log *slog.Logger, opts ...TxOption) Transactor {
+	options := pgx.TxOptions{IsoLevel: pgx.ReadCommitted}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &transactor{
+		pool:        pool,
+		options:     options,
+		retryPolicy: retryPolicy,
+		budget:      newBudget(retryPolicy.Budget),
+		log:         log,
+	}
+}
+
+func (transactor *transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	// a failed statement aborts the whole transaction, only the outermost call can run it again
+	if outer, ok := ctx.Value(txKey{}).(*txState); ok {
+		return transactor.savepoint(ctx, outer, fn, opts)
+	}
+
+	options := transactor.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return transactor.retry(ctx, func() error {
+		return transactor.run(ctx, fn, options)
+	})
+}
+
+func (transactor *transactor) run(ctx context.Context, fn func(ctx context.Context) error, options pgx.TxOptions) (err error) {
+	tx, err := transactor.pool.BeginTx(ctx, options)
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			transactor.rollback(ctx, tx)
+			metrics.ObserveTransaction(metrics.Rollback)
+			panic(p)
+		}
+
+		// a canceled request must not commit what fn managed to do before it noticed
+		if err == nil && ctx.Err() != nil {
+			err = apperror.Internal.WithError(ctx.Err())
+		}
+		if err != nil {
+			transactor.rollback(ctx, tx)
+			metrics.ObserveTransaction(metrics.Rollback)
+			return
+		}
+
+		if err = tx.Commit(ctx); err != nil {
+			metrics.ObserveTransaction(metrics.CommitError)
+			err = apperror.Internal.WithError(err)
+			return
+		}
+		metrics.ObserveTransaction(metrics.Commit)
+	}()
+
+	return fn(context.WithValue(ctx, txKey{}, &txState{tx: tx, options: options}))
+}
+
+// savepoint runs fn in a savepoint of outer. The isolation level and access mode are fixed when a transaction
+// starts: the savepoint keeps those of outer, and asking for another level or access mode is an error rather than
+// silently running with the wrong one.
+func (transactor *transactor) savepoint(ctx context.Context, outer *txState, fn func(ctx context.Context) error, opts []TxOption) (err error) {
+	options := outer.options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.IsoLevel != outer.options.IsoLevel {
+		return apperror.Internal.WithError(fmt.Errorf("savepoint at %s in a %s transaction", options.IsoLevel, outer.options.IsoLevel))
+	}
+	if options.AccessMode != outer.options.AccessMode {
+		return apperror.Internal.WithError(fmt.Errorf("%s savepoint in a %s transaction", accessMode(options), accessMode(outer.options)))
+	}
+
+	tx, err := outer.tx.Begin(ctx)
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			transactor.rollback(ctx, tx)
+			panic(p)
+		}
+
+		if err != nil {
+			transactor.rollback(ctx, tx)
+			return
+		}
+
+		if err = tx.Commit(ctx); err != nil {
+			err = apperror.Internal.WithError(err)
+		}
+	}()
+
+	return fn(context.WithValue(ctx, txKey{}, &txState{tx: tx, options: outer.options}))
+}
+
+// accessMode names the access mode of options, which is read write when not set.
+func accessMode(options pgx.TxOptions) pgx.TxAccessMode {
+	if options.AccessMode == "" {
+		return pgx.ReadWrite
+	}
+
+	return options.AccessMode
+}
+
+// rollback ends tx even when ctx is canceled, so the ROLLBACK still reaches the server. A failed rollback is only
+// logged: pgx closes the connection, which makes the server discard the transaction, and the caller needs the
+// error that caused the rollback rather than this one.
+func (transactor *transactor) rollback(ctx context.Context, tx pgx.Tx) {
+	if err := tx.Rollback(context.WithoutCancel(ctx)); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		transactor.log.ErrorContext(ctx, "roll back transaction", slog.Any("error", err))
+	}
+}
+
+// conn returns the transaction carried by ctx, or client outside of one.
+func conn(ctx context.Context, client postgres.Client) postgres.Client {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return postgres.Txw{Tx: state.tx}
+	}
+
+	return client
+}
+
+// txOptions returns the options of the transaction carried by ctx, ok is false outside of one.
+func txOptions(ctx context.Context) (options pgx.TxOptions, ok bool) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		return pgx.TxOptions{}, false
+	}
+
+	return state.options, true
+}
@@ -0,0 +1,87 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// Ensure, that TransactorMock does implement Transactor.
+// If this is not the case, regenerate this file with moq.
+var _ Transactor = &TransactorMock{}
+
+// TransactorMock is a mock implementation of Transactor.
+//
+//	func TestSomethingThatUsesTransactor(t *testing.T) {
+//
+//		// make and configure a mocked Transactor
+//		mockedTransactor := &TransactorMock{
+//			WithTransactionFunc: func(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+//				panic("mock out the WithTransaction method")
+//			},
+//		}
+//
+//		// use mockedTransactor in code that requires Transactor
+//		// and then make assertions.
+//
+//	}
+type TransactorMock struct {
+	// WithTransactionFunc mocks the WithTransaction method.
+	WithTransactionFunc func(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// WithTransaction holds details about calls to the WithTransaction method.
+		WithTransaction []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Fn is the fn argument value.
+			Fn func(ctx context.Context) error
+			// Opts is the opts argument value.
+			Opts []TxOption
+		}
+	}
+	lockWithTransaction sync.RWMutex
+}
+
+// WithTransaction calls WithTransactionFunc.
+func (mock *TransactorMock) WithTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	if mock.WithTransactionFunc == nil {
+		panic("TransactorMock.WithTransactionFunc: method is nil but Transactor.WithTransaction was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Fn   func(ctx context.Context) error
+		Opts []TxOption
+	}{
+		Ctx:  ctx,
+		Fn:   fn,
+		Opts: opts,
+	}
+	mock.lockWithTransaction.Lock()
+	mock.calls.WithTransaction = append(mock.calls.WithTransaction, callInfo)
+	mock.lockWithTransaction.Unlock()
+	return mock.WithTransactionFunc(ctx, fn, opts...)
+}
+
+// WithTransactionCalls gets all the calls that were made to WithTransaction.
+// Check the length with:
+//
+//	len(mockedTransactor.WithTransactionCalls())
+func (mock *TransactorMock) WithTransactionCalls() []struct {
+	Ctx  context.Context
+	Fn   func(ctx context.Context) error
+	Opts []TxOption
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Fn   func(ctx context.Context) error
+		Opts []TxOption
+	}
+	mock.lockWithTransaction.RLock()
+	calls = mock.calls.WithTransaction
+	mock.lockWithTransaction.RUnlock()
+	return calls
+}
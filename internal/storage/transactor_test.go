@@ -0,0 +1,291 @@
+package storage_test
+
+import (
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"bank/pkg/logger"
+	"bank/pkg/postgres"
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+// fakePool starts fakeTx transactions, so the transactor runs without a database.
+type fakePool struct {
+	postgres.Pool
+	commits     []error
+	rollbackErr error
+
+	begins    int
+	committed int
+	rollbacks int
+}
+
+func (pool *fakePool) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	pool.begins++
+	return &fakeTx{pool: pool}, nil
+}
+
+// fakeTx fails the commits of its pool with the next error of commits until they run out, and its rollbacks with
+// rollbackErr.
+type fakeTx struct {
+	pgx.Tx
+	pool *fakePool
+}
+
+func (tx *fakeTx) Begin(context.Context) (pgx.Tx, error) {
+	return &fakeTx{pool: tx.pool}, nil
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	if len(tx.pool.commits) == 0 {
+		tx.pool.committed++
+		return nil
+	}
+
+	err := tx.pool.commits[0]
+	tx.pool.commits = tx.pool.commits[1:]
+
+	return err
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	tx.pool.rollbacks++
+	return tx.pool.rollbackErr
+}
+
+// pgError is a driver error as the storages return it.
+func pgError(code string) apperror.Error {
+	return apperror.Internal.WithError(&pgconn.PgError{Code: code})
+}
+
+func TestTransactor_Retry(t *testing.T) {
+	policy := storage.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, Budget: 10}
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name    string
+		policy  storage.RetryPolicy
+		fn      []error
+		commits []error
+		wantErr error
+		runs    int
+	}{
+		{
+			name:    "serialization failure on commit",
+			policy:  policy,
+			commits: []error{&pgconn.PgError{Code: "40001"}},
+			runs:    2,
+		},
+		{
+			name:   "deadlock in fn",
+			policy: policy,
+			fn:     []error{pgError("40P01").WithScope("balanceService.lock"), pgError("40P01")},
+			runs:   3,
+		},
+		{
+			name:    "attempts run out",
+			policy:  policy,
+			fn:      []error{pgError("40001"), pgError("40001"), pgError("40001"), pgError("40001")},
+			wantErr: apperror.Internal,
+			runs:    3,
+		},
+		{
+			name:    "budget runs out",
+			policy:  storage.RetryPolicy{MaxAttempts: 5, Budget: 1},
+			fn:      []error{pgError("40001"), pgError("40001"), pgError("40001")},
+			wantErr: apperror.Internal,
+			runs:    2,
+		},
+		{
+			name:    "other driver errors are not retried",
+			policy:  policy,
+			fn:      []error{pgError("23505")},
+			wantErr: apperror.Internal,
+			runs:    1,
+		},
+		{
+			name:    "business errors are not retried",
+			policy:  policy,
+			fn:      []error{errFailed},
+			wantErr: errFailed,
+			runs:    1,
+		},
+		{
+			name:    "zero policy",
+			fn:      []error{pgError("40001")},
+			wantErr: apperror.Internal,
+			runs:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &fakePool{commits: tt.commits}
+			transactor := storage.NewTransactor(pool, tt.policy, logger.New(io.Discard, "info"))
+
+			runs := 0
+			err := transactor.WithTransaction(context.Background(), func(ctx context.Context) error {
+				runs++
+				if runs <= len(tt.fn) {
+					return tt.fn[runs-1]
+				}
+
+				return nil
+			})
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.runs, runs)
+			assert.Equal(t, tt.runs, pool.begins, "every run starts a transaction")
+		})
+	}
+}
+
+func TestTransactor_RetryCanceled(t *testing.T) {
+	pool := &fakePool{}
+	transactor := storage.NewTransactor(pool, storage.RetryPolicy{MaxAttempts: 3, Backoff: time.Hour, MaxBackoff: time.Hour, Budget: 10}, logger.New(io.Discard, "info"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	runs := 0
+	err := transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		runs++
+		cancel()
+
+		return pgError("40001")
+	})
+	assert.ErrorIs(t, err, apperror.Internal)
+	assert.Equal(t, 1, runs, "the backoff ends with the context")
+}
+
+func TestTransactor_End(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name        string
+		fn          func(ctx context.Context, cancel context.CancelFunc) error
+		commits     []error
+		rollbackErr error
+		wantErr     error
+		panics      bool
+		committed   int
+		rollbacks   int
+	}{
+		{
+			name:      "commit",
+			fn:        func(context.Context, context.CancelFunc) error { return nil },
+			committed: 1,
+		},
+		{
+			name:    "commit failure",
+			fn:      func(context.Context, context.CancelFunc) error { return nil },
+			commits: []error{errors.New("connection reset")},
+			wantErr: apperror.Internal,
+		},
+		{
+			name:      "error",
+			fn:        func(context.Context, context.CancelFunc) error { return errFailed },
+			wantErr:   errFailed,
+			rollbacks: 1,
+		},
+		{
+			name:        "rollback failure keeps the error of fn",
+			fn:          func(context.Context, context.CancelFunc) error { return errFailed },
+			rollbackErr: errors.New("connection reset"),
+			wantErr:     errFailed,
+			rollbacks:   1,
+		},
+		{
+			name:      "panic",
+			fn:        func(context.Context, context.CancelFunc) error { panic("boom") },
+			panics:    true,
+			rollbacks: 1,
+		},
+		{
+			name:        "panic with rollback failure",
+			fn:          func(context.Context, context.CancelFunc) error { panic("boom") },
+			rollbackErr: errors.New("connection reset"),
+			panics:      true,
+			rollbacks:   1,
+		},
+		{
+			name: "canceled context",
+			fn: func(_ context.Context, cancel context.CancelFunc) error {
+				cancel()
+				return nil
+			},
+			wantErr:   apperror.Internal,
+			rollbacks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &fakePool{commits: tt.commits, rollbackErr: tt.rollbackErr}
+			transactor := storage.NewTransactor(pool, storage.RetryPolicy{}, logger.New(io.Discard, "info"))
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			run := func() error {
+				return transactor.WithTransaction(ctx, func(ctx context.Context) error {
+					return tt.fn(ctx, cancel)
+				})
+			}
+
+			if tt.panics {
+				assert.PanicsWithValue(t, "boom", func() { _ = run() })
+			} else if tt.wantErr != nil {
+				assert.ErrorIs(t, run(), tt.wantErr)
+			} else {
+				assert.NoError(t, run())
+			}
+
+			assert.Equal(t, tt.committed, pool.committed)
+			assert.Equal(t, tt.rollbacks, pool.rollbacks)
+		})
+	}
+}
+
+func TestTransactor_SavepointOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		outer   []storage.TxOption
+		nested  []storage.TxOption
+		wantErr error
+	}{
+		{name: "same options", outer: []storage.TxOption{storage.ReadOnly()}},
+		{name: "read only in read write", nested: []storage.TxOption{storage.ReadOnly()}, wantErr: apperror.Internal},
+		{name: "another isolation level", nested: []storage.TxOption{storage.WithIsolation(pgx.Serializable)}, wantErr: apperror.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &fakePool{}
+			transactor := storage.NewTransactor(pool, storage.RetryPolicy{}, logger.New(io.Discard, "info"))
+
+			var ran bool
+			err := transactor.WithTransaction(context.Background(), func(ctx context.Context) error {
+				return transactor.WithTransaction(ctx, func(ctx context.Context) error {
+					ran = true
+					return nil
+				}, tt.nested...)
+			}, tt.outer...)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantErr == nil, ran, "a conflicting savepoint does not run fn")
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package storage
+
+import (
+	"bank/internal/model"
+	"bank/pkg/apperror"
+	"bank/pkg/postgres"
+	"context"
+	"errors"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+//go:generate moq -out webhook_mock.go . WebhookStorage
+type WebhookStorage interface {
+	CreateSubscription(ctx context.Context, subscription model.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id uuid.UUID) (model.WebhookSubscription, error)
+	SelectSubscription(ctx context.Context, accountID uuid.UUID, count, offset uint64) ([]model.WebhookSubscription, error)
+	DisableSubscription(ctx context.Context, id uuid.UUID) error
+	Enqueue(ctx context.Context, event model.Event) (int64, error)
+	Claim(ctx context.Context, limit uint64, lease time.Duration) ([]model.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID, attempts int) error
+	MarkFailed(ctx context.Context, id uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error
+	Replay(ctx context.Context, subscriptionID uuid.UUID) (int64, error)
+	SelectDelivery(ctx context.Context, subscriptionID uuid.UUID, status string, count, offset uint64) ([]model.WebhookDelivery, error)
+}
+
+type webhookStorage struct {
+	client postgres.Client
+}
+
+func NewWebhookStorage(client postgres.Client) WebhookStorage {
+	return &webhookStorage{client: client}
+}
+
+var (
+	subscriptionColumns = []string{
+		"id",
+		"account_id",
+		"url",
+		"secret",
+		"event_types",
+		"enabled",
+		"created_at",
+	}
+	deliveryColumns = []string{
+		"id",
+		"subscription_id",
+		"event_id",
+		"event_type",
+		"payload",
+		"status",
+		"attempts",
+		"next_attempt_at",
+		"last_error",
+		"created_at",
+		"delivered_at",
+	}
+)
+
+func (storage *webhookStorage) CreateSubscription(ctx context.Context, subscription model.WebhookSubscription) error {
+	builder := psql.
+		Insert("webhook_subscription").
+		Columns(subscriptionColumns...).
+		Values(
+			subscription.ID,
+			subscription.AccountID,
+			subscription.URL,
+			subscription.Secret,
+			subscription.EventTypes,
+			subscription.Enabled,
+			subscription.CreatedAt,
+		)
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	_, err = conn(ctx, storage.client).Exec(ctx, q, args...)
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	return nil
+}
+
+func (storage *webhookStorage) GetSubscription(ctx context.Context, id uuid.UUID) (model.WebhookSubscription, error) {
+	builder := psql.
+		Select(subscriptionColumns...).
+		From("webhook_subscription").
+		Where(squirrel.Eq{"id": id})
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return model.WebhookSubscription{}, apperror.Internal.WithError(err)
+	}
+
+	var subscription model.WebhookSubscription
+	err = conn(ctx, storage.client).Get(ctx, &subscription, q, args...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.WebhookSubscription{}, apperror.NotFound.WithError(err)
+		}
+
+		return model.WebhookSubscription{}, apperror.Internal.WithError(err)
+	}
+
+	return subscription, nil
+}
+
+func (storage *webhookStorage) SelectSubscription(ctx context.Context, accountID uuid.UUID, count, offset uint64) ([]model.WebhookSubscription, error) {
+	builder := psql.
+		Select(subscriptionColumns...).
+		From("webhook_subscription").
+		OrderBy("created_at").
+		Offset(offset)
+
+	if accountID != uuid.Nil {
+		builder = builder.Where(squirrel.Eq{"account_id": accountID})
+	}
+
+	if count != 0 {
+		builder = builder.Limit(count)
+	}
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return []model.WebhookSubscription{}, apperror.Internal.WithError(err)
+	}
+
+	var subscriptions []model.WebhookSubscription
+	err = conn(ctx, storage.client).Select(ctx, &subscriptions, q, args...)
+	if err != nil {
+		return []model.WebhookSubscription{}, apperror.Internal.WithError(err)
+	}
+
+	return subscriptions, nil
+}
+
+func (storage *webhookStorage) DisableSubscription(ctx context.Context, id uuid.UUID) error {
+	builder := psql.
+		Update("webhook_subscription").
+		Set("enabled", false).
+		Where(squirrel.Eq{"id": id})
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	tag, err := conn(ctx, storage.client).Exec(ctx, q, args...)
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return apperror.NotFound.WithMessage("subscription not found")
+	}
+
+	return nil
+}
+
+// Enqueue creates a pending delivery of event for every enabled matching subscription: those to one of the accounts
+// of event, or those to every account when event has no accounts.
+// Enqueuing the same event twice is a no-op, which keeps the outbox's at-least-once relay from duplicating deliveries.
+func (storage *webhookStorage) Enqueue(ctx context.Context, event model.Event) (int64, error) {
+	const q = `
+INSERT INTO webhook_delivery (subscription_id, event_id, event_type, payload, next_attempt_at, created_at)
+SELECT id, $1, $2, $3, NOW(), NOW()
+FROM webhook_subscription
+WHERE enabled
+  AND (CASE WHEN COALESCE(CARDINALITY($4::UUID[]), 0) = 0 THEN account_id IS NULL ELSE account_id = ANY ($4) END)
+  AND (CARDINALITY(event_types) = 0 OR $2 = ANY (event_types))
+ON CONFLICT (subscription_id, event_id) DO NOTHING`
+
+	tag, err := conn(ctx, storage.client).Exec(ctx, q, event.EventID, event.Type, event.Payload, event.AccountIDs)
+	if err != nil {
+		return 0, apperror.Internal.WithError(err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// Claim leases up to limit due deliveries of enabled subscriptions by pushing their next attempt lease into the
+// future, so a crashed dispatcher's deliveries are retried once the lease expires and concurrent dispatchers
+// never pick the same delivery.
+func (storage *webhookStorage) Claim(ctx context.Context, limit uint64, lease time.Duration) ([]model.WebhookDelivery, error) {
+	const q = `
+UPDATE webhook_delivery
+SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond'
+WHERE id IN (SELECT d.id
+             FROM webhook_delivery d
+                      JOIN webhook_subscription s ON s.id = d.subscription_id
+             WHERE d.status = 'pending'
+               AND d.next_attempt_at <= NOW()
+               AND s.enabled
+             ORDER BY d.next_attempt_at
+             LIMIT $1 FOR UPDATE OF d SKIP LOCKED)
+RETURNING id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at`
+
+	var deliveries []model.WebhookDelivery
+	err := conn(ctx, storage.client).Select(ctx, &deliveries, q, limit, lease.Milliseconds())
+	if err != nil {
+		return []model.WebhookDelivery{}, apperror.Internal.WithError(err)
+	}
+
+	return deliveries, nil
+}
+
+func (storage *webhookStorage) MarkDelivered(ctx context.Context, id uuid.UUID, attempts int) error {
+	builder := psql.
+		Update("webhook_delivery").
+		Set("status", "delivered").
+		Set("attempts", attempts).
+		Set("last_error", "").
+		Set("delivered_at", time.Now()).
+		Where(squirrel.Eq{"id": id})
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	_, err = conn(ctx, storage.client).Exec(ctx, q, args...)
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	return nil
+}
+
+func (storage *webhookStorage) MarkFailed(ctx context.Context, id uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error {
+	status := "pending"
+	if dead {
+		status = "dead"
+	}
+
+	builder := psql.
+		Update("webhook_delivery").
+		Set("status", status).
+		Set("attempts", attempts).
+		Set("next_attempt_at", nextAttemptAt).
+		Set("last_error", lastError).
+		Where(squirrel.Eq{"id": id})
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	_, err = conn(ctx, storage.client).Exec(ctx, q, args...)
+	if err != nil {
+		return apperror.Internal.WithError(err)
+	}
+
+	return nil
+}
+
+// Replay moves dead deliveries of the subscription back to pending with a fresh retry budget.
+func (storage *webhookStorage) Replay(ctx context.Context, subscriptionID uuid.UUID) (int64, error) {
+	builder := psql.
+		Update("webhook_delivery").
+		Set("status", "pending").
+		Set("attempts", 0).
+		Set("next_attempt_at", time.Now()).
+		Where(squirrel.Eq{"subscription_id": subscriptionID, "status": "dead"})
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return 0, apperror.Internal.WithError(err)
+	}
+
+	tag, err := conn(ctx, storage.client).Exec(ctx, q, args...)
+	if err != nil {
+		return 0, apperror.Internal.WithError(err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (storage *webhookStorage) SelectDelivery(ctx context.Context, subscriptionID uuid.UUID, status string, count, offset uint64) ([]model.WebhookDelivery, error) {
+	builder := psql.
+		Select(deliveryColumns...).
+		From("webhook_delivery").
+		Where(squirrel.Eq{"subscription_id": subscriptionID}).
+		OrderBy("created_at DESC").
+		Offset(offset)
+
+	if status != "" {
+		builder = builder.Where(squirrel.Eq{"status": status})
+	}
+
+	if count != 0 {
+		builder = builder.Limit(count)
+	}
+
+	q, args, err := builder.ToSql()
+	if err != nil {
+		return []model.WebhookDelivery{}, apperror.Internal.WithError(err)
+	}
+
+	var deliveries []model.WebhookDelivery
+	err = conn(ctx, storage.client).Select(ctx, &deliveries, q, args...)
+	if err != nil {
+		return []model.WebhookDelivery{}, apperror.Internal.WithError(err)
+	}
+
+	return deliveries, nil
+}
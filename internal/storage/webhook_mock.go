@@ -0,0 +1,600 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package storage
+
+import (
+	"bank/internal/model"
+	"context"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// Ensure, that WebhookStorageMock does implement WebhookStorage.
+// If this is not the case, regenerate this file with moq.
+var _ WebhookStorage = &WebhookStorageMock{}
+
+// WebhookStorageMock is a mock implementation of WebhookStorage.
+//
+//	func TestSomethingThatUsesWebhookStorage(t *testing.T) {
+//
+//		// make and configure a mocked WebhookStorage
+//		mockedWebhookStorage := &WebhookStorageMock{
+//			ClaimFunc: func(ctx context.Context, limit uint64, lease time.Duration) ([]model.WebhookDelivery, error) {
+//				panic("mock out the Claim method")
+//			},
+//			CreateSubscriptionFunc: func(ctx context.Context, subscription model.WebhookSubscription) error {
+//				panic("mock out the CreateSubscription method")
+//			},
+//			DisableSubscriptionFunc: func(ctx context.Context, id uuid.UUID) error {
+//				panic("mock out the DisableSubscription method")
+//			},
+//			EnqueueFunc: func(ctx context.Context, event model.Event) (int64, error) {
+//				panic("mock out the Enqueue method")
+//			},
+//			GetSubscriptionFunc: func(ctx context.Context, id uuid.UUID) (model.WebhookSubscription, error) {
+//				panic("mock out the GetSubscription method")
+//			},
+//			MarkDeliveredFunc: func(ctx context.Context, id uuid.UUID, attempts int) error {
+//				panic("mock out the MarkDelivered method")
+//			},
+//			MarkFailedFunc: func(ctx context.Context, id uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error {
+//				panic("mock out the MarkFailed method")
+//			},
+//			ReplayFunc: func(ctx context.Context, subscriptionID uuid.UUID) (int64, error) {
+//				panic("mock out the Replay method")
+//			},
+//			SelectDeliveryFunc: func(ctx context.Context, subscriptionID uuid.UUID, status string, count uint64, offset uint64) ([]model.WebhookDelivery, error) {
+//				panic("mock out the SelectDelivery method")
+//			},
+//			SelectSubscriptionFunc: func(ctx context.Context, accountID uuid.UUID, count uint64, offset uint64) ([]model.WebhookSubscription, error) {
+//				panic("mock out the SelectSubscription method")
+//			},
+//		}
+//
+//		// use mockedWebhookStorage in code that requires WebhookStorage
+//		// and then make assertions.
+//
+//	}
+type WebhookStorageMock struct {
+	// ClaimFunc mocks the Claim method.
+	ClaimFunc func(ctx context.Context, limit uint64, lease time.Duration) ([]model.WebhookDelivery, error)
+
+	// CreateSubscriptionFunc mocks the CreateSubscription method.
+	CreateSubscriptionFunc func(ctx context.Context, subscription model.WebhookSubscription) error
+
+	// DisableSubscriptionFunc mocks the DisableSubscription method.
+	DisableSubscriptionFunc func(ctx context.Context, id uuid.UUID) error
+
+	// EnqueueFunc mocks the Enqueue method.
+	EnqueueFunc func(ctx context.Context, event model.Event) (int64, error)
+
+	// GetSubscriptionFunc mocks the GetSubscription method.
+	GetSubscriptionFunc func(ctx context.Context, id uuid.UUID) (model.WebhookSubscription, error)
+
+	// MarkDeliveredFunc mocks the MarkDelivered method.
+	MarkDeliveredFunc func(ctx context.Context, id uuid.UUID, attempts int) error
+
+	// MarkFailedFunc mocks the MarkFailed method.
+	MarkFailedFunc func(ctx context.Context, id uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error
+
+	// ReplayFunc mocks the Replay method.
+	ReplayFunc func(ctx context.Context, subscriptionID uuid.UUID) (int64, error)
+
+	// SelectDeliveryFunc mocks the SelectDelivery method.
+	SelectDeliveryFunc func(ctx context.Context, subscriptionID uuid.UUID, status string, count uint64, offset uint64) ([]model.WebhookDelivery, error)
+
+	// SelectSubscriptionFunc mocks the SelectSubscription method.
+	SelectSubscriptionFunc func(ctx context.Context, accountID uuid.UUID, count uint64, offset uint64) ([]model.WebhookSubscription, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Claim holds details about calls to the Claim method.
+		Claim []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Limit is the limit argument value.
+			Limit uint64
+			// Lease is the lease argument value.
+			Lease time.Duration
+		}
+		// CreateSubscription holds details about calls to the CreateSubscription method.
+		CreateSubscription []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Subscription is the subscription argument value.
+			Subscription model.WebhookSubscription
+		}
+		// DisableSubscription holds details about calls to the DisableSubscription method.
+		DisableSubscription []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID uuid.UUID
+		}
+		// Enqueue holds details about calls to the Enqueue method.
+		Enqueue []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Event is the event argument value.
+			Event model.Event
+		}
+		// GetSubscription holds details about calls to the GetSubscription method.
+		GetSubscription []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID uuid.UUID
+		}
+		// MarkDelivered holds details about calls to the MarkDelivered method.
+		MarkDelivered []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID uuid.UUID
+			// Attempts is the attempts argument value.
+			Attempts int
+		}
+		// MarkFailed holds details about calls to the MarkFailed method.
+		MarkFailed []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID uuid.UUID
+			// Attempts is the attempts argument value.
+			Attempts int
+			// NextAttemptAt is the nextAttemptAt argument value.
+			NextAttemptAt time.Time
+			// LastError is the lastError argument value.
+			LastError string
+			// Dead is the dead argument value.
+			Dead bool
+		}
+		// Replay holds details about calls to the Replay method.
+		Replay []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// SubscriptionID is the subscriptionID argument value.
+			SubscriptionID uuid.UUID
+		}
+		// SelectDelivery holds details about calls to the SelectDelivery method.
+		SelectDelivery []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// SubscriptionID is the subscriptionID argument value.
+			SubscriptionID uuid.UUID
+			// Status is the status argument value.
+			Status string
+			// Count is the count argument value.
+			Count uint64
+			// Offset is the offset argument value.
+			Offset uint64
+		}
+		// SelectSubscription holds details about calls to the SelectSubscription method.
+		SelectSubscription []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// AccountID is the accountID argument value.
+			AccountID uuid.UUID
+			// Count is the count argument value.
+			Count uint64
+			// Offset is the offset argument value.
+			Offset uint64
+		}
+	}
+	lockClaim               sync.RWMutex
+	lockCreateSubscription  sync.RWMutex
+	lockDisableSubscription sync.RWMutex
+	lockEnqueue             sync.RWMutex
+	lockGetSubscription     sync.RWMutex
+	lockMarkDelivered       sync.RWMutex
+	lockMarkFailed          sync.RWMutex
+	lockReplay              sync.RWMutex
+	lockSelectDelivery      sync.RWMutex
+	lockSelectSubscription  sync.RWMutex
+}
+
+// Claim calls ClaimFunc.
+func (mock *WebhookStorageMock) Claim(ctx context.Context, limit uint64, lease time.Duration) ([]model.WebhookDelivery, error) {
+	if mock.ClaimFunc == nil {
+		panic("WebhookStorageMock.ClaimFunc: method is nil but WebhookStorage.Claim was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Limit uint64
+		Lease time.Duration
+	}{
+		Ctx:   ctx,
+		Limit: limit,
+		Lease: lease,
+	}
+	mock.lockClaim.Lock()
+	mock.calls.Claim = append(mock.calls.Claim, callInfo)
+	mock.lockClaim.Unlock()
+	return mock.ClaimFunc(ctx, limit, lease)
+}
+
+// ClaimCalls gets all the calls that were made to Claim.
+// Check the length with:
+//
+//	len(mockedWebhookStorage.ClaimCalls())
+func (mock *WebhookStorageMock) ClaimCalls() []struct {
+	Ctx   context.Context
+	Limit uint64
+	Lease time.Duration
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Limit uint64
+		Lease time.Duration
+	}
+	mock.lockClaim.RLock()
+	calls = mock.calls.Claim
+	mock.lockClaim.RUnlock()
+	return calls
+}
+
+// CreateSubscription calls CreateSubscriptionFunc.
+func (mock *WebhookStorageMock) CreateSubscription(ctx context.Context, subscription model.WebhookSubscription) error {
+	if mock.CreateSubscriptionFunc == nil {
+		panic("WebhookStorageMock.CreateSubscriptionFunc: method is nil but WebhookStorage.CreateSubscription was just called")
+	}
+	callInfo := struct {
+		Ctx          context.Context
+		Subscription model.WebhookSubscription
+	}{
+		Ctx:          ctx,
+		Subscription: subscription,
+	}
+	mock.lockCreateSubscription.Lock()
+	mock.calls.CreateSubscription = append(mock.calls.CreateSubscription, callInfo)
+	mock.lockCreateSubscription.Unlock()
+	return mock.CreateSubscriptionFunc(ctx, subscription)
+}
+
+// CreateSubscriptionCalls gets all the calls that were made to CreateSubscription.
+// Check the length with:
+//
+//	len(mockedWebhookStorage.CreateSubscriptionCalls())
+func (mock *WebhookStorageMock) CreateSubscriptionCalls() []struct {
+	Ctx          context.Context
+	Subscription model.WebhookSubscription
+} {
+	var calls []struct {
+		Ctx          context.Context
+		Subscription model.WebhookSubscription
+	}
+	mock.lockCreateSubscription.RLock()
+	calls = mock.calls.CreateSubscription
+	mock.lockCreateSubscription.RUnlock()
+	return calls
+}
+
+// DisableSubscription calls DisableSubscriptionFunc.
+func (mock *WebhookStorageMock) DisableSubscription(ctx context.Context, id uuid.UUID) error {
+	if mock.DisableSubscriptionFunc == nil {
+		panic("WebhookStorageMock.DisableSubscriptionFunc: method is nil but WebhookStorage.DisableSubscription was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockDisableSubscription.Lock()
+	mock.calls.DisableSubscription = append(mock.calls.DisableSubscription, callInfo)
+	mock.lockDisableSubscription.Unlock()
+	return mock.DisableSubscriptionFunc(ctx, id)
+}
+
+// DisableSubscriptionCalls gets all the calls that were made to DisableSubscription.
+// Check the length with:
+//
+//	len(mockedWebhookStorage.DisableSubscriptionCalls())
+func (mock *WebhookStorageMock) DisableSubscriptionCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}
+	mock.lockDisableSubscription.RLock()
+	calls = mock.calls.DisableSubscription
+	mock.lockDisableSubscription.RUnlock()
+	return calls
+}
+
+// Enqueue calls EnqueueFunc.
+func (mock *WebhookStorageMock) Enqueue(ctx context.Context, event model.Event) (int64, error) {
+	if mock.EnqueueFunc == nil {
+		panic("WebhookStorageMock.EnqueueFunc: method is nil but WebhookStorage.Enqueue was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Event model.Event
+	}{
+		Ctx:   ctx,
+		Event: event,
+	}
+	mock.lockEnqueue.Lock()
+	mock.calls.Enqueue = append(mock.calls.Enqueue, callInfo)
+	mock.lockEnqueue.Unlock()
+	return mock.EnqueueFunc(ctx, event)
+}
+
+// EnqueueCalls gets all the calls that were made to Enqueue.
+// Check the length with:
+//
+//	len(mockedWebhookStorage.EnqueueCalls())
+func (mock *WebhookStorageMock) EnqueueCalls() []struct {
+	Ctx   context.Context
+	Event model.Event
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Event model.Event
+	}
+	mock.lockEnqueue.RLock()
+	calls = mock.calls.Enqueue
+	mock.lockEnqueue.RUnlock()
+	return calls
+}
+
+// GetSubscription calls GetSubscriptionFunc.
+func (mock *WebhookStorageMock) GetSubscription(ctx context.Context, id uuid.UUID) (model.WebhookSubscription, error) {
+	if mock.GetSubscriptionFunc == nil {
+		panic("WebhookStorageMock.GetSubscriptionFunc: method is nil but WebhookStorage.GetSubscription was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockGetSubscription.Lock()
+	mock.calls.GetSubscription = append(mock.calls.GetSubscription, callInfo)
+	mock.lockGetSubscription.Unlock()
+	return mock.GetSubscriptionFunc(ctx, id)
+}
+
+// GetSubscriptionCalls gets all the calls that were made to GetSubscription.
+// Check the length with:
+//
+//	len(mockedWebhookStorage.GetSubscriptionCalls())
+func (mock *WebhookStorageMock) GetSubscriptionCalls() []struct {
+	Ctx context.Context
+	ID  uuid.UUID
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uuid.UUID
+	}
+	mock.lockGetSubscription.RLock()
+	calls = mock.calls.GetSubscription
+	mock.lockGetSubscription.RUnlock()
+	return calls
+}
+
+// MarkDelivered calls MarkDeliveredFunc.
+func (mock *WebhookStorageMock) MarkDelivered(ctx context.Context, id uuid.UUID, attempts int) error {
+	if mock.MarkDeliveredFunc == nil {
+		panic("WebhookStorageMock.MarkDeliveredFunc: method is nil but WebhookStorage.MarkDelivered was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		ID       uuid.UUID
+		Attempts int
+	}{
+		Ctx:      ctx,
+		ID:       id,
+		Attempts: attempts,
+	}
+	mock.lockMarkDelivered.Lock()
+	mock.calls.MarkDelivered = append(mock.calls.MarkDelivered, callInfo)
+	mock.lockMarkDelivered.Unlock()
+	return mock.MarkDeliveredFunc(ctx, id, attempts)
+}
+
+// MarkDeliveredCalls gets all the calls that were made to MarkDelivered.
+// Check the length with:
+//
+//	len(mockedWebhookStorage.MarkDeliveredCalls())
+func (mock *WebhookStorageMock) MarkDeliveredCalls() []struct {
+	Ctx      context.Context
+	ID       uuid.UUID
+	Attempts int
+} {
+	var calls []struct {
+		Ctx      context.Context
+		ID       uuid.UUID
+		Attempts int
+	}
+	mock.lockMarkDelivered.RLock()
+	calls = mock.calls.MarkDelivered
+	mock.lockMarkDelivered.RUnlock()
+	return calls
+}
+
+// MarkFailed calls MarkFailedFunc.
+func (mock *WebhookStorageMock) MarkFailed(ctx context.Context, id uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error {
+	if mock.MarkFailedFunc == nil {
+		panic("WebhookStorageMock.MarkFailedFunc: method is nil but WebhookStorage.MarkFailed was just called")
+	}
+	callInfo := struct {
+		Ctx           context.Context
+		ID            uuid.UUID
+		Attempts      int
+		NextAttemptAt time.Time
+		LastError     string
+		Dead          bool
+	}{
+		Ctx:           ctx,
+		ID:            id,
+		Attempts:      attempts,
+		NextAttemptAt: nextAttemptAt,
+		LastError:     lastError,
+		Dead:          dead,
+	}
+	mock.lockMarkFailed.Lock()
+	mock.calls.MarkFailed = append(mock.calls.MarkFailed, callInfo)
+	mock.lockMarkFailed.Unlock()
+	return mock.MarkFailedFunc(ctx, id, attempts, nextAttemptAt, lastError, dead)
+}
+
+// MarkFailedCalls gets all the calls that were made to MarkFailed.
+// Check the length with:
+//
+//	len(mockedWebhookStorage.MarkFailedCalls())
+func (mock *WebhookStorageMock) MarkFailedCalls() []struct {
+	Ctx           context.Context
+	ID            uuid.UUID
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	Dead          bool
+} {
+	var calls []struct {
+		Ctx           context.Context
+		ID            uuid.UUID
+		Attempts      int
+		NextAttemptAt time.Time
+		LastError     string
+		Dead          bool
+	}
+	mock.lockMarkFailed.RLock()
+	calls = mock.calls.MarkFailed
+	mock.lockMarkFailed.RUnlock()
+	return calls
+}
+
+// Replay calls ReplayFunc.
+func (mock *WebhookStorageMock) Replay(ctx context.Context, subscriptionID uuid.UUID) (int64, error) {
+	if mock.ReplayFunc == nil {
+		panic("WebhookStorageMock.ReplayFunc: method is nil but WebhookStorage.Replay was just called")
+	}
+	callInfo := struct {
+		Ctx            context.Context
+		SubscriptionID uuid.UUID
+	}{
+		Ctx:            ctx,
+		SubscriptionID: subscriptionID,
+	}
+	mock.lockReplay.Lock()
+	mock.calls.Replay = append(mock.calls.Replay, callInfo)
+	mock.lockReplay.Unlock()
+	return mock.ReplayFunc(ctx, subscriptionID)
+}
+
+// ReplayCalls gets all the calls that were made to Replay.
+// Check the length with:
+//
+//	len(mockedWebhookStorage.ReplayCalls())
+func (mock *WebhookStorageMock) ReplayCalls() []struct {
+	Ctx            context.Context
+	SubscriptionID uuid.UUID
+} {
+	var calls []struct {
+		Ctx            context.Context
+		SubscriptionID uuid.UUID
+	}
+	mock.lockReplay.RLock()
+	calls = mock.calls.Replay
+	mock.lockReplay.RUnlock()
+	return calls
+}
+
+// SelectDelivery calls SelectDeliveryFunc.
+func (mock *WebhookStorageMock) SelectDelivery(ctx context.Context, subscriptionID uuid.UUID, status string, count uint64, offset uint64) ([]model.WebhookDelivery, error) {
+	if mock.SelectDeliveryFunc == nil {
+		panic("WebhookStorageMock.SelectDeliveryFunc: method is nil but WebhookStorage.SelectDelivery was just called")
+	}
+	callInfo := struct {
+		Ctx            context.Context
+		SubscriptionID uuid.UUID
+		Status         string
+		Count          uint64
+		Offset         uint64
+	}{
+		Ctx:            ctx,
+		SubscriptionID: subscriptionID,
+		Status:         status,
+		Count:          count,
+		Offset:         offset,
+	}
+	mock.lockSelectDelivery.Lock()
+	mock.calls.SelectDelivery = append(mock.calls.SelectDelivery, callInfo)
+	mock.lockSelectDelivery.Unlock()
+	return mock.SelectDeliveryFunc(ctx, subscriptionID, status, count, offset)
+}
+
+// SelectDeliveryCalls gets all the calls that were made to SelectDelivery.
+// Check the length with:
+//
+//	len(mockedWebhookStorage.SelectDeliveryCalls())
+func (mock *WebhookStorageMock) SelectDeliveryCalls() []struct {
+	Ctx            context.Context
+	SubscriptionID uuid.UUID
+	Status         string
+	Count          uint64
+	Offset         uint64
+} {
+	var calls []struct {
+		Ctx            context.Context
+		SubscriptionID uuid.UUID
+		Status         string
+		Count          uint64
+		Offset         uint64
+	}
+	mock.lockSelectDelivery.RLock()
+	calls = mock.calls.SelectDelivery
+	mock.lockSelectDelivery.RUnlock()
+	return calls
+}
+
+// SelectSubscription calls SelectSubscriptionFunc.
+func (mock *WebhookStorageMock) SelectSubscription(ctx context.Context, accountID uuid.UUID, count uint64, offset uint64) ([]model.WebhookSubscription, error) {
+	if mock.SelectSubscriptionFunc == nil {
+		panic("WebhookStorageMock.SelectSubscriptionFunc: method is nil but WebhookStorage.SelectSubscription was just called")
+	}
+	callInfo := struct {
+		Ctx       context.Context
+		AccountID uuid.UUID
+		Count     uint64
+		Offset    uint64
+	}{
+		Ctx:       ctx,
+		AccountID: accountID,
+		Count:     count,
+		Offset:    offset,
+	}
+	mock.lockSelectSubscription.Lock()
+	mock.calls.SelectSubscription = append(mock.calls.SelectSubscription, callInfo)
+	mock.lockSelectSubscription.Unlock()
+	return mock.SelectSubscriptionFunc(ctx, accountID, count, offset)
+}
+
+// SelectSubscriptionCalls gets all the calls that were made to SelectSubscription.
+// Check the length with:
+//
+//	len(mockedWebhookStorage.SelectSubscriptionCalls())
+func (mock *WebhookStorageMock) SelectSubscriptionCalls() []struct {
+	Ctx       context.Context
+	AccountID uuid.UUID
+	Count     uint64
+	Offset    uint64
+} {
+	var calls []struct {
+		Ctx       context.Context
+		AccountID uuid.UUID
+		Count     uint64
+		Offset    uint64
+	}
+	mock.lockSelectSubscription.RLock()
+	calls = mock.calls.SelectSubscription
+	mock.lockSelectSubscription.RUnlock()
+	return calls
+}
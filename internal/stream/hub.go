@@ -0,0 +1,170 @@
+package stream
+
+import (
+	"bank/internal/domain"
+	"bank/internal/model"
+	"encoding/json"
+	"github.com/google/uuid"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const subscriptionBuffer = 64
+
+type Subscription struct {
+	C <-chan domain.Activity
+
+	c         chan domain.Activity
+	accountID uuid.UUID
+	hub       *Hub
+	once      sync.Once
+}
+
+func (subscription *Subscription) Close() {
+	subscription.hub.unsubscribe(subscription)
+}
+
+// Hub fans out committed account activity to the streams of that account within this process.
+type Hub struct {
+	mu            sync.Mutex
+	subscriptions map[uuid.UUID]map[*Subscription]struct{}
+	closed        bool
+	log           *slog.Logger
+}
+
+func NewHub(log *slog.Logger) *Hub {
+	return &Hub{
+		subscriptions: make(map[uuid.UUID]map[*Subscription]struct{}),
+		log:           log,
+	}
+}
+
+func (hub *Hub) Subscribe(accountID uuid.UUID) *Subscription {
+	c := make(chan domain.Activity, subscriptionBuffer)
+	subscription := &Subscription{
+		C:         c,
+		c:         c,
+		accountID: accountID,
+		hub:       hub,
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	if hub.closed {
+		subscription.once.Do(func() {
+			close(subscription.c)
+		})
+		return subscription
+	}
+
+	if hub.subscriptions[accountID] == nil {
+		hub.subscriptions[accountID] = make(map[*Subscription]struct{})
+	}
+	hub.subscriptions[accountID][subscription] = struct{}{}
+
+	return subscription
+}
+
+// Publish never blocks: a subscriber that can't keep up is dropped and its channel closed,
+// the client then reconnects with Last-Event-ID and catches up from history.
+func (hub *Hub) Publish(activity domain.Activity) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for subscription := range hub.subscriptions[activity.AccountID] {
+		select {
+		case subscription.c <- activity:
+		default:
+			hub.remove(subscription)
+		}
+	}
+}
+
+// Close ends every stream, used on shutdown. Streams subscribed afterwards end immediately.
+func (hub *Hub) Close() {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.closed = true
+
+	for _, subscriptions := range hub.subscriptions {
+		for subscription := range subscriptions {
+			hub.remove(subscription)
+		}
+	}
+}
+
+func (hub *Hub) unsubscribe(subscription *Subscription) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.remove(subscription)
+}
+
+func (hub *Hub) remove(subscription *Subscription) {
+	subscriptions := hub.subscriptions[subscription.accountID]
+	delete(subscriptions, subscription)
+	if len(subscriptions) == 0 {
+		delete(hub.subscriptions, subscription.accountID)
+	}
+
+	subscription.once.Do(func() {
+		close(subscription.c)
+	})
+}
+
+type notification struct {
+	Kind        string    `json:"kind"`
+	AccountID   uuid.UUID `json:"account_id"`
+	Balance     int64     `json:"balance"`
+	Transaction *struct {
+		ID        uuid.UUID  `json:"id"`
+		PayeeID   uuid.UUID  `json:"payee_id"`
+		PayerID   *uuid.UUID `json:"payer_id"`
+		Type      string     `json:"type"`
+		Amount    int64      `json:"amount"`
+		Comment   string     `json:"comment"`
+		CreatedAt time.Time  `json:"created_at"`
+	} `json:"transaction"`
+}
+
+// Notify publishes a balance_activity notification sent by the database triggers.
+func (hub *Hub) Notify(payload string) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		hub.log.Error("invalid notification", slog.Any("error", err))
+		return
+	}
+
+	activity := domain.Activity{
+		Kind:      n.Kind,
+		AccountID: n.AccountID,
+	}
+
+	switch n.Kind {
+	case domain.ActivityBalance:
+		balance := domain.BalanceFromModel(model.Balance{UserID: n.AccountID, Balance: n.Balance})
+		activity.Balance = &balance
+	case domain.ActivityTransaction:
+		if n.Transaction == nil {
+			return
+		}
+
+		transaction := domain.TransactionFromModel(model.Transaction{
+			ID:        n.Transaction.ID,
+			PayeeID:   n.Transaction.PayeeID,
+			PayerID:   n.Transaction.PayerID,
+			Type:      n.Transaction.Type,
+			Amount:    n.Transaction.Amount,
+			Comment:   n.Transaction.Comment,
+			CreatedAt: n.Transaction.CreatedAt,
+		})
+		activity.Transaction = &transaction
+	default:
+		return
+	}
+
+	hub.Publish(activity)
+}
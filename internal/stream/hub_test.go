@@ -0,0 +1,115 @@
+package stream_test
+
+import (
+	"bank/internal/domain"
+	"bank/internal/stream"
+	"bank/pkg/logger"
+	"bytes"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestHub_Notify(t *testing.T) {
+	accountID := uuid.New()
+	transactionID := uuid.New()
+
+	tests := []struct {
+		name    string
+		payload string
+		want    *domain.Activity
+		logged  bool
+	}{
+		{
+			name:    "balance",
+			payload: `{"kind":"balance","account_id":"` + accountID.String() + `","balance":12345}`,
+			want: &domain.Activity{
+				Kind:      domain.ActivityBalance,
+				AccountID: accountID,
+				Balance:   &domain.Balance{UserID: accountID, Balance: 123.45},
+			},
+		},
+		{
+			name: "transaction",
+			payload: `{"kind":"transaction","account_id":"` + accountID.String() + `","transaction":{"id":"` + transactionID.String() +
+				`","payee_id":"` + accountID.String() + `","payer_id":null,"type":"debet","amount":500,"comment":"","created_at":"2023-05-01T12:00:00.123456+03:00"}}`,
+			want: &domain.Activity{
+				Kind:      domain.ActivityTransaction,
+				AccountID: accountID,
+			},
+		},
+		{name: "other account", payload: `{"kind":"balance","account_id":"` + uuid.NewString() + `","balance":1}`, want: nil},
+		{name: "unknown kind", payload: `{"kind":"other","account_id":"` + accountID.String() + `"}`, want: nil},
+		{name: "malformed", payload: `{`, want: nil, logged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			hub := stream.NewHub(logger.New(&logs, "info"))
+			subscription := hub.Subscribe(accountID)
+			defer subscription.Close()
+
+			hub.Notify(tt.payload)
+			assert.Equal(t, tt.logged, strings.Contains(logs.String(), "invalid notification"))
+
+			if tt.want == nil {
+				assert.Len(t, subscription.C, 0)
+				return
+			}
+
+			require.Len(t, subscription.C, 1)
+			got := <-subscription.C
+			assert.Equal(t, tt.want.Kind, got.Kind)
+			assert.Equal(t, tt.want.AccountID, got.AccountID)
+
+			if tt.want.Balance != nil {
+				assert.Equal(t, tt.want.Balance, got.Balance)
+			}
+
+			if tt.want.Kind == domain.ActivityTransaction {
+				require.NotNil(t, got.Transaction)
+				assert.Equal(t, transactionID, got.Transaction.ID)
+				assert.Equal(t, 5.0, got.Transaction.Amount)
+			}
+		})
+	}
+}
+
+func TestHub_SlowSubscriberIsDropped(t *testing.T) {
+	accountID := uuid.New()
+
+	hub := stream.NewHub(logger.New(io.Discard, "info"))
+	subscription := hub.Subscribe(accountID)
+
+	for i := 0; i < 1000; i++ {
+		hub.Publish(domain.Activity{Kind: domain.ActivityBalance, AccountID: accountID, Balance: &domain.Balance{}})
+	}
+
+	count := 0
+	for range subscription.C {
+		count++
+	}
+
+	assert.Less(t, count, 1000)
+
+	// closing an already dropped subscription is a no-op
+	subscription.Close()
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := stream.NewHub(logger.New(io.Discard, "info"))
+
+	before := hub.Subscribe(uuid.New())
+	hub.Close()
+	after := hub.Subscribe(uuid.New())
+
+	for _, subscription := range []*stream.Subscription{before, after} {
+		_, ok := <-subscription.C
+		assert.False(t, ok)
+		subscription.Close()
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"bank/internal/auth"
+	"bank/internal/domain"
+	"bank/internal/dto"
+	"bank/internal/service"
+	"bank/pkg/apperror"
+	"bank/pkg/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AuditHandler struct {
+	auditService service.AuditService
+	policy       *auth.Policy
+}
+
+func NewAuditHandler(auditService service.AuditService, policy *auth.Policy) *AuditHandler {
+	return &AuditHandler{auditService: auditService, policy: policy}
+}
+
+func (handler *AuditHandler) Register(router fiber.Router) {
+	router.Get("", handler.Select)
+	router.Get("/verify", handler.Verify)
+}
+
+// Select godoc
+// @Summary Retrieves audit log entries of mutating calls
+// @Produce json
+// @Param	account_id	query 	string 	false	"account id"	Format(uuid)
+// @Param	actor		query 	string 	false	"actor"			Format(string)
+// @Param	operation	query 	string 	false	"operation"		Enums(transfer, debet, credit)
+// @Param	request_id	query 	string 	false	"request id"	Format(string)
+// @Param	from		query 	string 	false	"from"			Format(date-time)
+// @Param	to			query 	string 	false	"to"			Format(date-time)
+// @Param	order		query 	string 	false	"order"			Enums(asc, desc)
+// @Param	count		query 	int 	false	"count"			Format(int)
+// @Param	offset		query 	int 	false	"offset"		Format(int)
+// @Security BearerAuth
+// @Success 200 {array} domain.Audit
+// @Failure 400 {object} apperror.Error
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
+// @Router /api/v1/audit [get]
+func (handler *AuditHandler) Select(c *fiber.Ctx) error {
+	var request dto.SelectAudit
+	if err := c.QueryParser(&request); err != nil {
+		return apperror.BadRequest.WithError(err)
+	}
+
+	err := validator.Validate(request)
+	if err != nil {
+		return err
+	}
+
+	err = authorize(c, handler.policy, auth.SelectAudit, request.AccountID)
+	if err != nil {
+		return err
+	}
+
+	var audits []domain.Audit
+	audits, err = handler.auditService.Select(c.UserContext(), request)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"response": audits,
+	})
+}
+
+// Verify godoc
+// @Summary Verifies the hash chain of the audit log
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.AuditVerification
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
+// @Router /api/v1/audit/verify [get]
+func (handler *AuditHandler) Verify(c *fiber.Ctx) error {
+	err := authorize(c, handler.policy, auth.VerifyAudit, uuid.Nil)
+	if err != nil {
+		return err
+	}
+
+	var verification domain.AuditVerification
+	verification, err = handler.auditService.Verify(c.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"response": verification,
+	})
+}
@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"bank/internal/auth"
+	"bank/pkg/apperror"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func authorize(c *fiber.Ctx, policy *auth.Policy, action auth.Action, accountID uuid.UUID) error {
+	principal, ok := auth.FromContext(c.UserContext())
+	if !ok {
+		return apperror.Unauthorized.WithMessage("caller is not identified")
+	}
+
+	return policy.Authorize(principal, action, accountID)
+}
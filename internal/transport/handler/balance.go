@@ -1,27 +1,38 @@
 package handler
 
 import (
+	"bank/internal/auth"
 	"bank/internal/domain"
 	"bank/internal/dto"
 	"bank/internal/service"
-	"bank/pkg/apilayer"
+	"bank/internal/stream"
 	"bank/pkg/apperror"
 	"bank/pkg/validator"
+	"bufio"
+	"errors"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"time"
 )
 
+const streamHeartbeat = 15 * time.Second
+
 type BalanceHandler struct {
 	balanceService service.BalanceService
-	apiLayer       *apilayer.Client
+	rates          service.RateProvider
+	policy         *auth.Policy
+	hub            *stream.Hub
 }
 
-func NewBalanceHandler(balanceService service.BalanceService, apilayer *apilayer.Client) *BalanceHandler {
-	return &BalanceHandler{balanceService: balanceService, apiLayer: apilayer}
+func NewBalanceHandler(balanceService service.BalanceService, rates service.RateProvider, policy *auth.Policy, hub *stream.Hub) *BalanceHandler {
+	return &BalanceHandler{balanceService: balanceService, rates: rates, policy: policy, hub: hub}
 }
 
 func (handler *BalanceHandler) Register(router fiber.Router) {
 	router.Get("", handler.Get)
+	router.Get("/at", handler.GetAt)
 	router.Get("/transaction", handler.SelectTransaction)
+	router.Get("/stream", handler.Stream)
 	router.Post("/transfer", handler.Transfer)
 	router.Post("/debet", handler.Debet)
 	router.Post("/credit", handler.Credit)
@@ -33,6 +44,10 @@ func (handler *BalanceHandler) Register(router fiber.Router) {
 // @Param	user_id  	query 	string 	true	"user id" 	Format(uuid)
 // @Param	currency	query 	string 	false	"currency"	Format(string)
 // @Success 200 {object} domain.Balance
+// @Security BearerAuth
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
 // @Failure 404 {object} apperror.Error
 // @Router /api/v1/balance [get]
 func (handler *BalanceHandler) Get(c *fiber.Ctx) error {
@@ -46,14 +61,19 @@ func (handler *BalanceHandler) Get(c *fiber.Ctx) error {
 		return err
 	}
 
+	err = authorize(c, handler.policy, auth.GetBalance, request.UserID)
+	if err != nil {
+		return err
+	}
+
 	var balance domain.Balance
-	balance, err = handler.balanceService.Get(c.Context(), request.UserID)
+	balance, err = handler.balanceService.Get(c.UserContext(), request.UserID)
 	if err != nil {
 		return err
 	}
 
 	if request.Currency != "" {
-		balance.Balance, err = handler.apiLayer.Convert(balance.Balance, "RUB", request.Currency)
+		balance.Balance, err = handler.rates.Convert(c.UserContext(), balance.Balance, "RUB", request.Currency)
 		if err != nil {
 			return apperror.BadRequest.WithError(err).WithMessage("invalid currency")
 		}
@@ -64,6 +84,47 @@ func (handler *BalanceHandler) Get(c *fiber.Ctx) error {
 	})
 }
 
+// GetAt godoc
+// @Summary Retrieves balance of given user ID at given moment
+// @Description The balance is the closing balance of the last day snapshotted before the timestamp plus the transactions since.
+// @Produce json
+// @Param	user_id  	query 	string 	true	"user id" 	Format(uuid)
+// @Param	timestamp	query 	string 	true	"RFC 3339 timestamp"	Format(date-time)
+// @Success 200 {object} domain.Balance
+// @Security BearerAuth
+// @Failure 400 {object} apperror.Error
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
+// @Failure 404 {object} apperror.Error
+// @Router /api/v1/balance/at [get]
+func (handler *BalanceHandler) GetAt(c *fiber.Ctx) error {
+	var request dto.GetBalanceAt
+	if err := c.QueryParser(&request); err != nil {
+		return apperror.BadRequest.WithError(err)
+	}
+
+	err := validator.Validate(request)
+	if err != nil {
+		return err
+	}
+
+	err = authorize(c, handler.policy, auth.GetBalance, request.UserID)
+	if err != nil {
+		return err
+	}
+
+	var balance domain.Balance
+	balance, err = handler.balanceService.GetAt(c.UserContext(), request)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"response": balance,
+	})
+}
+
 // SelectTransaction godoc
 // @Summary Retrieves transactions based on given user ID
 // @Produce json
@@ -73,6 +134,10 @@ func (handler *BalanceHandler) Get(c *fiber.Ctx) error {
 // @Param	count	    query 	int 	false	"count"		Format(int)
 // @Param	offset	    query 	int 	false	"offset"	Format(int)
 // @Success 200 {array} domain.Transaction
+// @Security BearerAuth
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
 // @Failure 404 {object} apperror.Error
 // @Router /api/v1/balance/transaction [get]
 func (handler *BalanceHandler) SelectTransaction(c *fiber.Ctx) error {
@@ -86,8 +151,13 @@ func (handler *BalanceHandler) SelectTransaction(c *fiber.Ctx) error {
 		return err
 	}
 
+	err = authorize(c, handler.policy, auth.SelectTransaction, request.UserID)
+	if err != nil {
+		return err
+	}
+
 	var transactions []domain.Transaction
-	transactions, err = handler.balanceService.SelectTransaction(c.Context(), request)
+	transactions, err = handler.balanceService.SelectTransaction(c.UserContext(), request)
 	if err != nil {
 		return err
 	}
@@ -102,6 +172,10 @@ func (handler *BalanceHandler) SelectTransaction(c *fiber.Ctx) error {
 // @Produce json
 // @Param request body dto.Transfer true "transfer params"
 // @Success 200 {int} 1
+// @Security BearerAuth
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
 // @Failure 404 {object} apperror.Error
 // @Router /api/v1/balance/transfer [post]
 func (handler *BalanceHandler) Transfer(c *fiber.Ctx) error {
@@ -115,7 +189,12 @@ func (handler *BalanceHandler) Transfer(c *fiber.Ctx) error {
 		return err
 	}
 
-	err = handler.balanceService.Transfer(c.Context(), request)
+	err = authorize(c, handler.policy, auth.Transfer, request.PayerID)
+	if err != nil {
+		return err
+	}
+
+	err = handler.balanceService.Transfer(c.UserContext(), request)
 	if err != nil {
 		return err
 	}
@@ -130,6 +209,10 @@ func (handler *BalanceHandler) Transfer(c *fiber.Ctx) error {
 // @Produce json
 // @Param request body dto.Debet true "debet params"
 // @Success 200 {object} domain.Balance
+// @Security BearerAuth
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
 // @Failure 404 {object} apperror.Error
 // @Router /api/v1/balance/debet [post]
 func (handler *BalanceHandler) Debet(c *fiber.Ctx) error {
@@ -143,8 +226,13 @@ func (handler *BalanceHandler) Debet(c *fiber.Ctx) error {
 		return err
 	}
 
+	err = authorize(c, handler.policy, auth.Debet, request.UserID)
+	if err != nil {
+		return err
+	}
+
 	var balance domain.Balance
-	balance, err = handler.balanceService.Debet(c.Context(), request)
+	balance, err = handler.balanceService.Debet(c.UserContext(), request)
 	if err != nil {
 		return err
 	}
@@ -159,6 +247,10 @@ func (handler *BalanceHandler) Debet(c *fiber.Ctx) error {
 // @Produce json
 // @Param request body dto.Credit true "credit params"
 // @Success 200 {object} domain.Transaction
+// @Security BearerAuth
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
 // @Failure 404 {object} apperror.Error
 // @Router /api/v1/balance/credit [post]
 func (handler *BalanceHandler) Credit(c *fiber.Ctx) error {
@@ -172,8 +264,13 @@ func (handler *BalanceHandler) Credit(c *fiber.Ctx) error {
 		return err
 	}
 
+	err = authorize(c, handler.policy, auth.Credit, request.UserID)
+	if err != nil {
+		return err
+	}
+
 	var balance domain.Balance
-	balance, err = handler.balanceService.Credit(c.Context(), request)
+	balance, err = handler.balanceService.Credit(c.UserContext(), request)
 	if err != nil {
 		return err
 	}
@@ -182,3 +279,130 @@ func (handler *BalanceHandler) Credit(c *fiber.Ctx) error {
 		"response": balance,
 	})
 }
+
+// Stream godoc
+// @Summary Streams balance updates and new transactions of the user as server-sent events
+// @Description Transaction events carry the transaction id as event id. Reconnecting with Last-Event-ID replays the transactions committed since, otherwise the stream starts with the current balance. An unknown Last-Event-ID is answered with 404, the client has to reconnect without it.
+// @Produce text/event-stream
+// @Param	user_id			query 	string 	true	"user id" 						Format(uuid)
+// @Param	Last-Event-ID	header 	string 	false	"last received transaction id" 	Format(uuid)
+// @Security BearerAuth
+// @Success 200 {object} domain.Activity
+// @Failure 400 {object} apperror.Error
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 404 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
+// @Router /api/v1/balance/stream [get]
+func (handler *BalanceHandler) Stream(c *fiber.Ctx) error {
+	var request dto.StreamBalance
+	if err := c.QueryParser(&request); err != nil {
+		return apperror.BadRequest.WithError(err)
+	}
+
+	err := validator.Validate(request)
+	if err != nil {
+		return err
+	}
+
+	err = authorize(c, handler.policy, auth.GetBalance, request.UserID)
+	if err != nil {
+		return err
+	}
+
+	var lastEventID uuid.UUID
+	if header := c.Get("Last-Event-ID"); header != "" {
+		lastEventID, err = uuid.Parse(header)
+		if err != nil {
+			return apperror.BadRequest.WithError(err).WithMessage("invalid Last-Event-ID")
+		}
+	}
+
+	// subscribe before reading the backlog, so nothing committed in between is missed
+	subscription := handler.hub.Subscribe(request.UserID)
+
+	var backlog []domain.Activity
+	if lastEventID != uuid.Nil {
+		var transactions []domain.Transaction
+		transactions, err = handler.balanceService.SelectTransactionAfter(c.UserContext(), request.UserID, lastEventID)
+		if err != nil {
+			subscription.Close()
+			return err
+		}
+
+		for i := range transactions {
+			backlog = append(backlog, domain.Activity{
+				Kind:        domain.ActivityTransaction,
+				AccountID:   request.UserID,
+				Transaction: &transactions[i],
+			})
+		}
+	} else {
+		var balance domain.Balance
+		balance, err = handler.balanceService.Get(c.UserContext(), request.UserID)
+		if err != nil && !errors.Is(err, apperror.NotFound) {
+			subscription.Close()
+			return err
+		}
+
+		if err == nil {
+			backlog = append(backlog, domain.Activity{
+				Kind:      domain.ActivityBalance,
+				AccountID: request.UserID,
+				Balance:   &balance,
+			})
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer subscription.Close()
+
+		sent := make(map[uuid.UUID]struct{}, len(backlog))
+		for _, activity := range backlog {
+			if activity.Transaction != nil {
+				sent[activity.Transaction.ID] = struct{}{}
+			}
+
+			if writeActivity(w, activity) != nil {
+				return
+			}
+		}
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case activity, ok := <-subscription.C:
+				if !ok {
+					return
+				}
+
+				if activity.Transaction != nil {
+					if _, ok = sent[activity.Transaction.ID]; ok {
+						continue
+					}
+				}
+
+				if writeActivity(w, activity) != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if _, err := w.WriteString(": ping\n\n"); err != nil {
+					return
+				}
+
+				if w.Flush() != nil {
+					return
+				}
+			}
+		}
+	})
+
+	return nil
+}
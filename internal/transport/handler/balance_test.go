@@ -0,0 +1,625 @@
+package handler_test
+
+import (
+	"bank/internal/auth"
+	"bank/internal/domain"
+	"bank/internal/dto"
+	"bank/internal/stream"
+	"bank/internal/transport/handler"
+	"bank/internal/transport/middleware"
+	"bank/pkg/apperror"
+	"bank/pkg/logger"
+	"bank/pkg/sort"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var (
+	alice = uuid.MustParse("a39c71f8-6d1b-466c-8367-ebd86764268b")
+	bob   = uuid.MustParse("bcf4b5f7-8f73-4205-82e6-cf20e898a98a")
+
+	createdAt = time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+)
+
+var (
+	asAlice   = &auth.Principal{Subject: alice.String(), Role: auth.User}
+	asService = &auth.Principal{Subject: "payments", Role: auth.Service}
+	asAdmin   = &auth.Principal{Subject: "root", Role: auth.Admin}
+)
+
+// fakeBalanceService keeps balances in a map and records the requests it receives.
+type fakeBalanceService struct {
+	balances     map[uuid.UUID]float64
+	transactions []domain.Transaction
+	err          error
+	requests     []any
+}
+
+func newFakeBalanceService() *fakeBalanceService {
+	return &fakeBalanceService{
+		balances: map[uuid.UUID]float64{alice: 100, bob: 50},
+		transactions: []domain.Transaction{
+			{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), PayeeID: alice, Type: "debet", Amount: 150, Comment: "salary", CreatedAt: createdAt},
+			{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), PayeeID: alice, PayerID: &bob, Type: "transfer", Amount: -50, CreatedAt: createdAt.Add(time.Hour)},
+		},
+	}
+}
+
+func (service *fakeBalanceService) Get(_ context.Context, userID uuid.UUID) (domain.Balance, error) {
+	service.requests = append(service.requests, userID)
+	if service.err != nil {
+		return domain.Balance{}, service.err
+	}
+
+	balance, ok := service.balances[userID]
+	if !ok {
+		return domain.Balance{}, apperror.NotFound.WithMessage("balance not found")
+	}
+
+	return domain.Balance{UserID: userID, Balance: balance}, nil
+}
+
+// GetAt takes back the transactions of the fake created after the timestamp.
+func (service *fakeBalanceService) GetAt(_ context.Context, request dto.GetBalanceAt) (domain.Balance, error) {
+	service.requests = append(service.requests, request)
+	if service.err != nil {
+		return domain.Balance{}, service.err
+	}
+
+	balance, ok := service.balances[request.UserID]
+	if !ok {
+		return domain.Balance{}, apperror.NotFound.WithMessage("balance not found")
+	}
+
+	at, err := time.Parse(time.RFC3339, request.Timestamp)
+	if err != nil {
+		return domain.Balance{}, apperror.BadRequest.WithError(err)
+	}
+
+	for _, transaction := range service.transactions {
+		if transaction.CreatedAt.After(at) {
+			balance -= transaction.Amount
+		}
+	}
+
+	return domain.Balance{UserID: request.UserID, Balance: balance}, nil
+}
+
+func (service *fakeBalanceService) Transfer(ctx context.Context, request dto.Transfer) error {
+	service.requests = append(service.requests, request)
+	if service.err != nil {
+		return service.err
+	}
+
+	if _, err := service.withdraw(request.PayerID, request.Amount); err != nil {
+		return err
+	}
+	service.balances[request.PayeeID] += request.Amount
+
+	return nil
+}
+
+func (service *fakeBalanceService) Debet(_ context.Context, request dto.Debet) (domain.Balance, error) {
+	service.requests = append(service.requests, request)
+	if service.err != nil {
+		return domain.Balance{}, service.err
+	}
+
+	service.balances[request.UserID] += request.Amount
+
+	return domain.Balance{UserID: request.UserID, Balance: service.balances[request.UserID]}, nil
+}
+
+func (service *fakeBalanceService) Credit(_ context.Context, request dto.Credit) (domain.Balance, error) {
+	service.requests = append(service.requests, request)
+	if service.err != nil {
+		return domain.Balance{}, service.err
+	}
+
+	balance, err := service.withdraw(request.UserID, request.Amount)
+	if err != nil {
+		return domain.Balance{}, err
+	}
+
+	return domain.Balance{UserID: request.UserID, Balance: balance}, nil
+}
+
+func (service *fakeBalanceService) SelectTransaction(_ context.Context, request dto.SelectTransaction) ([]domain.Transaction, error) {
+	service.requests = append(service.requests, request)
+	if service.err != nil {
+		return nil, service.err
+	}
+
+	return service.transactions, nil
+}
+
+func (service *fakeBalanceService) SelectTransactionAfter(_ context.Context, userID uuid.UUID, afterID uuid.UUID) ([]domain.Transaction, error) {
+	service.requests = append(service.requests, afterID)
+	if service.err != nil {
+		return nil, service.err
+	}
+
+	for i, transaction := range service.transactions {
+		if transaction.ID == afterID {
+			return service.transactions[i+1:], nil
+		}
+	}
+
+	return nil, apperror.NotFound.WithMessage("last event not found, reconnect without Last-Event-ID")
+}
+
+func (service *fakeBalanceService) withdraw(userID uuid.UUID, amount float64) (float64, error) {
+	balance, ok := service.balances[userID]
+	if !ok {
+		return 0, apperror.NotFound.WithMessage("balance not found")
+	}
+
+	if balance < amount {
+		return 0, apperror.BadRequest.WithMessage("insufficient funds")
+	}
+	service.balances[userID] = balance - amount
+
+	return service.balances[userID], nil
+}
+
+// fakeRates converts from RUB with fixed rates.
+type fakeRates map[string]float64
+
+func (rates fakeRates) Convert(_ context.Context, amount float64, from, to string) (float64, error) {
+	rate, ok := rates[to]
+	if from != "RUB" || !ok {
+		return 0, fmt.Errorf("unsupported currency %s", to)
+	}
+
+	return amount * rate, nil
+}
+
+type testServer struct {
+	app      *fiber.App
+	verifier *auth.Verifier
+	hub      *stream.Hub
+}
+
+func newTestServer(t *testing.T, service *fakeBalanceService) *testServer {
+	t.Helper()
+
+	policy, err := auth.NewPolicy(map[string]string{
+		"user":    "balance.get=own balance.transaction=own balance.transfer=own",
+		"service": "balance.debet balance.credit",
+		"admin":   "*",
+	})
+	require.NoError(t, err)
+
+	server := &testServer{
+		app:      fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler(logger.New(io.Discard, "info"))}),
+		verifier: auth.NewVerifier("secret", time.Hour),
+		hub:      stream.NewHub(logger.New(io.Discard, "info")),
+	}
+	t.Cleanup(server.hub.Close)
+
+	balanceHandler := handler.NewBalanceHandler(service, fakeRates{"USD": 0.0125, "EUR": 0.0115}, policy, server.hub)
+	balanceHandler.Register(server.app.Group("/api/v1/balance", middleware.Authenticate(server.verifier)))
+
+	return server
+}
+
+func (server *testServer) do(t *testing.T, method, target string, body string, principal *auth.Principal, headers ...string) (int, string) {
+	t.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+
+	request := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	if principal != nil {
+		token, err := server.verifier.Sign(*principal)
+		require.NoError(t, err)
+		request.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		request.Header.Set(headers[i], headers[i+1])
+	}
+
+	response, err := server.app.Test(request, -1)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	return response.StatusCode, string(data)
+}
+
+// assertGolden compares body with testdata/name, JSON bodies are compared indented. Run the tests with -update to
+// rewrite the files.
+func assertGolden(t *testing.T, name string, body string) {
+	t.Helper()
+
+	var indented bytes.Buffer
+	if json.Indent(&indented, []byte(body), "", "  ") == nil {
+		body = indented.String() + "\n"
+	}
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(body), 0o644))
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), body)
+}
+
+func TestBalanceHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		body      string
+		principal *auth.Principal
+		status    int
+		golden    string
+		requests  []any
+	}{
+		{
+			name:      "get",
+			method:    fiber.MethodGet,
+			target:    "/api/v1/balance?user_id=" + alice.String(),
+			principal: asAlice,
+			status:    fiber.StatusOK,
+			golden:    "get.json",
+			requests:  []any{alice},
+		},
+		{
+			name:      "get converted",
+			method:    fiber.MethodGet,
+			target:    "/api/v1/balance?currency=USD&user_id=" + alice.String(),
+			principal: asAlice,
+			status:    fiber.StatusOK,
+			golden:    "get_currency.json",
+			requests:  []any{alice},
+		},
+		{
+			name:      "get invalid currency",
+			method:    fiber.MethodGet,
+			target:    "/api/v1/balance?currency=XXX&user_id=" + alice.String(),
+			principal: asAlice,
+			status:    fiber.StatusBadRequest,
+			golden:    "get_invalid_currency.json",
+			requests:  []any{alice},
+		},
+		{
+			name:      "get not found",
+			method:    fiber.MethodGet,
+			target:    "/api/v1/balance?user_id=" + uuid.Nil.String(),
+			principal: asAdmin,
+			status:    fiber.StatusNotFound,
+			golden:    "get_not_found.json",
+			requests:  []any{uuid.Nil},
+		},
+		{
+			name:      "get invalid user id",
+			method:    fiber.MethodGet,
+			target:    "/api/v1/balance?user_id=alice",
+			principal: asAdmin,
+			status:    fiber.StatusBadRequest,
+			golden:    "bad_request.json",
+		},
+		{
+			name:   "get without token",
+			method: fiber.MethodGet,
+			target: "/api/v1/balance?user_id=" + alice.String(),
+			status: fiber.StatusUnauthorized,
+			golden: "unauthorized.json",
+		},
+		{
+			name:      "get foreign account",
+			method:    fiber.MethodGet,
+			target:    "/api/v1/balance?user_id=" + bob.String(),
+			principal: asAlice,
+			status:    fiber.StatusForbidden,
+			golden:    "forbidden_get.json",
+		},
+		{
+			name:      "get at",
+			method:    fiber.MethodGet,
+			target:    "/api/v1/balance/at?timestamp=2023-06-01T12:30:00Z&user_id=" + alice.String(),
+			principal: asAlice,
+			status:    fiber.StatusOK,
+			golden:    "get_at.json",
+			requests:  []any{dto.GetBalanceAt{UserID: alice, Timestamp: "2023-06-01T12:30:00Z"}},
+		},
+		{
+			name:      "get at invalid timestamp",
+			method:    fiber.MethodGet,
+			target:    "/api/v1/balance/at?timestamp=yesterday&user_id=" + alice.String(),
+			principal: asAlice,
+			status:    fiber.StatusBadRequest,
+			golden:    "get_at_invalid_timestamp.json",
+		},
+		{
+			name:      "get at foreign account",
+			method:    fiber.MethodGet,
+			target:    "/api/v1/balance/at?timestamp=2023-06-01T11:00:00Z&user_id=" + bob.String(),
+			principal: asAlice,
+			status:    fiber.StatusForbidden,
+			golden:    "forbidden_get.json",
+		},
+		{
+			name:      "select transaction",
+			method:    fiber.MethodGet,
+			target:    "/api/v1/balance/transaction?sort=amount&order=desc&count=2&offset=1&user_id=" + alice.String(),
+			principal: asAlice,
+			status:    fiber.StatusOK,
+			golden:    "select_transaction.json",
+			requests: []any{dto.SelectTransaction{
+				UserID: alice,
+				SelectTransactionFilter: dto.SelectTransactionFilter{
+					Sort:       "amount",
+					Order:      "desc",
+					Pagination: sort.Pagination{Count: 2, Offset: 1},
+				},
+			}},
+		},
+		{
+			name:      "select transaction foreign account",
+			method:    fiber.MethodGet,
+			target:    "/api/v1/balance/transaction?user_id=" + bob.String(),
+			principal: asAlice,
+			status:    fiber.StatusForbidden,
+			golden:    "forbidden_transaction.json",
+		},
+		{
+			name:      "transfer",
+			method:    fiber.MethodPost,
+			target:    "/api/v1/balance/transfer",
+			body:      fmt.Sprintf(`{"payee_id":%q,"payer_id":%q,"amount":30,"comment":"lunch"}`, bob, alice),
+			principal: asAlice,
+			status:    fiber.StatusOK,
+			golden:    "transfer.json",
+			requests:  []any{dto.Transfer{PayeeID: bob, PayerID: alice, Amount: 30, Comment: "lunch"}},
+		},
+		{
+			name:      "transfer insufficient funds",
+			method:    fiber.MethodPost,
+			target:    "/api/v1/balance/transfer",
+			body:      fmt.Sprintf(`{"payee_id":%q,"payer_id":%q,"amount":300}`, bob, alice),
+			principal: asAlice,
+			status:    fiber.StatusBadRequest,
+			golden:    "insufficient_funds.json",
+			requests:  []any{dto.Transfer{PayeeID: bob, PayerID: alice, Amount: 300}},
+		},
+		{
+			name:      "transfer zero amount",
+			method:    fiber.MethodPost,
+			target:    "/api/v1/balance/transfer",
+			body:      fmt.Sprintf(`{"payee_id":%q,"payer_id":%q,"amount":0}`, bob, alice),
+			principal: asAlice,
+			status:    fiber.StatusBadRequest,
+			golden:    "invalid_amount.json",
+		},
+		{
+			name:      "transfer malformed body",
+			method:    fiber.MethodPost,
+			target:    "/api/v1/balance/transfer",
+			body:      `{"amount":`,
+			principal: asAlice,
+			status:    fiber.StatusBadRequest,
+			golden:    "bad_request.json",
+		},
+		{
+			name:      "transfer from foreign account",
+			method:    fiber.MethodPost,
+			target:    "/api/v1/balance/transfer",
+			body:      fmt.Sprintf(`{"payee_id":%q,"payer_id":%q,"amount":30}`, alice, bob),
+			principal: asAlice,
+			status:    fiber.StatusForbidden,
+			golden:    "forbidden_transfer.json",
+		},
+		{
+			name:      "debet",
+			method:    fiber.MethodPost,
+			target:    "/api/v1/balance/debet",
+			body:      fmt.Sprintf(`{"user_id":%q,"amount":25.5,"comment":"salary"}`, alice),
+			principal: asService,
+			status:    fiber.StatusOK,
+			golden:    "debet.json",
+			requests:  []any{dto.Debet{UserID: alice, Amount: 25.5, Comment: "salary"}},
+		},
+		{
+			name:      "debet by user",
+			method:    fiber.MethodPost,
+			target:    "/api/v1/balance/debet",
+			body:      fmt.Sprintf(`{"user_id":%q,"amount":25.5}`, alice),
+			principal: asAlice,
+			status:    fiber.StatusForbidden,
+			golden:    "forbidden_debet.json",
+		},
+		{
+			name:      "debet negative amount",
+			method:    fiber.MethodPost,
+			target:    "/api/v1/balance/debet",
+			body:      fmt.Sprintf(`{"user_id":%q,"amount":-1}`, alice),
+			principal: asService,
+			status:    fiber.StatusBadRequest,
+			golden:    "invalid_amount.json",
+		},
+		{
+			name:      "debet sub-kopeck amount",
+			method:    fiber.MethodPost,
+			target:    "/api/v1/balance/debet",
+			body:      fmt.Sprintf(`{"user_id":%q,"amount":10.004}`, alice),
+			principal: asService,
+			status:    fiber.StatusBadRequest,
+			golden:    "invalid_amount.json",
+		},
+		{
+			name:      "credit",
+			method:    fiber.MethodPost,
+			target:    "/api/v1/balance/credit",
+			body:      fmt.Sprintf(`{"user_id":%q,"amount":40,"comment":"atm"}`, alice),
+			principal: asService,
+			status:    fiber.StatusOK,
+			golden:    "credit.json",
+			requests:  []any{dto.Credit{UserID: alice, Amount: 40, Comment: "atm"}},
+		},
+		{
+			name:      "credit insufficient funds",
+			method:    fiber.MethodPost,
+			target:    "/api/v1/balance/credit",
+			body:      fmt.Sprintf(`{"user_id":%q,"amount":400}`, alice),
+			principal: asService,
+			status:    fiber.StatusBadRequest,
+			golden:    "insufficient_funds.json",
+			requests:  []any{dto.Credit{UserID: alice, Amount: 400}},
+		},
+		{
+			name:      "credit not found",
+			method:    fiber.MethodPost,
+			target:    "/api/v1/balance/credit",
+			body:      fmt.Sprintf(`{"user_id":%q,"amount":1}`, uuid.Nil),
+			principal: asService,
+			status:    fiber.StatusNotFound,
+			golden:    "not_found.json",
+			requests:  []any{dto.Credit{UserID: uuid.Nil, Amount: 1}},
+		},
+		{
+			name:      "unknown route",
+			method:    fiber.MethodGet,
+			target:    "/api/v1/balance/unknown",
+			principal: asAdmin,
+			status:    fiber.StatusNotFound,
+			golden:    "route_not_found.json",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := newFakeBalanceService()
+			server := newTestServer(t, service)
+
+			status, body := server.do(t, test.method, test.target, test.body, test.principal)
+
+			assert.Equal(t, test.status, status)
+			assertGolden(t, filepath.Join("balance", test.golden), body)
+			assert.Equal(t, test.requests, service.requests)
+		})
+	}
+}
+
+func TestBalanceHandler_Stream(t *testing.T) {
+	tests := []struct {
+		name        string
+		userID      uuid.UUID
+		lastEventID string
+		status      int
+		golden      string
+	}{
+		{
+			name:   "current balance",
+			userID: alice,
+			status: fiber.StatusOK,
+			golden: "stream.txt",
+		},
+		{
+			name:        "resume after last event",
+			userID:      alice,
+			lastEventID: "00000000-0000-0000-0000-000000000001",
+			status:      fiber.StatusOK,
+			golden:      "stream_resume.txt",
+		},
+		{
+			name:   "no balance yet",
+			userID: uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+			status: fiber.StatusOK,
+			golden: "stream_empty.txt",
+		},
+		{
+			name:        "unknown last event id",
+			userID:      alice,
+			lastEventID: "00000000-0000-0000-0000-0000000000ff",
+			status:      fiber.StatusNotFound,
+			golden:      "stream_unknown_last_event_id.json",
+		},
+		{
+			name:        "invalid last event id",
+			userID:      alice,
+			lastEventID: "last",
+			status:      fiber.StatusBadRequest,
+			golden:      "stream_invalid_last_event_id.json",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newTestServer(t, newFakeBalanceService())
+			// a closed hub ends the stream once the backlog is written
+			server.hub.Close()
+
+			var headers []string
+			if test.lastEventID != "" {
+				headers = append(headers, "Last-Event-ID", test.lastEventID)
+			}
+
+			status, body := server.do(t, fiber.MethodGet, "/api/v1/balance/stream?user_id="+test.userID.String(), "", asAdmin, headers...)
+
+			assert.Equal(t, test.status, status)
+			assertGolden(t, filepath.Join("balance", test.golden), body)
+		})
+	}
+}
+
+func TestBalanceHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		golden string
+	}{
+		{name: "unknown", err: apperror.Unknown, status: fiber.StatusTeapot, golden: "unknown.json"},
+		{name: "internal", err: apperror.Internal.WithError(errors.New("connection refused")).WithScope("balanceService.Get"), status: fiber.StatusInternalServerError, golden: "internal.json"},
+		{name: "not found", err: apperror.NotFound, status: fiber.StatusNotFound, golden: "not_found_bare.json"},
+		{name: "already exists", err: apperror.AlreadyExists, status: fiber.StatusBadRequest, golden: "already_exists.json"},
+		{name: "bad request", err: apperror.BadRequest.WithMessage("invalid amount"), status: fiber.StatusBadRequest, golden: "bad_request_message.json"},
+		{name: "unauthorized", err: apperror.Unauthorized, status: fiber.StatusUnauthorized, golden: "unauthorized_bare.json"},
+		{name: "forbidden", err: apperror.Forbidden.WithMessage("account is frozen"), status: fiber.StatusForbidden, golden: "frozen.json"},
+		{name: "too many requests", err: apperror.TooManyRequests, status: fiber.StatusTooManyRequests, golden: "too_many_requests.json"},
+		{name: "unmapped", err: apperror.New("custom"), status: fiber.StatusTeapot, golden: "unknown.json"},
+		{name: "plain", err: errors.New("boom"), status: fiber.StatusTeapot, golden: "unknown.json"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service := newFakeBalanceService()
+			service.err = test.err
+			server := newTestServer(t, service)
+
+			status, body := server.do(t, fiber.MethodGet, "/api/v1/balance?user_id="+alice.String(), "", asAlice)
+
+			assert.Equal(t, test.status, status)
+			assertGolden(t, filepath.Join("errors", test.golden), body)
+		})
+	}
+}
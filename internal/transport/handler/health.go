@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"bank/internal/health"
+	"github.com/gofiber/fiber/v2"
+)
+
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+func (handler *HealthHandler) Register(router fiber.Router) {
+	router.Get("/healthz", handler.Live)
+	router.Get("/readyz", handler.Ready)
+}
+
+// Live godoc
+// @Summary Reports that the process is alive
+// @Produce json
+// @Success 200 {object} health.Report
+// @Router /healthz [get]
+func (handler *HealthHandler) Live(c *fiber.Ctx) error {
+	return c.JSON(health.Report{Status: health.StatusOK, Checks: map[string]health.CheckResult{}})
+}
+
+// Ready godoc
+// @Summary Reports whether every dependency is usable and the service is not shutting down
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (handler *HealthHandler) Ready(c *fiber.Ctx) error {
+	report, ok := handler.checker.Ready(c.UserContext())
+	if !ok {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+
+	return c.JSON(report)
+}
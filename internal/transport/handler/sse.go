@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"bank/internal/domain"
+	"bufio"
+	"encoding/json"
+	"fmt"
+)
+
+// writeActivity writes activity as a server-sent event. Transaction events carry the transaction id as event id,
+// which the client sends back in Last-Event-ID on reconnect.
+func writeActivity(w *bufio.Writer, activity domain.Activity) error {
+	var data []byte
+	var err error
+
+	switch activity.Kind {
+	case domain.ActivityBalance:
+		data, err = json.Marshal(activity.Balance)
+	case domain.ActivityTransaction:
+		data, err = json.Marshal(activity.Transaction)
+		if err == nil {
+			_, err = fmt.Fprintf(w, "id: %s\n", activity.Transaction.ID)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", activity.Kind, data)
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"bank/internal/auth"
+	"bank/internal/domain"
+	"bank/internal/dto"
+	"bank/internal/service"
+	"bank/pkg/apperror"
+	"bank/pkg/validator"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type WebhookHandler struct {
+	webhookService service.WebhookService
+	policy         *auth.Policy
+}
+
+func NewWebhookHandler(webhookService service.WebhookService, policy *auth.Policy) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService, policy: policy}
+}
+
+func (handler *WebhookHandler) Register(router fiber.Router) {
+	router.Post("/subscription", handler.CreateSubscription)
+	router.Get("/subscription", handler.SelectSubscription)
+	router.Post("/subscription/:id/disable", handler.DisableSubscription)
+	router.Post("/subscription/:id/replay", handler.Replay)
+	router.Get("/subscription/:id/delivery", handler.SelectDelivery)
+}
+
+// CreateSubscription godoc
+// @Summary Subscribes an URL to balance events of an account, or of all accounts when account id is omitted
+// @Description The url must be https to a host with public addresses only. The response holds the signing secret, it is not shown again.
+// @Produce json
+// @Param request body dto.CreateWebhookSubscription true "subscription params"
+// @Security BearerAuth
+// @Success 200 {object} domain.WebhookSubscription
+// @Failure 400 {object} apperror.Error
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
+// @Router /api/v1/webhook/subscription [post]
+func (handler *WebhookHandler) CreateSubscription(c *fiber.Ctx) error {
+	var request dto.CreateWebhookSubscription
+	if err := c.BodyParser(&request); err != nil {
+		return apperror.BadRequest.WithError(err)
+	}
+
+	err := validator.Validate(request)
+	if err != nil {
+		return err
+	}
+
+	accountID := uuid.Nil
+	if request.AccountID != nil {
+		accountID = *request.AccountID
+	}
+
+	err = authorize(c, handler.policy, auth.CreateWebhook, accountID)
+	if err != nil {
+		return err
+	}
+
+	var subscription domain.WebhookSubscription
+	subscription, err = handler.webhookService.CreateSubscription(c.UserContext(), request)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"response": subscription,
+	})
+}
+
+// SelectSubscription godoc
+// @Summary Retrieves webhook subscriptions, optionally of a single account
+// @Produce json
+// @Param	account_id	query 	string 	false	"account id"	Format(uuid)
+// @Param	count	    query 	int 	false	"count"			Format(int)
+// @Param	offset	    query 	int 	false	"offset"		Format(int)
+// @Security BearerAuth
+// @Success 200 {array} domain.WebhookSubscription
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
+// @Router /api/v1/webhook/subscription [get]
+func (handler *WebhookHandler) SelectSubscription(c *fiber.Ctx) error {
+	var request dto.SelectWebhookSubscription
+	if err := c.QueryParser(&request); err != nil {
+		return apperror.BadRequest.WithError(err)
+	}
+
+	err := validator.Validate(request)
+	if err != nil {
+		return err
+	}
+
+	err = authorize(c, handler.policy, auth.SelectWebhook, request.AccountID)
+	if err != nil {
+		return err
+	}
+
+	var subscriptions []domain.WebhookSubscription
+	subscriptions, err = handler.webhookService.SelectSubscription(c.UserContext(), request)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"response": subscriptions,
+	})
+}
+
+// DisableSubscription godoc
+// @Summary Disables a webhook subscription, its pending deliveries are no longer attempted
+// @Produce json
+// @Param	id	path 	string 	true	"subscription id"	Format(uuid)
+// @Security BearerAuth
+// @Success 200 {int} 1
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
+// @Failure 404 {object} apperror.Error
+// @Router /api/v1/webhook/subscription/{id}/disable [post]
+func (handler *WebhookHandler) DisableSubscription(c *fiber.Ctx) error {
+	subscription, err := handler.subscription(c, auth.DisableWebhook)
+	if err != nil {
+		return err
+	}
+
+	err = handler.webhookService.DisableSubscription(c.UserContext(), subscription.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"response": 1,
+	})
+}
+
+// Replay godoc
+// @Summary Requeues dead deliveries of a webhook subscription
+// @Produce json
+// @Param	id	path 	string 	true	"subscription id"	Format(uuid)
+// @Security BearerAuth
+// @Success 200 {int} 1
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
+// @Failure 404 {object} apperror.Error
+// @Router /api/v1/webhook/subscription/{id}/replay [post]
+func (handler *WebhookHandler) Replay(c *fiber.Ctx) error {
+	subscription, err := handler.subscription(c, auth.ReplayWebhook)
+	if err != nil {
+		return err
+	}
+
+	var replayed int64
+	replayed, err = handler.webhookService.Replay(c.UserContext(), subscription.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"response": replayed,
+	})
+}
+
+// SelectDelivery godoc
+// @Summary Retrieves deliveries of a webhook subscription
+// @Produce json
+// @Param	id		path 	string 	true	"subscription id"	Format(uuid)
+// @Param	status	query 	string 	false	"status"			Enums(pending, delivered, dead)
+// @Param	count	query 	int 	false	"count"				Format(int)
+// @Param	offset	query 	int 	false	"offset"			Format(int)
+// @Security BearerAuth
+// @Success 200 {array} domain.WebhookDelivery
+// @Failure 400 {object} apperror.Error
+// @Failure 401 {object} apperror.Error
+// @Failure 403 {object} apperror.Error
+// @Failure 429 {object} apperror.Error
+// @Failure 404 {object} apperror.Error
+// @Router /api/v1/webhook/subscription/{id}/delivery [get]
+func (handler *WebhookHandler) SelectDelivery(c *fiber.Ctx) error {
+	subscription, err := handler.subscription(c, auth.SelectWebhook)
+	if err != nil {
+		return err
+	}
+
+	var request dto.SelectWebhookDelivery
+	if err = c.QueryParser(&request); err != nil {
+		return apperror.BadRequest.WithError(err)
+	}
+	request.SubscriptionID = subscription.ID
+
+	err = validator.Validate(request)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []domain.WebhookDelivery
+	deliveries, err = handler.webhookService.SelectDelivery(c.UserContext(), request)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"response": deliveries,
+	})
+}
+
+// subscription loads the subscription named by the id path parameter and checks that the caller may act on it.
+func (handler *WebhookHandler) subscription(c *fiber.Ctx, action auth.Action) (domain.WebhookSubscription, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return domain.WebhookSubscription{}, apperror.BadRequest.WithError(err).WithMessage("invalid subscription id")
+	}
+
+	subscription, err := handler.webhookService.GetSubscription(c.UserContext(), id)
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+
+	accountID := uuid.Nil
+	if subscription.AccountID != nil {
+		accountID = *subscription.AccountID
+	}
+
+	err = authorize(c, handler.policy, action, accountID)
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+
+	return subscription, nil
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"bank/internal/audit"
+	"bank/internal/auth"
+	"bank/pkg/logger"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+)
+
+func Audit() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		metadata := audit.Metadata{
+			SourceIP:  c.IP(),
+			RequestID: logger.RequestID(c.UserContext()),
+		}
+
+		if principal, ok := auth.FromContext(c.UserContext()); ok {
+			metadata.Actor = principal.Subject
+		}
+
+		if body := c.Body(); len(body) != 0 {
+			sum := sha256.Sum256(body)
+			metadata.PayloadHash = hex.EncodeToString(sum[:])
+		}
+
+		c.SetUserContext(audit.NewContext(c.UserContext(), metadata))
+
+		return c.Next()
+	}
+}
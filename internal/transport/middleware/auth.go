@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"bank/internal/auth"
+	"bank/pkg/apperror"
+	"github.com/gofiber/fiber/v2"
+	"strings"
+)
+
+func Authenticate(verifier *auth.Verifier) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || token == "" {
+			return apperror.Unauthorized.WithMessage("missing bearer token")
+		}
+
+		principal, err := verifier.Verify(token)
+		if err != nil {
+			return err
+		}
+
+		c.SetUserContext(auth.NewContext(c.UserContext(), principal))
+
+		return c.Next()
+	}
+}
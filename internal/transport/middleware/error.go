@@ -1,37 +1,85 @@
 package middleware
 
 import (
+	"bank/internal/ratelimit"
 	"bank/pkg/apperror"
 	"errors"
 	"github.com/gofiber/fiber/v2"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
-func ErrorHandler() fiber.ErrorHandler {
+// ErrorHandler renders errors as {"error": apperror.Error}. Internal and unknown errors are logged
+// with their scope and cause, while the client only receives the bare status. Errors of fiber itself, e.g. an
+// unknown route or a body over the limit, keep their status.
+func ErrorHandler(log *slog.Logger) fiber.ErrorHandler {
 	return func(c *fiber.Ctx, err error) error {
 		var e *fiber.Error
 		if errors.As(err, &e) {
-			if e.Code == http.StatusNotFound {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": apperror.NotFound.WithError(err)})
+			if e.Code >= http.StatusInternalServerError {
+				log.ErrorContext(c.UserContext(), "internal error", slog.Any("cause", err))
 			}
+
+			return c.Status(e.Code).JSON(fiber.Map{"error": fiberError(e)})
 		}
 
 		var apperr apperror.Error
-		if errors.As(err, &apperr) {
+		if errors.As(err, &apperr) && apperr.Code != apperror.Unknown.Code {
 			switch apperr.Code {
 			case apperror.Internal.Code:
-				c.Status(fiber.StatusInternalServerError)
+				log.ErrorContext(c.UserContext(), "internal error",
+					slog.String("scope", apperr.Scope),
+					slog.String("message", apperr.Message),
+					slog.Any("cause", apperr.Err),
+				)
+
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": apperror.Internal})
 			case apperror.NotFound.Code:
 				c.Status(fiber.StatusNotFound)
 			case apperror.AlreadyExists.Code, apperror.BadRequest.Code:
 				c.Status(fiber.StatusBadRequest)
 			case apperror.Unauthorized.Code:
 				c.Status(fiber.StatusUnauthorized)
+			case apperror.Forbidden.Code:
+				c.Status(fiber.StatusForbidden)
+			case apperror.TooManyRequests.Code:
+				c.Status(fiber.StatusTooManyRequests)
+
+				var exceeded ratelimit.Exceeded
+				if errors.As(err, &exceeded) {
+					c.Set(fiber.HeaderRetryAfter, strconv.Itoa(exceeded.Seconds()))
+				}
+			default:
+				log.ErrorContext(c.UserContext(), "unmapped error", slog.String("status", apperr.Status), slog.Any("cause", err))
+
+				return c.Status(fiber.StatusTeapot).JSON(fiber.Map{"error": apperror.Unknown})
 			}
 
 			return c.JSON(fiber.Map{"error": apperr})
 		}
 
-		return c.Status(fiber.StatusTeapot).JSON(fiber.Map{"error": apperror.Unknown.WithError(err)})
+		log.ErrorContext(c.UserContext(), "unknown error", slog.Any("cause", err))
+
+		return c.Status(fiber.StatusTeapot).JSON(fiber.Map{"error": apperror.Unknown})
 	}
 }
+
+// fiberError maps a fiber error to the apperror of its status, client errors keep their message.
+func fiberError(e *fiber.Error) apperror.Error {
+	switch {
+	case e.Code == http.StatusNotFound:
+		return apperror.NotFound.WithError(e)
+	case e.Code == http.StatusUnauthorized:
+		return apperror.Unauthorized
+	case e.Code == http.StatusForbidden:
+		return apperror.Forbidden
+	case e.Code == http.StatusTooManyRequests:
+		return apperror.TooManyRequests
+	case e.Code < http.StatusInternalServerError:
+		return apperror.BadRequest.WithMessage(strings.ToLower(e.Message))
+	}
+
+	return apperror.Internal
+}
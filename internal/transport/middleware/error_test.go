@@ -0,0 +1,143 @@
+package middleware_test
+
+import (
+	"bank/internal/ratelimit"
+	"bank/internal/transport/middleware"
+	"bank/pkg/apperror"
+	"bank/pkg/logger"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorHandler_Internal(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(&buf, "info")
+
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler(log)})
+	app.Use(middleware.RequestID())
+	app.Get("/", func(c *fiber.Ctx) error {
+		return apperror.Internal.WithError(errors.New("connection refused")).WithScope("balanceService.Get")
+	})
+
+	request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+	request.Header.Set(middleware.HeaderRequestID, "42")
+
+	response, err := app.Test(request)
+	require.NoError(t, err)
+
+	assert.Equal(t, fiber.StatusInternalServerError, response.StatusCode)
+	assert.Equal(t, "42", response.Header.Get(middleware.HeaderRequestID))
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "connection refused")
+	assert.NotContains(t, string(body), "balanceService.Get")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "internal error", record["msg"])
+	assert.Equal(t, "balanceService.Get", record["scope"])
+	assert.Equal(t, "connection refused", record["cause"])
+	assert.Equal(t, "42", record["request_id"])
+}
+
+func TestErrorHandler_TooManyRequests(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler(logger.New(io.Discard, "info"))})
+	app.Get("/", func(c *fiber.Ctx) error {
+		return ratelimit.Exceeded{RetryAfter: 1500 * time.Millisecond}
+	})
+
+	response, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, fiber.StatusTooManyRequests, response.StatusCode)
+	assert.Equal(t, "2", response.Header.Get(fiber.HeaderRetryAfter))
+
+	var body struct {
+		Error apperror.Error `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+	assert.Equal(t, apperror.TooManyRequests.Code, body.Error.Code)
+}
+
+// TestErrorHandler_Fiber serves over a listener: app.Test fails on a body over the limit rather than returning the
+// response the error handler wrote.
+func TestErrorHandler_Fiber(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		BodyLimit:             16,
+		DisableStartupMessage: true,
+		ErrorHandler:          middleware.ErrorHandler(logger.New(io.Discard, "info")),
+	})
+	app.Post("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(listener) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	url := "http://" + listener.Addr().String()
+
+	tests := []struct {
+		name        string
+		path        string
+		body        string
+		wantStatus  int
+		wantCode    apperror.Code
+		wantMessage string
+	}{
+		{
+			name:       "body within the limit",
+			path:       "/",
+			body:       `{"amount":100}`,
+			wantStatus: fiber.StatusNoContent,
+		},
+		{
+			name:        "body over the limit",
+			path:        "/",
+			body:        `{"amount":100,"comment":"over the limit"}`,
+			wantStatus:  fiber.StatusRequestEntityTooLarge,
+			wantCode:    apperror.BadRequest.Code,
+			wantMessage: "request entity too large",
+		},
+		{
+			name:       "unknown route",
+			path:       "/unknown",
+			wantStatus: fiber.StatusNotFound,
+			wantCode:   apperror.NotFound.Code,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := http.Post(url+tt.path, fiber.MIMEApplicationJSON, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			defer response.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, response.StatusCode)
+
+			if tt.wantCode == 0 {
+				return
+			}
+
+			var body struct {
+				Error apperror.Error `json:"error"`
+			}
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+			assert.Equal(t, tt.wantCode, body.Error.Code)
+			assert.Equal(t, tt.wantMessage, body.Error.Message)
+		})
+	}
+}
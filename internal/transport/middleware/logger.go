@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"log/slog"
+	"time"
+)
+
+// Logger writes one access record per request. Errors are rendered by the app's ErrorHandler first,
+// so the logged status is the one the client receives.
+func Logger(log *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		if err := c.Next(); err != nil {
+			if err = c.App().ErrorHandler(c, err); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		log.InfoContext(c.UserContext(), "request",
+			slog.String("method", c.Method()),
+			slog.String("path", c.Path()),
+			slog.Int("status", c.Response().StatusCode()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("ip", c.IP()),
+		)
+
+		return nil
+	}
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"bank/internal/metrics"
+	"github.com/gofiber/fiber/v2"
+	"time"
+)
+
+// Metrics records request count and latency per route pattern, so /balance?user_id=... stays one series.
+// It expects errors to be rendered already, i.e. to be mounted outside Logger.
+func Metrics() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		err := c.Next()
+
+		metrics.ObserveRequest(c.Method(), c.Route().Path, c.Response().StatusCode(), time.Since(start))
+
+		return err
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"bank/internal/auth"
+	"bank/internal/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimit takes a token per request from the bucket of the route and the authenticated principal,
+// or of the client IP when there is none. It must run after Authenticate.
+func RateLimit(limiter *ratelimit.Limiter) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		caller := "ip:" + c.IP()
+		if principal, ok := auth.FromContext(c.UserContext()); ok {
+			caller = "principal:" + principal.Subject
+		}
+
+		err := limiter.AllowRequest(c.UserContext(), c.Method(), c.Path(), caller)
+		if err != nil {
+			return err
+		}
+
+		return c.Next()
+	}
+}
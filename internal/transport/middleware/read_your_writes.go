@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"bank/internal/storage"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+)
+
+const HeaderReadYourWrites = "X-Read-Your-Writes"
+
+// ReadYourWrites serves the request from the primary database when X-Read-Your-Writes is true, so it sees writes
+// the read replica has not caught up with yet.
+func ReadYourWrites() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if ok, _ := strconv.ParseBool(c.Get(HeaderReadYourWrites)); ok {
+			c.SetUserContext(storage.WithReadYourWrites(c.UserContext()))
+		}
+
+		return c.Next()
+	}
+}
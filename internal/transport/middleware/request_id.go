@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"bank/pkg/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const HeaderRequestID = "X-Request-ID"
+
+// RequestID reuses the caller's X-Request-ID or assigns a new one, echoes it back and puts it into the user context.
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(HeaderRequestID)
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		c.Set(HeaderRequestID, requestID)
+
+		c.SetUserContext(logger.WithRequestID(c.UserContext(), requestID))
+
+		return c.Next()
+	}
+}
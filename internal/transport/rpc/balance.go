@@ -0,0 +1,262 @@
+package rpc
+
+import (
+	bankv1 "bank/api/bank/v1"
+	"bank/internal/auth"
+	"bank/internal/domain"
+	"bank/internal/dto"
+	"bank/internal/service"
+	"bank/pkg/apperror"
+	"bank/pkg/sort"
+	"bank/pkg/validator"
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+// BalanceServer exposes BalanceService over gRPC with the same validation and policy as handler.BalanceHandler.
+type BalanceServer struct {
+	bankv1.UnimplementedBalanceServiceServer
+
+	balanceService service.BalanceService
+	rates          service.RateProvider
+	policy         *auth.Policy
+}
+
+func NewBalanceServer(balanceService service.BalanceService, rates service.RateProvider, policy *auth.Policy) *BalanceServer {
+	return &BalanceServer{balanceService: balanceService, rates: rates, policy: policy}
+}
+
+func (server *BalanceServer) Get(ctx context.Context, in *bankv1.GetBalanceRequest) (*bankv1.Balance, error) {
+	userID, err := parseUUID("user_id", in.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	request := dto.GetBalance{UserID: userID, Currency: in.GetCurrency()}
+
+	err = validator.Validate(request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorize(ctx, server.policy, auth.GetBalance, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance domain.Balance
+	balance, err = server.balanceService.Get(ctx, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Currency != "" {
+		balance.Balance, err = server.rates.Convert(ctx, balance.Balance, "RUB", request.Currency)
+		if err != nil {
+			return nil, apperror.BadRequest.WithError(err).WithMessage("invalid currency")
+		}
+	}
+
+	return balanceToProto(balance), nil
+}
+
+func (server *BalanceServer) GetAt(ctx context.Context, in *bankv1.GetBalanceAtRequest) (*bankv1.Balance, error) {
+	userID, err := parseUUID("user_id", in.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	request := dto.GetBalanceAt{UserID: userID}
+	if in.GetTimestamp() != nil {
+		request.Timestamp = in.GetTimestamp().AsTime().Format(time.RFC3339Nano)
+	}
+
+	err = validator.Validate(request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorize(ctx, server.policy, auth.GetBalance, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance domain.Balance
+	balance, err = server.balanceService.GetAt(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return balanceToProto(balance), nil
+}
+
+func (server *BalanceServer) SelectTransaction(ctx context.Context, in *bankv1.SelectTransactionRequest) (*bankv1.SelectTransactionResponse, error) {
+	userID, err := parseUUID("user_id", in.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	request := dto.SelectTransaction{
+		UserID: userID,
+		SelectTransactionFilter: dto.SelectTransactionFilter{
+			Sort:       in.GetSort(),
+			Order:      in.GetOrder(),
+			Pagination: sort.Pagination{Count: in.GetCount(), Offset: in.GetOffset()},
+		},
+	}
+
+	err = validator.Validate(request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorize(ctx, server.policy, auth.SelectTransaction, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []domain.Transaction
+	transactions, err = server.balanceService.SelectTransaction(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &bankv1.SelectTransactionResponse{
+		Transactions: make([]*bankv1.Transaction, len(transactions)),
+	}
+	for i, transaction := range transactions {
+		response.Transactions[i] = transactionToProto(transaction)
+	}
+
+	return response, nil
+}
+
+func (server *BalanceServer) Transfer(ctx context.Context, in *bankv1.TransferRequest) (*bankv1.TransferResponse, error) {
+	payeeID, err := parseUUID("payee_id", in.GetPayeeId())
+	if err != nil {
+		return nil, err
+	}
+
+	payerID, err := parseUUID("payer_id", in.GetPayerId())
+	if err != nil {
+		return nil, err
+	}
+
+	request := dto.Transfer{PayeeID: payeeID, PayerID: payerID, Amount: in.GetAmount(), Comment: in.GetComment()}
+
+	err = validator.Validate(request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorize(ctx, server.policy, auth.Transfer, request.PayerID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = server.balanceService.Transfer(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bankv1.TransferResponse{}, nil
+}
+
+func (server *BalanceServer) Debet(ctx context.Context, in *bankv1.DebetRequest) (*bankv1.Balance, error) {
+	userID, err := parseUUID("user_id", in.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	request := dto.Debet{UserID: userID, Amount: in.GetAmount(), Comment: in.GetComment()}
+
+	err = validator.Validate(request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorize(ctx, server.policy, auth.Debet, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance domain.Balance
+	balance, err = server.balanceService.Debet(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return balanceToProto(balance), nil
+}
+
+func (server *BalanceServer) Credit(ctx context.Context, in *bankv1.CreditRequest) (*bankv1.Balance, error) {
+	userID, err := parseUUID("user_id", in.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	request := dto.Credit{UserID: userID, Amount: in.GetAmount(), Comment: in.GetComment()}
+
+	err = validator.Validate(request)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorize(ctx, server.policy, auth.Credit, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var balance domain.Balance
+	balance, err = server.balanceService.Credit(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return balanceToProto(balance), nil
+}
+
+func authorize(ctx context.Context, policy *auth.Policy, action auth.Action, accountID uuid.UUID) error {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return apperror.Unauthorized.WithMessage("caller is not identified")
+	}
+
+	return policy.Authorize(principal, action, accountID)
+}
+
+func parseUUID(field string, value string) (uuid.UUID, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, apperror.BadRequest.WithError(err).WithMessage(field + " must be a valid uuid")
+	}
+
+	return id, nil
+}
+
+func balanceToProto(balance domain.Balance) *bankv1.Balance {
+	return &bankv1.Balance{
+		UserId:  balance.UserID.String(),
+		Balance: balance.Balance,
+	}
+}
+
+func transactionToProto(transaction domain.Transaction) *bankv1.Transaction {
+	res := &bankv1.Transaction{
+		Id:        transaction.ID.String(),
+		PayeeId:   transaction.PayeeID.String(),
+		Type:      transaction.Type,
+		Amount:    transaction.Amount,
+		Comment:   transaction.Comment,
+		CreatedAt: timestamppb.New(transaction.CreatedAt),
+	}
+
+	if transaction.PayerID != nil {
+		payerID := transaction.PayerID.String()
+		res.PayerId = &payerID
+	}
+
+	return res
+}
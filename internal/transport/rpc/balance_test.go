@@ -0,0 +1,288 @@
+package rpc_test
+
+import (
+	bankv1 "bank/api/bank/v1"
+	"bank/internal/auth"
+	"bank/internal/model"
+	"bank/internal/ratelimit"
+	"bank/internal/service"
+	"bank/internal/storage"
+	"bank/internal/transport/rpc"
+	"bank/pkg/apperror"
+	"bank/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+)
+
+func newLimiter(t *testing.T) *ratelimit.Limiter {
+	limiter, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), nil, "", "", logger.New(io.Discard, "info"))
+	require.NoError(t, err)
+
+	return limiter
+}
+
+type fakeRates map[string]float64
+
+func (rates fakeRates) Convert(_ context.Context, amount float64, from, to string) (float64, error) {
+	rate, ok := rates[to]
+	if from != "RUB" || !ok {
+		return 0, fmt.Errorf("unsupported currency %s", to)
+	}
+
+	return amount * rate, nil
+}
+
+func newBalanceService(balanceStorage storage.BalanceStorage) service.BalanceService {
+	return service.NewBalanceService(&storage.TransactorMock{}, balanceStorage, &storage.TransactionStorageMock{}, &storage.AuditStorageMock{}, &storage.OutboxStorageMock{}, &storage.SnapshotStorageMock{})
+}
+
+func newClient(t *testing.T, balanceService service.BalanceService, verifier *auth.Verifier) bankv1.BalanceServiceClient {
+	policy, err := auth.NewPolicy(map[string]string{"user": "balance.get=own", "admin": "*"})
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1 << 20)
+	server := rpc.New(verifier, newLimiter(t), slog.New(slog.NewJSONHandler(io.Discard, nil))).Handle(rpc.NewBalanceServer(balanceService, fakeRates{"USD": 0.0125}, policy))
+	go server.Serve(listener)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return bankv1.NewBalanceServiceClient(conn)
+}
+
+func TestBalanceServer_Get(t *testing.T) {
+	verifier := auth.NewVerifier("secret", time.Hour)
+
+	ownID := uuid.New()
+	missingID := uuid.New()
+
+	balanceStorage := &storage.BalanceStorageMock{
+		GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+			if userID == missingID {
+				return model.Balance{}, apperror.NotFound
+			}
+
+			return model.Balance{UserID: userID, Balance: 10000}, nil
+		},
+	}
+
+	client := newClient(t, newBalanceService(balanceStorage), verifier)
+
+	userToken, err := verifier.Sign(auth.Principal{Subject: ownID.String(), Role: auth.User})
+	require.NoError(t, err)
+	adminToken, err := verifier.Sign(auth.Principal{Subject: "root", Role: auth.Admin})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		token    string
+		userID   string
+		currency string
+		response *bankv1.Balance
+		wantCode codes.Code
+	}{
+		{name: "ok", token: userToken, userID: ownID.String(), response: &bankv1.Balance{UserId: ownID.String(), Balance: 100}, wantCode: codes.OK},
+		{name: "currency", token: userToken, userID: ownID.String(), currency: "USD", response: &bankv1.Balance{UserId: ownID.String(), Balance: 1.25}, wantCode: codes.OK},
+		{name: "unsupported currency", token: userToken, userID: ownID.String(), currency: "XYZ", wantCode: codes.InvalidArgument},
+		{name: "missing token", token: "", userID: ownID.String(), wantCode: codes.Unauthenticated},
+		{name: "other account", token: userToken, userID: uuid.NewString(), wantCode: codes.PermissionDenied},
+		{name: "invalid user id", token: userToken, userID: "42", wantCode: codes.InvalidArgument},
+		{name: "balance not found", token: adminToken, userID: missingID.String(), wantCode: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+tt.token)
+			}
+
+			got, err := client.Get(ctx, &bankv1.GetBalanceRequest{UserId: tt.userID, Currency: tt.currency})
+			assert.Equal(t, tt.wantCode, status.Code(err))
+
+			if tt.response != nil {
+				assert.Equal(t, tt.response.GetUserId(), got.GetUserId())
+				assert.Equal(t, tt.response.GetBalance(), got.GetBalance())
+			}
+		})
+	}
+}
+
+func TestBalanceServer_GetAt(t *testing.T) {
+	verifier := auth.NewVerifier("secret", time.Hour)
+
+	ownID := uuid.New()
+	day := time.Date(2023, time.June, 14, 0, 0, 0, 0, time.UTC)
+	at := time.Date(2023, time.June, 16, 9, 30, 0, 0, time.UTC)
+
+	transactionStorage := &storage.TransactionStorageMock{
+		SumFunc: func(ctx context.Context, userID uuid.UUID, from, to time.Time) (int64, error) {
+			return 2550, nil
+		},
+	}
+	balanceService := service.NewBalanceService(&storage.TransactorMock{},
+		&storage.BalanceStorageMock{
+			GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+				return model.Balance{UserID: userID, Balance: 99999}, nil
+			},
+		},
+		transactionStorage, &storage.AuditStorageMock{}, &storage.OutboxStorageMock{},
+		&storage.SnapshotStorageMock{
+			GetBeforeFunc: func(ctx context.Context, userID uuid.UUID, at time.Time) (model.Snapshot, error) {
+				return model.Snapshot{UserID: userID, Day: day, Balance: 10000}, nil
+			},
+		},
+	)
+	client := newClient(t, balanceService, verifier)
+
+	token, err := verifier.Sign(auth.Principal{Subject: ownID.String(), Role: auth.User})
+	require.NoError(t, err)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+
+	got, err := client.GetAt(ctx, &bankv1.GetBalanceAtRequest{UserId: ownID.String(), Timestamp: timestamppb.New(at)})
+	require.NoError(t, err)
+	assert.Equal(t, 125.5, got.GetBalance())
+
+	calls := transactionStorage.SumCalls()
+	require.Len(t, calls, 1)
+	assert.True(t, day.AddDate(0, 0, 1).Equal(calls[0].From))
+	assert.True(t, at.Equal(calls[0].To))
+
+	_, err = client.GetAt(ctx, &bankv1.GetBalanceAtRequest{UserId: ownID.String()})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "the timestamp is required")
+
+	_, err = client.GetAt(ctx, &bankv1.GetBalanceAtRequest{UserId: uuid.NewString(), Timestamp: timestamppb.New(at)})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "internal", err: apperror.Internal.WithError(errors.New("db is down")), want: codes.Internal},
+		{name: "not found", err: apperror.NotFound, want: codes.NotFound},
+		{name: "already exists", err: apperror.AlreadyExists, want: codes.AlreadyExists},
+		{name: "bad request", err: apperror.BadRequest.WithMessage("amount must be longer than 0"), want: codes.InvalidArgument},
+		{name: "unauthorized", err: apperror.Unauthorized, want: codes.Unauthenticated},
+		{name: "forbidden", err: apperror.Forbidden, want: codes.PermissionDenied},
+		{name: "foreign error", err: errors.New("boom"), want: codes.Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rpc.Status(tt.err).Code())
+		})
+	}
+
+	assert.NotContains(t, rpc.Status(apperror.Internal.WithError(errors.New("db is down"))).Message(), "db is down")
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	verifier := auth.NewVerifier("secret", time.Hour)
+	policy, err := auth.NewPolicy(map[string]string{"admin": "*"})
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	balanceStorage := &storage.BalanceStorageMock{
+		GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+			close(started)
+			<-release
+			return model.Balance{UserID: userID, Balance: 100}, nil
+		},
+	}
+	balanceService := service.NewBalanceService(&storage.TransactorMock{}, balanceStorage, &storage.TransactionStorageMock{}, &storage.AuditStorageMock{}, &storage.OutboxStorageMock{}, &storage.SnapshotStorageMock{})
+
+	listener := bufconn.Listen(1 << 20)
+	server := rpc.New(verifier, newLimiter(t), slog.New(slog.NewJSONHandler(io.Discard, nil))).Handle(rpc.NewBalanceServer(balanceService, nil, policy))
+	go server.Serve(listener)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	token, err := verifier.Sign(auth.Principal{Subject: "root", Role: auth.Admin})
+	require.NoError(t, err)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+
+	done := make(chan error)
+	go func() {
+		_, err := bankv1.NewBalanceServiceClient(conn).Get(ctx, &bankv1.GetBalanceRequest{UserId: uuid.NewString()})
+		done <- err
+	}()
+	<-started
+
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- server.Shutdown(context.Background())
+	}()
+
+	// the in-flight call is drained, not cut
+	close(release)
+	assert.NoError(t, <-done)
+	assert.NoError(t, <-shutdown)
+}
+
+func TestBalanceServer_ReadYourWrites(t *testing.T) {
+	verifier := auth.NewVerifier("secret", time.Hour)
+	token, err := verifier.Sign(auth.Principal{Subject: "root", Role: auth.Admin})
+	require.NoError(t, err)
+
+	var readYourWrites bool
+	client := newClient(t, newBalanceService(&storage.BalanceStorageMock{
+		GetFunc: func(ctx context.Context, userID uuid.UUID) (model.Balance, error) {
+			readYourWrites = storage.ReadYourWrites(ctx)
+			return model.Balance{UserID: userID}, nil
+		},
+	}), verifier)
+
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{name: "unset"},
+		{name: "true", value: "true", want: true},
+		{name: "false", value: "false"},
+		{name: "invalid", value: "primary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+			if tt.value != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, rpc.MetadataReadYourWrites, tt.value)
+			}
+
+			_, err := client.Get(ctx, &bankv1.GetBalanceRequest{UserId: uuid.NewString()})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, readYourWrites)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package rpc
+
+import (
+	"bank/pkg/apperror"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Status maps an application error onto a gRPC status the same way middleware.ErrorHandler maps it onto HTTP.
+func Status(err error) *status.Status {
+	if s, ok := status.FromError(err); ok {
+		return s
+	}
+
+	var apperr apperror.Error
+	if !errors.As(err, &apperr) {
+		return status.New(codes.Unknown, apperror.Unknown.Status)
+	}
+
+	message := apperr.Message
+	if message == "" {
+		message = apperr.Status
+	}
+
+	switch apperr.Code {
+	case apperror.Internal.Code:
+		return status.New(codes.Internal, apperr.Status)
+	case apperror.NotFound.Code:
+		return status.New(codes.NotFound, message)
+	case apperror.AlreadyExists.Code:
+		return status.New(codes.AlreadyExists, message)
+	case apperror.BadRequest.Code:
+		return status.New(codes.InvalidArgument, message)
+	case apperror.Unauthorized.Code:
+		return status.New(codes.Unauthenticated, message)
+	case apperror.Forbidden.Code:
+		return status.New(codes.PermissionDenied, message)
+	case apperror.TooManyRequests.Code:
+		return status.New(codes.ResourceExhausted, message)
+	}
+
+	return status.New(codes.Unknown, message)
+}
@@ -0,0 +1,204 @@
+package rpc
+
+import (
+	"bank/internal/audit"
+	"bank/internal/auth"
+	"bank/internal/ratelimit"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"bank/pkg/logger"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MetadataRequestID is the gRPC counterpart of the X-Request-ID header.
+	MetadataRequestID = "x-request-id"
+	// MetadataRetryAfter is set along with ResourceExhausted, in seconds like the Retry-After header.
+	MetadataRetryAfter = "retry-after"
+	// MetadataReadYourWrites is the gRPC counterpart of the X-Read-Your-Writes header.
+	MetadataReadYourWrites = "x-read-your-writes"
+)
+
+// Recover turns a handler panic into apperror.Internal instead of tearing the server down.
+func Recover() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = apperror.Internal.WithError(fmt.Errorf("panic: %v", r))
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// RequestID reuses the caller's x-request-id or assigns a new one, echoes it back and puts it into the context.
+func RequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		var requestID string
+		if values := md.Get(MetadataRequestID); len(values) != 0 {
+			requestID = values[0]
+		}
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataRequestID, requestID))
+
+		return handler(logger.WithRequestID(ctx, requestID), req)
+	}
+}
+
+// ReadYourWrites serves the call from the primary database when x-read-your-writes is true, so it sees writes
+// the read replica has not caught up with yet.
+func ReadYourWrites() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		if values := md.Get(MetadataReadYourWrites); len(values) != 0 {
+			if ok, _ := strconv.ParseBool(values[0]); ok {
+				ctx = storage.WithReadYourWrites(ctx)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// Logger writes one access record per call.
+func Logger(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		log.InfoContext(ctx, "call",
+			slog.String("method", info.FullMethod),
+			slog.String("code", status.Code(err).String()),
+			slog.Duration("latency", time.Since(start)),
+		)
+
+		return resp, err
+	}
+}
+
+// Error converts any error returned further down the chain into a gRPC status. Internal and unknown errors
+// are logged with their scope and cause, which never reach the client.
+func Error(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+
+		var exceeded ratelimit.Exceeded
+		if errors.As(err, &exceeded) {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataRetryAfter, strconv.Itoa(exceeded.Seconds())))
+		}
+
+		s := Status(err)
+		if s.Code() == codes.Internal || s.Code() == codes.Unknown {
+			var apperr apperror.Error
+			errors.As(err, &apperr)
+
+			log.ErrorContext(ctx, "internal error",
+				slog.String("method", info.FullMethod),
+				slog.String("scope", apperr.Scope),
+				slog.String("message", apperr.Message),
+				slog.Any("cause", cause(err, apperr)),
+			)
+		}
+
+		return nil, s.Err()
+	}
+}
+
+func cause(err error, apperr apperror.Error) any {
+	if apperr.Err != nil {
+		return apperr.Err
+	}
+
+	return err
+}
+
+func Authenticate(verifier *auth.Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		var token string
+		if values := md.Get("authorization"); len(values) != 0 {
+			token, _ = strings.CutPrefix(values[0], "Bearer ")
+		}
+		if token == "" {
+			return nil, apperror.Unauthorized.WithMessage("missing bearer token")
+		}
+
+		principal, err := verifier.Verify(token)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(auth.NewContext(ctx, principal), req)
+	}
+}
+
+// RateLimit takes a token per call from the bucket of "GRPC <full method>" and the principal or peer address.
+// It must run after Authenticate.
+func RateLimit(limiter *ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		var caller string
+		if principal, ok := auth.FromContext(ctx); ok {
+			caller = "principal:" + principal.Subject
+		} else if p, ok := peer.FromContext(ctx); ok {
+			caller = "ip:" + p.Addr.String()
+		}
+
+		err := limiter.AllowRequest(ctx, "GRPC", info.FullMethod, caller)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func Audit() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		meta := audit.Metadata{
+			RequestID: logger.RequestID(ctx),
+		}
+
+		if p, ok := peer.FromContext(ctx); ok {
+			meta.SourceIP = p.Addr.String()
+		}
+
+		if principal, ok := auth.FromContext(ctx); ok {
+			meta.Actor = principal.Subject
+		}
+
+		if message, ok := req.(proto.Message); ok {
+			if body, err := proto.Marshal(message); err == nil && len(body) != 0 {
+				sum := sha256.Sum256(body)
+				meta.PayloadHash = hex.EncodeToString(sum[:])
+			}
+		}
+
+		return handler(audit.NewContext(ctx, meta), req)
+	}
+}
@@ -0,0 +1,70 @@
+package rpc
+
+import (
+	bankv1 "bank/api/bank/v1"
+	"bank/internal/auth"
+	"bank/internal/ratelimit"
+	"context"
+	"google.golang.org/grpc"
+	"log/slog"
+	"net"
+)
+
+type Server struct {
+	server *grpc.Server
+}
+
+func New(verifier *auth.Verifier, limiter *ratelimit.Limiter, log *slog.Logger) *Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			RequestID(),
+			ReadYourWrites(),
+			Logger(log),
+			Error(log),
+			Recover(),
+			Authenticate(verifier),
+			RateLimit(limiter),
+			Audit(),
+		),
+	)
+
+	return &Server{
+		server: server,
+	}
+}
+
+func (server *Server) Handle(balanceServer *BalanceServer) *Server {
+	bankv1.RegisterBalanceServiceServer(server.server, balanceServer)
+
+	return server
+}
+
+func (server *Server) Serve(listener net.Listener) error {
+	return server.server.Serve(listener)
+}
+
+func (server *Server) Listen(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return server.Serve(listener)
+}
+
+// Shutdown stops accepting connections and waits for in-flight calls, cancelling those left when ctx is done.
+func (server *Server) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		server.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.server.Stop()
+		return ctx.Err()
+	}
+}
@@ -2,41 +2,72 @@ package transport
 
 import (
 	_ "bank/docs"
+	"bank/internal/auth"
+	"bank/internal/config"
+	"bank/internal/metrics"
+	"bank/internal/ratelimit"
 	"bank/internal/transport/handler"
 	"bank/internal/transport/middleware"
+	"context"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	fiberSwagger "github.com/swaggo/fiber-swagger"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+	"log/slog"
 )
 
 type Server struct {
-	router *fiber.App
+	router   *fiber.App
+	verifier *auth.Verifier
+	limiter  *ratelimit.Limiter
 }
 
-func New() *Server {
+func New(conf config.Server, verifier *auth.Verifier, limiter *ratelimit.Limiter, log *slog.Logger) *Server {
 	router := fiber.New(fiber.Config{
-		ErrorHandler: middleware.ErrorHandler(),
+		ErrorHandler:          middleware.ErrorHandler(log),
+		DisableStartupMessage: true,
+		ReadTimeout:           conf.ReadTimeout,
+		WriteTimeout:          conf.WriteTimeout,
+		IdleTimeout:           conf.IdleTimeout,
+		BodyLimit:             conf.BodyLimit,
 	})
 
 	router.Use(
+		middleware.RequestID(),
+		middleware.ReadYourWrites(),
+		middleware.Tracing(),
+		middleware.Metrics(),
+		middleware.Logger(log),
 		recover.New(),
-		logger.New(),
 	)
 
 	return &Server{
-		router: router,
+		router:   router,
+		verifier: verifier,
+		limiter:  limiter,
 	}
 }
 
-func (server *Server) Handle(balanceHandler *handler.BalanceHandler) *Server {
+// Handle registers the routes, a nil webhookHandler leaves out the webhook API.
+func (server *Server) Handle(
+	balanceHandler *handler.BalanceHandler,
+	auditHandler *handler.AuditHandler,
+	webhookHandler *handler.WebhookHandler,
+	healthHandler *handler.HealthHandler,
+) *Server {
 	server.router.Group("/swagger/*", fiberSwagger.WrapHandler)
+	healthHandler.Register(server.router)
+	server.router.Get("/metrics", metricsHandler())
 
-	api := server.router.Group("/api")
+	api := server.router.Group("/api", middleware.Authenticate(server.verifier), middleware.RateLimit(server.limiter), middleware.Audit())
 	{
 		v1 := api.Group("/v1")
 		{
 			balanceHandler.Register(v1.Group("/balance"))
+			auditHandler.Register(v1.Group("/audit"))
+			if webhookHandler != nil {
+				webhookHandler.Register(v1.Group("/webhook"))
+			}
 		}
 	}
 
@@ -46,3 +77,17 @@ func (server *Server) Handle(balanceHandler *handler.BalanceHandler) *Server {
 func (server *Server) Listen(addr string) error {
 	return server.router.Listen(addr)
 }
+
+// Shutdown stops accepting connections and waits for in-flight requests until ctx is done.
+func (server *Server) Shutdown(ctx context.Context) error {
+	return server.router.ShutdownWithContext(ctx)
+}
+
+func metricsHandler() fiber.Handler {
+	handler := fasthttpadaptor.NewFastHTTPHandler(metrics.Handler())
+
+	return func(c *fiber.Ctx) error {
+		handler(c.Context())
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package webhook_test
+
+import (
+	"bank/internal/webhook"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestPublic(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.1.2.3"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "169.254.169.254"},
+		{addr: "fe80::1"},
+		{addr: "fc00::1"},
+		{addr: "0.0.0.0"},
+		{addr: "100.64.0.1"},
+		{addr: "::ffff:127.0.0.1"},
+		{addr: "224.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.want, webhook.Public(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: "public https", url: "https://93.184.216.34/hooks"},
+		{name: "plain http", url: "http://93.184.216.34/hooks", wantErr: true},
+		{name: "loopback", url: "https://127.0.0.1/hooks", wantErr: true},
+		{name: "metadata service", url: "https://169.254.169.254/latest/meta-data", wantErr: true},
+		{name: "private", url: "https://10.0.0.1:8443/hooks", wantErr: true},
+		{name: "ipv6 loopback", url: "https://[::1]/hooks", wantErr: true},
+		{name: "localhost", url: "https://localhost/hooks", wantErr: true},
+		{name: "no host", url: "https:///hooks", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := webhook.CheckURL(context.Background(), net.DefaultResolver, tt.url)
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+		})
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := webhook.NewClient(time.Second).Get(server.URL)
+	assert.Error(t, err, "the client must not connect to loopback")
+
+	var opErr *net.OpError
+	assert.True(t, errors.As(err, &opErr), "error: %v", err)
+}
@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"bank/internal/model"
+	"bank/internal/storage"
+	"bank/pkg/apperror"
+	"bank/pkg/backoff"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type Dispatcher struct {
+	webhookStorage storage.WebhookStorage
+	http           *http.Client
+	backoff        backoff.Backoff
+	maxAttempts    int
+	interval       time.Duration
+	batch          uint64
+	lease          time.Duration
+	log            *slog.Logger
+}
+
+func NewDispatcher(
+	webhookStorage storage.WebhookStorage,
+	client *http.Client,
+	backoff backoff.Backoff,
+	maxAttempts int,
+	interval time.Duration,
+	batch uint64,
+	log *slog.Logger,
+) *Dispatcher {
+	return &Dispatcher{
+		webhookStorage: webhookStorage,
+		http:           client,
+		backoff:        backoff,
+		maxAttempts:    maxAttempts,
+		interval:       interval,
+		batch:          batch,
+		lease:          client.Timeout + time.Minute,
+		log:            log,
+	}
+}
+
+func (dispatcher *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatcher.interval)
+	defer ticker.Stop()
+
+	for {
+		dispatched, err := dispatcher.Dispatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			dispatcher.log.ErrorContext(ctx, "webhook dispatcher", slog.Any("error", err))
+		}
+
+		if err == nil && uint64(dispatched) == dispatcher.batch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch attempts every due delivery once and records the outcome: delivered, rescheduled with backoff,
+// or dead once the attempts are exhausted. A delivery whose subscription is gone is dead right away, one whose
+// subscription could not be read is rescheduled like a failed attempt.
+func (dispatcher *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	deliveries, err := dispatcher.webhookStorage.Claim(ctx, dispatcher.batch, dispatcher.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range deliveries {
+		attempts := delivery.Attempts + 1
+
+		subscription, err := dispatcher.webhookStorage.GetSubscription(ctx, delivery.SubscriptionID)
+		if err != nil {
+			err = dispatcher.fail(ctx, delivery, attempts, err, errors.Is(err, apperror.NotFound))
+			if err != nil {
+				return 0, err
+			}
+
+			continue
+		}
+
+		err = dispatcher.send(ctx, subscription, delivery)
+		if err == nil {
+			err = dispatcher.webhookStorage.MarkDelivered(ctx, delivery.ID, attempts)
+			if err != nil {
+				return 0, err
+			}
+
+			continue
+		}
+
+		err = dispatcher.fail(ctx, delivery, attempts, err, false)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// fail records the failed attempt of delivery and reschedules it with backoff, unless dead is set or the attempts
+// are exhausted.
+func (dispatcher *Dispatcher) fail(ctx context.Context, delivery model.WebhookDelivery, attempts int, cause error, dead bool) error {
+	dead = dead || attempts >= dispatcher.maxAttempts
+
+	return dispatcher.webhookStorage.MarkFailed(ctx, delivery.ID, attempts, time.Now().Add(dispatcher.backoff.Next(attempts)), cause.Error(), dead)
+}
+
+func (dispatcher *Dispatcher) send(ctx context.Context, subscription model.WebhookSubscription, delivery model.WebhookDelivery) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderID, delivery.ID.String())
+	request.Header.Set(HeaderEvent, delivery.EventType)
+	request.Header.Set(HeaderTimestamp, fmt.Sprint(now.Unix()))
+	request.Header.Set(HeaderSignature, Sign(subscription.Secret, now, delivery.Payload))
+
+	response, err := dispatcher.http.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 1<<16))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package webhook_test
+
+import (
+	"bank/internal/model"
+	"bank/internal/storage"
+	"bank/internal/webhook"
+	"bank/pkg/apperror"
+	"bank/pkg/backoff"
+	"bank/pkg/logger"
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"id":"1"}`)
+
+	signature := webhook.Sign("secret", now, body)
+
+	assert.True(t, webhook.Verify("secret", now, body, signature))
+	assert.False(t, webhook.Verify("other", now, body, signature))
+	assert.False(t, webhook.Verify("secret", now.Add(time.Second), body, signature))
+	assert.False(t, webhook.Verify("secret", now, []byte(`{"id":"2"}`), signature))
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		attempts      int
+		wantDelivered bool
+		wantDead      bool
+	}{
+		{name: "delivered", status: http.StatusOK, attempts: 0, wantDelivered: true},
+		{name: "rescheduled", status: http.StatusBadGateway, attempts: 0, wantDead: false},
+		{name: "dead letter", status: http.StatusBadGateway, attempts: 2, wantDead: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subscription := model.WebhookSubscription{ID: uuid.New(), Secret: "secret", Enabled: true}
+			delivery := model.WebhookDelivery{
+				ID:             uuid.New(),
+				SubscriptionID: subscription.ID,
+				EventType:      "balance.debet",
+				Payload:        []byte(`{"type":"balance.debet"}`),
+				Attempts:       tt.attempts,
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				unix, _ := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+
+				assert.True(t, webhook.Verify(subscription.Secret, time.Unix(unix, 0), body, r.Header.Get(webhook.HeaderSignature)))
+				assert.Equal(t, delivery.ID.String(), r.Header.Get(webhook.HeaderID))
+				assert.Equal(t, delivery.EventType, r.Header.Get(webhook.HeaderEvent))
+
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+			subscription.URL = server.URL
+
+			var delivered, dead bool
+			var failedAttempts int
+			webhookStorage := &storage.WebhookStorageMock{
+				ClaimFunc: func(ctx context.Context, limit uint64, lease time.Duration) ([]model.WebhookDelivery, error) {
+					return []model.WebhookDelivery{delivery}, nil
+				},
+				GetSubscriptionFunc: func(ctx context.Context, id uuid.UUID) (model.WebhookSubscription, error) {
+					return subscription, nil
+				},
+				MarkDeliveredFunc: func(ctx context.Context, id uuid.UUID, attempts int) error {
+					delivered = true
+					return nil
+				},
+				MarkFailedFunc: func(ctx context.Context, id uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string, isDead bool) error {
+					assert.True(t, nextAttemptAt.After(time.Now()))
+					assert.NotEmpty(t, lastError)
+					failedAttempts = attempts
+					dead = isDead
+					return nil
+				},
+			}
+
+			dispatcher := webhook.NewDispatcher(webhookStorage, server.Client(), backoff.Backoff{Base: time.Second, Max: time.Minute}, 3, time.Second, 10, logger.New(io.Discard, "info"))
+
+			dispatched, err := dispatcher.Dispatch(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, 1, dispatched)
+			assert.Equal(t, tt.wantDelivered, delivered)
+			assert.Equal(t, tt.wantDead, dead)
+
+			if !tt.wantDelivered {
+				assert.Equal(t, tt.attempts+1, failedAttempts)
+			}
+		})
+	}
+}
+
+func TestDispatcher_DispatchSubscriptionError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	subscription := model.WebhookSubscription{ID: uuid.New(), URL: server.URL, Secret: "secret", Enabled: true}
+	missing := model.WebhookDelivery{ID: uuid.New(), SubscriptionID: uuid.New(), Payload: []byte(`{}`)}
+	unreadable := model.WebhookDelivery{ID: uuid.New(), SubscriptionID: uuid.New(), Payload: []byte(`{}`), Attempts: 1}
+	delivery := model.WebhookDelivery{ID: uuid.New(), SubscriptionID: subscription.ID, Payload: []byte(`{}`)}
+
+	type failure struct {
+		attempts int
+		dead     bool
+	}
+	failed := make(map[uuid.UUID]failure)
+	var delivered []uuid.UUID
+
+	webhookStorage := &storage.WebhookStorageMock{
+		ClaimFunc: func(ctx context.Context, limit uint64, lease time.Duration) ([]model.WebhookDelivery, error) {
+			return []model.WebhookDelivery{missing, unreadable, delivery}, nil
+		},
+		GetSubscriptionFunc: func(ctx context.Context, id uuid.UUID) (model.WebhookSubscription, error) {
+			switch id {
+			case subscription.ID:
+				return subscription, nil
+			case unreadable.SubscriptionID:
+				return model.WebhookSubscription{}, apperror.Internal.WithError(errors.New("connection reset"))
+			}
+
+			return model.WebhookSubscription{}, apperror.NotFound
+		},
+		MarkDeliveredFunc: func(ctx context.Context, id uuid.UUID, attempts int) error {
+			delivered = append(delivered, id)
+			return nil
+		},
+		MarkFailedFunc: func(ctx context.Context, id uuid.UUID, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error {
+			assert.NotEmpty(t, lastError)
+			failed[id] = failure{attempts: attempts, dead: dead}
+			return nil
+		},
+	}
+
+	dispatcher := webhook.NewDispatcher(webhookStorage, server.Client(), backoff.Backoff{Base: time.Second, Max: time.Minute}, 3, time.Second, 10, logger.New(io.Discard, "info"))
+
+	dispatched, err := dispatcher.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, dispatched)
+	assert.Equal(t, []uuid.UUID{delivery.ID}, delivered, "the rest of the batch is delivered")
+	assert.Equal(t, map[uuid.UUID]failure{
+		missing.ID:    {attempts: 1, dead: true},
+		unreadable.ID: {attempts: 2, dead: false},
+	}, failed)
+}
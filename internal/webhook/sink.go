@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"bank/internal/domain"
+	"bank/internal/model"
+	"bank/internal/outbox"
+	"bank/internal/storage"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+)
+
+type sink struct {
+	webhookStorage storage.WebhookStorage
+}
+
+// NewSink returns an outbox sink that turns every event into pending deliveries of the matching subscriptions.
+func NewSink(webhookStorage storage.WebhookStorage) outbox.Sink {
+	return &sink{webhookStorage: webhookStorage}
+}
+
+// Publish delivers event as it is to the subscriptions to every account. A subscription to one account gets the
+// event with that account only, so the other party of a transfer does not leak its balance.
+func (sink *sink) Publish(ctx context.Context, event domain.Event) error {
+	err := sink.enqueue(ctx, event, []uuid.UUID{})
+	if err != nil {
+		return err
+	}
+
+	var changed domain.BalanceChanged
+	if err = json.Unmarshal(event.Payload, &changed); err != nil {
+		return err
+	}
+
+	for _, accountID := range event.AccountIDs {
+		scoped := event
+		scoped.AccountIDs = []uuid.UUID{accountID}
+		scoped.Payload, err = json.Marshal(changed.For(accountID))
+		if err != nil {
+			return err
+		}
+
+		if err = sink.enqueue(ctx, scoped, scoped.AccountIDs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// enqueue stores the deliveries of event to the subscriptions of accountIDs, or to every account when it is empty.
+func (sink *sink) enqueue(ctx context.Context, event domain.Event, accountIDs []uuid.UUID) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = sink.webhookStorage.Enqueue(ctx, model.Event{
+		EventID:    event.ID,
+		Type:       event.Type,
+		AccountIDs: accountIDs,
+		Payload:    payload,
+		CreatedAt:  event.CreatedAt,
+	})
+
+	return err
+}
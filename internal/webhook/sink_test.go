@@ -0,0 +1,82 @@
+package webhook_test
+
+import (
+	"bank/internal/domain"
+	"bank/internal/model"
+	"bank/internal/storage"
+	"bank/internal/webhook"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSink_Publish(t *testing.T) {
+	payer, payee := uuid.New(), uuid.New()
+
+	changed := domain.BalanceChanged{
+		Balances: []domain.Balance{{UserID: payer, Balance: 50}, {UserID: payee, Balance: 150}},
+		Transactions: []domain.Transaction{
+			{ID: uuid.New(), PayeeID: payer, PayerID: &payee, Type: domain.Transfer, Amount: -50},
+			{ID: uuid.New(), PayeeID: payee, PayerID: &payer, Type: domain.Transfer, Amount: 50},
+		},
+	}
+	payload, err := json.Marshal(changed)
+	require.NoError(t, err)
+
+	event := domain.Event{
+		ID:         uuid.New(),
+		Type:       domain.EventTransfer,
+		AccountIDs: []uuid.UUID{payer, payee},
+		Payload:    payload,
+		CreatedAt:  time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	webhookStorage := &storage.WebhookStorageMock{
+		EnqueueFunc: func(ctx context.Context, event model.Event) (int64, error) { return 1, nil },
+	}
+	require.NoError(t, webhook.NewSink(webhookStorage).Publish(context.Background(), event))
+
+	calls := webhookStorage.EnqueueCalls()
+	require.Len(t, calls, 3)
+
+	tests := []struct {
+		name       string
+		accountIDs []uuid.UUID
+		want       domain.BalanceChanged
+	}{
+		{
+			name:       "every account",
+			accountIDs: []uuid.UUID{},
+			want:       changed,
+		},
+		{
+			name:       "payer",
+			accountIDs: []uuid.UUID{payer},
+			want:       domain.BalanceChanged{Balances: changed.Balances[:1], Transactions: changed.Transactions[:1]},
+		},
+		{
+			name:       "payee",
+			accountIDs: []uuid.UUID{payee},
+			want:       domain.BalanceChanged{Balances: changed.Balances[1:], Transactions: changed.Transactions[1:]},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enqueued := calls[i].Event
+			assert.Equal(t, event.ID, enqueued.EventID)
+			assert.Equal(t, tt.accountIDs, enqueued.AccountIDs)
+
+			var delivered domain.Event
+			require.NoError(t, json.Unmarshal(enqueued.Payload, &delivered))
+
+			var got domain.BalanceChanged
+			require.NoError(t, json.Unmarshal(delivered.Payload, &got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -9,5 +9,5 @@ CREATE TABLE IF NOT EXISTS balance
 
 -- +goose Down
 -- +goose StatementBegin
-DROP TABLE IF EXISTS "user";
+DROP TABLE IF EXISTS balance;
 -- +goose StatementEnd
@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS audit
+(
+    id             BIGSERIAL PRIMARY KEY,
+    actor          TEXT        NOT NULL,
+    source_ip      TEXT        NOT NULL DEFAULT '',
+    request_id     TEXT        NOT NULL DEFAULT '',
+    operation      TEXT        NOT NULL,
+    account_id     UUID        NOT NULL,
+    balance_before BIGINT      NOT NULL,
+    balance_after  BIGINT      NOT NULL,
+    payload_hash   TEXT        NOT NULL DEFAULT '',
+    prev_hash      TEXT        NOT NULL DEFAULT '',
+    hash           TEXT        NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_account_id_idx ON audit (account_id, id);
+CREATE INDEX IF NOT EXISTS audit_actor_idx ON audit (actor, id);
+CREATE INDEX IF NOT EXISTS audit_request_id_idx ON audit (request_id);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION audit_append_only() RETURNS TRIGGER AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER audit_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE
+    ON audit
+    FOR EACH STATEMENT
+EXECUTE FUNCTION audit_append_only();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit;
+DROP FUNCTION IF EXISTS audit_append_only();
+-- +goose StatementEnd
@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_transaction_activity() RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM pg_notify('balance_activity', json_build_object(
+            'kind', 'transaction',
+            'account_id', NEW.payee_id,
+            'transaction', json_build_object(
+                    'id', NEW.id,
+                    'payee_id', NEW.payee_id,
+                    'payer_id', NEW.payer_id,
+                    'type', NEW.type,
+                    'amount', NEW.amount,
+                    'comment', LEFT(NEW.comment, 1000),
+                    'created_at', NEW.created_at
+                ))::TEXT);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER transaction_activity
+    AFTER INSERT
+    ON transaction
+    FOR EACH ROW
+EXECUTE FUNCTION notify_transaction_activity();
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_balance_activity() RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM pg_notify('balance_activity', json_build_object(
+            'kind', 'balance',
+            'account_id', NEW.user_id,
+            'balance', NEW.balance
+        )::TEXT);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER balance_activity
+    AFTER UPDATE
+    ON balance
+    FOR EACH ROW
+    WHEN (OLD.balance IS DISTINCT FROM NEW.balance)
+EXECUTE FUNCTION notify_balance_activity();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS balance_activity ON balance;
+DROP TRIGGER IF EXISTS transaction_activity ON transaction;
+DROP FUNCTION IF EXISTS notify_balance_activity();
+DROP FUNCTION IF EXISTS notify_transaction_activity();
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS transaction_payee_id_created_at_idx ON transaction (payee_id, created_at, id);
+CREATE INDEX IF NOT EXISTS transaction_payee_id_amount_idx ON transaction (payee_id, amount);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS transaction_payee_id_amount_idx;
+DROP INDEX IF EXISTS transaction_payee_id_created_at_idx;
+-- +goose StatementEnd
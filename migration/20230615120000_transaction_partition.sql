@@ -0,0 +1,153 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the constraint and index names of the old table are taken over by the partitioned one
+ALTER TABLE transaction
+    RENAME TO transaction_unpartitioned;
+-- the foreign keys are looked up rather than dropped by guessed names, the new table takes their names over
+DO
+$$
+    DECLARE
+        foreign_key NAME;
+    BEGIN
+        FOR foreign_key IN SELECT conname FROM pg_constraint WHERE conrelid = 'transaction_unpartitioned'::REGCLASS AND contype = 'f'
+            LOOP
+                EXECUTE FORMAT('ALTER TABLE transaction_unpartitioned DROP CONSTRAINT %I', foreign_key);
+            END LOOP;
+    END
+$$;
+ALTER TABLE transaction_unpartitioned
+    DROP CONSTRAINT transaction_pkey;
+DROP INDEX transaction_payee_id_created_at_idx, transaction_payee_id_amount_idx;
+
+-- the partition key has to be part of the primary key
+CREATE TABLE transaction
+(
+    id         UUID        NOT NULL DEFAULT GEN_RANDOM_UUID(),
+    payee_id   UUID REFERENCES balance (user_id) ON DELETE CASCADE,
+    payer_id   UUID REFERENCES balance (user_id) ON DELETE CASCADE,
+    type       TEXT        NOT NULL,
+    amount     BIGINT      NOT NULL,
+    comment    TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+CREATE INDEX transaction_payee_id_created_at_idx ON transaction (payee_id, created_at, id);
+CREATE INDEX transaction_payee_id_amount_idx ON transaction (payee_id, amount);
+
+-- transaction_archive holds the partitions moved out by bank archive, they no longer reference the balances
+CREATE TABLE transaction_archive
+(
+    id         UUID        NOT NULL,
+    payee_id   UUID,
+    payer_id   UUID,
+    type       TEXT        NOT NULL,
+    amount     BIGINT      NOT NULL,
+    comment    TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+CREATE INDEX transaction_archive_payee_id_created_at_idx ON transaction_archive (payee_id, created_at, id);
+CREATE INDEX transaction_archive_payee_id_amount_idx ON transaction_archive (payee_id, amount);
+
+CREATE VIEW transaction_history AS
+SELECT id, payee_id, payer_id, type, amount, comment, created_at
+FROM transaction
+UNION ALL
+SELECT id, payee_id, payer_id, type, amount, comment, created_at
+FROM transaction_archive;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- create_transaction_partition creates the partition of the UTC month of ts unless it exists and returns its name.
+CREATE OR REPLACE FUNCTION create_transaction_partition(ts TIMESTAMPTZ) RETURNS TEXT AS
+$$
+DECLARE
+    partition_month TIMESTAMP := DATE_TRUNC('month', ts AT TIME ZONE 'UTC');
+    partition_name  TEXT      := 'transaction_' || TO_CHAR(partition_month, 'YYYY_MM');
+BEGIN
+    -- concurrent callers would both find the partition missing
+    PERFORM PG_ADVISORY_XACT_LOCK(HASHTEXT('create_transaction_partition'));
+
+    EXECUTE FORMAT('CREATE TABLE IF NOT EXISTS %I PARTITION OF transaction FOR VALUES FROM (%L) TO (%L)',
+                   partition_name,
+                   partition_month AT TIME ZONE 'UTC',
+                   (partition_month + INTERVAL '1 month') AT TIME ZONE 'UTC');
+
+    RETURN partition_name;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+SELECT create_transaction_partition(month AT TIME ZONE 'UTC')
+FROM GENERATE_SERIES(
+             DATE_TRUNC('month', LEAST((SELECT MIN(created_at) FROM transaction_unpartitioned), NOW()) AT TIME ZONE 'UTC'),
+             DATE_TRUNC('month', GREATEST((SELECT MAX(created_at) FROM transaction_unpartitioned), NOW()) AT TIME ZONE 'UTC'),
+             INTERVAL '1 month') AS month;
+
+INSERT INTO transaction (id, payee_id, payer_id, type, amount, comment, created_at)
+SELECT id, payee_id, payer_id, type, amount, comment, created_at
+FROM transaction_unpartitioned;
+
+DROP TABLE transaction_unpartitioned;
+
+CREATE TRIGGER transaction_activity
+    AFTER INSERT
+    ON transaction
+    FOR EACH ROW
+EXECUTE FUNCTION notify_transaction_activity();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW transaction_history;
+
+ALTER TABLE transaction
+    RENAME TO transaction_partitioned;
+DO
+$$
+    DECLARE
+        foreign_key NAME;
+    BEGIN
+        FOR foreign_key IN SELECT conname FROM pg_constraint WHERE conrelid = 'transaction_partitioned'::REGCLASS AND contype = 'f'
+            LOOP
+                EXECUTE FORMAT('ALTER TABLE transaction_partitioned DROP CONSTRAINT %I', foreign_key);
+            END LOOP;
+    END
+$$;
+ALTER TABLE transaction_partitioned
+    DROP CONSTRAINT transaction_pkey;
+DROP INDEX transaction_payee_id_created_at_idx, transaction_payee_id_amount_idx;
+
+CREATE TABLE transaction
+(
+    id         UUID PRIMARY KEY     DEFAULT GEN_RANDOM_UUID(),
+    payee_id   UUID REFERENCES balance (user_id) ON DELETE CASCADE,
+    payer_id   UUID REFERENCES balance (user_id) ON DELETE CASCADE,
+    type       TEXT        NOT NULL,
+    amount     BIGINT      NOT NULL,
+    comment    TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX transaction_payee_id_created_at_idx ON transaction (payee_id, created_at, id);
+CREATE INDEX transaction_payee_id_amount_idx ON transaction (payee_id, amount);
+
+INSERT INTO transaction (id, payee_id, payer_id, type, amount, comment, created_at)
+SELECT id, payee_id, payer_id, type, amount, comment, created_at
+FROM transaction_partitioned
+UNION ALL
+SELECT id, payee_id, payer_id, type, amount, comment, created_at
+FROM transaction_archive;
+
+DROP TABLE transaction_partitioned, transaction_archive;
+DROP FUNCTION create_transaction_partition(TIMESTAMPTZ);
+
+CREATE TRIGGER transaction_activity
+    AFTER INSERT
+    ON transaction
+    FOR EACH ROW
+EXECUTE FUNCTION notify_transaction_activity();
+-- +goose StatementEnd
@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+-- no foreign key to balance: checking it would share-lock the balance rows a transfer holds for update while the
+-- transfer waits for the snapshot lock below
+CREATE TABLE IF NOT EXISTS balance_snapshot
+(
+    user_id    UUID        NOT NULL,
+    day        DATE        NOT NULL,
+    balance    BIGINT      NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS balance_snapshot_day
+(
+    day        DATE PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- invalidate_balance_snapshot drops the snapshots of the payee from the day of a row stamped with a day that has
+-- ended, e.g. a backdated import or a transfer committing after the settle window, as they no longer add up. It runs
+-- when the row commits and waits for a snapshot being taken, which then either sees the row or is dropped.
+CREATE OR REPLACE FUNCTION invalidate_balance_snapshot() RETURNS TRIGGER AS
+$$
+BEGIN
+    IF NEW.created_at < DATE_TRUNC('day', CLOCK_TIMESTAMP() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' THEN
+        PERFORM PG_ADVISORY_XACT_LOCK_SHARED(HASHTEXT('balance_snapshot'));
+
+        DELETE
+        FROM balance_snapshot
+        WHERE user_id = NEW.payee_id
+          AND day >= (NEW.created_at AT TIME ZONE 'UTC')::DATE;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE CONSTRAINT TRIGGER transaction_balance_snapshot
+    AFTER INSERT
+    ON transaction
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW
+EXECUTE FUNCTION invalidate_balance_snapshot();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS transaction_balance_snapshot ON transaction;
+DROP FUNCTION IF EXISTS invalidate_balance_snapshot();
+DROP TABLE IF EXISTS balance_snapshot_day;
+DROP TABLE IF EXISTS balance_snapshot;
+-- +goose StatementEnd
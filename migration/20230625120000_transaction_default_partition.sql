@@ -0,0 +1,70 @@
+-- +goose Up
+-- +goose StatementBegin
+-- rows of a month without a partition, e.g. backdated imports, land here instead of failing
+CREATE TABLE IF NOT EXISTS transaction_default PARTITION OF transaction DEFAULT;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- create_transaction_partition creates the partition of the UTC month of ts unless it exists and returns its name.
+-- The rows of the month in the default partition are moved to it: a partition cannot be attached while the default
+-- one holds rows of its range.
+CREATE OR REPLACE FUNCTION create_transaction_partition(ts TIMESTAMPTZ) RETURNS TEXT AS
+$$
+DECLARE
+    partition_month TIMESTAMP   := DATE_TRUNC('month', ts AT TIME ZONE 'UTC');
+    partition_name  TEXT        := 'transaction_' || TO_CHAR(partition_month, 'YYYY_MM');
+    partition_from  TIMESTAMPTZ := partition_month AT TIME ZONE 'UTC';
+    partition_to    TIMESTAMPTZ := (partition_month + INTERVAL '1 month') AT TIME ZONE 'UTC';
+BEGIN
+    -- concurrent callers would both find the partition missing
+    PERFORM PG_ADVISORY_XACT_LOCK(HASHTEXT('create_transaction_partition'));
+
+    IF TO_REGCLASS(partition_name) IS NOT NULL THEN
+        RETURN partition_name;
+    END IF;
+
+    -- the moved rows are not new, filling the table before it is attached keeps the activity trigger quiet
+    EXECUTE FORMAT('CREATE TABLE %I (LIKE transaction INCLUDING DEFAULTS)', partition_name);
+    EXECUTE FORMAT('WITH moved AS (DELETE FROM transaction_default WHERE created_at >= %L AND created_at < %L RETURNING *) '
+                       'INSERT INTO %I SELECT * FROM moved',
+                   partition_from, partition_to, partition_name);
+    EXECUTE FORMAT('ALTER TABLE transaction ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)',
+                   partition_name, partition_from, partition_to);
+
+    RETURN partition_name;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION create_transaction_partition(ts TIMESTAMPTZ) RETURNS TEXT AS
+$$
+DECLARE
+    partition_month TIMESTAMP := DATE_TRUNC('month', ts AT TIME ZONE 'UTC');
+    partition_name  TEXT      := 'transaction_' || TO_CHAR(partition_month, 'YYYY_MM');
+BEGIN
+    -- concurrent callers would both find the partition missing
+    PERFORM PG_ADVISORY_XACT_LOCK(HASHTEXT('create_transaction_partition'));
+
+    EXECUTE FORMAT('CREATE TABLE IF NOT EXISTS %I PARTITION OF transaction FOR VALUES FROM (%L) TO (%L)',
+                   partition_name,
+                   partition_month AT TIME ZONE 'UTC',
+                   (partition_month + INTERVAL '1 month') AT TIME ZONE 'UTC');
+
+    RETURN partition_name;
+END;
+$$ LANGUAGE plpgsql;
+
+-- the rows of the default partition get partitions of their own
+ALTER TABLE transaction DETACH PARTITION transaction_default;
+
+SELECT create_transaction_partition(created_at)
+FROM transaction_default;
+
+INSERT INTO transaction (id, payee_id, payer_id, type, amount, comment, created_at)
+SELECT id, payee_id, payer_id, type, amount, comment, created_at
+FROM transaction_default;
+
+DROP TABLE transaction_default;
+-- +goose StatementEnd
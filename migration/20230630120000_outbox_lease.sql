@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- a relay leases the events it publishes instead of holding their rows locked while the sinks run
+ALTER TABLE outbox
+    ADD COLUMN IF NOT EXISTS leased_until TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE outbox
+    DROP COLUMN IF EXISTS leased_until;
+-- +goose StatementEnd
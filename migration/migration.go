@@ -0,0 +1,62 @@
+// Package migration embeds the goose SQL migrations kept next to it, so the binary applies them from any
+// working directory.
+package migration
+
+import (
+	"database/sql"
+	"embed"
+	"github.com/pressly/goose/v3"
+	"io"
+	"log"
+)
+
+//go:embed *.sql
+var embedded embed.FS
+
+// SourceDir is where Create writes new migrations, relative to the repository root.
+const SourceDir = "migration"
+
+func init() {
+	// every goose call of the binary reads the embedded migrations
+	goose.SetBaseFS(embedded)
+}
+
+func Open(dsn string) (*sql.DB, error) {
+	return goose.OpenDBWithDriver("postgres", dsn)
+}
+
+// SetOutput sends the goose progress and status lines to w without timestamps.
+func SetOutput(w io.Writer) {
+	goose.SetLogger(log.New(w, "", 0))
+}
+
+// Run runs a goose command: up, down, status, redo, version, up-to VERSION or down-to VERSION.
+func Run(db *sql.DB, command string, args ...string) error {
+	return goose.Run(command, db, ".", args...)
+}
+
+// Create writes a new timestamped SQL migration named name to SourceDir. It has to run from the repository root,
+// the binary embeds the migrations that existed when it was built.
+func Create(name string) error {
+	return goose.Create(nil, SourceDir, name, "sql")
+}
+
+// Latest returns the version of the newest embedded migration.
+func Latest() (int64, error) {
+	migrations, err := goose.CollectMigrations(".", 0, goose.MaxVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	last, err := migrations.Last()
+	if err != nil {
+		return 0, err
+	}
+
+	return last.Version, nil
+}
+
+// Current returns the version the database is migrated to.
+func Current(db *sql.DB) (int64, error) {
+	return goose.GetDBVersion(db)
+}
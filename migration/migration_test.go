@@ -0,0 +1,110 @@
+package migration_test
+
+import (
+	"bank/internal/pgtest"
+	"bank/migration"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"sort"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Run(m))
+}
+
+func TestLatest(t *testing.T) {
+	latest, err := migration.Latest()
+	require.NoError(t, err)
+	assert.NotZero(t, latest)
+}
+
+// TestRoundTrip applies the migrations one by one and checks that each down restores the previous schema and
+// that applying the migration again yields the same schema as the first time.
+func TestRoundTrip(t *testing.T) {
+	db, err := migration.Open(pgtest.DSN(t))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	migration.SetOutput(io.Discard)
+
+	latest, err := migration.Latest()
+	require.NoError(t, err)
+
+	empty := schema(t, db)
+	before := empty
+
+	for {
+		current, err := migration.Current(db)
+		require.NoError(t, err)
+		if current == latest {
+			break
+		}
+
+		require.NoError(t, migration.Run(db, "up-by-one"))
+		version, err := migration.Current(db)
+		require.NoError(t, err)
+		after := schema(t, db)
+
+		require.NoError(t, migration.Run(db, "down"), "down %d", version)
+		assert.Equal(t, before, schema(t, db), "down %d does not restore the previous schema", version)
+
+		require.NoError(t, migration.Run(db, "up-by-one"), "up %d after down", version)
+		assert.Equal(t, after, schema(t, db), "up %d after down yields another schema", version)
+
+		before = after
+	}
+
+	require.NoError(t, migration.Run(db, "down-to", "0"))
+	assert.Equal(t, empty, schema(t, db), "down to 0 leaves objects behind")
+}
+
+// schema describes the objects of the public schema, except the goose version table, as sorted lines.
+func schema(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	queries := []string{
+		`SELECT format('column %s.%s %s %s %s', table_name, column_name, data_type, is_nullable, COALESCE(column_default, ''))
+		FROM information_schema.columns
+		WHERE table_schema = 'public' AND table_name <> 'goose_db_version'`,
+		`SELECT format('index %s', indexdef)
+		FROM pg_indexes
+		WHERE schemaname = 'public' AND tablename <> 'goose_db_version'`,
+		`SELECT format('constraint %s.%s %s', conrelid::regclass, conname, pg_get_constraintdef(oid))
+		FROM pg_constraint
+		WHERE connamespace = 'public'::regnamespace AND conrelid::regclass::text <> 'goose_db_version'`,
+		`SELECT format('trigger %s', pg_get_triggerdef(oid))
+		FROM pg_trigger
+		WHERE NOT tgisinternal`,
+		`SELECT format('function %s', pg_get_functiondef(oid))
+		FROM pg_proc
+		WHERE pronamespace = 'public'::regnamespace AND prokind IN ('f', 'p')`,
+		`SELECT format('sequence %s', sequence_name)
+		FROM information_schema.sequences
+		WHERE sequence_schema = 'public' AND sequence_name <> 'goose_db_version_id_seq'`,
+		`SELECT format('table %s', relname)
+		FROM pg_class
+		WHERE relnamespace = 'public'::regnamespace AND relkind IN ('r', 'p', 'v', 'm') AND relname <> 'goose_db_version'`,
+	}
+
+	var lines []string
+	for _, query := range queries {
+		rows, err := db.Query(query)
+		require.NoError(t, err)
+
+		for rows.Next() {
+			var line string
+			require.NoError(t, rows.Scan(&line))
+			lines = append(lines, line)
+		}
+		require.NoError(t, rows.Err())
+		require.NoError(t, rows.Close())
+	}
+
+	sort.Strings(lines)
+
+	return lines
+}
@@ -1,25 +1,45 @@
 package apilayer
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 )
 
+// Observer is called after every API call with its operation name, latency and error, if any.
+type Observer func(operation string, duration time.Duration, err error)
+
 type Client struct {
-	http   *http.Client
-	apikey string
+	http     *http.Client
+	apikey   string
+	observer Observer
 }
 
 func New(apikey string) *Client {
 	return &Client{
-		http:   http.DefaultClient,
-		apikey: apikey,
+		http:     http.DefaultClient,
+		apikey:   apikey,
+		observer: func(string, time.Duration, error) {},
 	}
 }
 
+// WithHTTPClient replaces http.DefaultClient, e.g. with one whose transport adds tracing.
+func (client *Client) WithHTTPClient(http *http.Client) *Client {
+	client.http = http
+
+	return client
+}
+
+func (client *Client) WithObserver(observer Observer) *Client {
+	client.observer = observer
+
+	return client
+}
+
 type ConvertResponse struct {
 	Date       string `json:"date"`
 	Historical bool   `json:"historical"`
@@ -36,7 +56,11 @@ type ConvertResponse struct {
 	Success bool    `json:"success"`
 }
 
-func (client *Client) Convert(amount float64, from, to string) (float64, error) {
+func (client *Client) Convert(ctx context.Context, amount float64, from, to string) (result float64, err error) {
+	defer func(start time.Time) {
+		client.observer("convert", time.Since(start), err)
+	}(time.Now())
+
 	u, _ := url.Parse("https://api.apilayer.com/currency_data/convert")
 
 	v := url.Values{}
@@ -46,7 +70,7 @@ func (client *Client) Convert(amount float64, from, to string) (float64, error)
 
 	u.RawQuery, _ = url.PathUnescape(v.Encode())
 
-	request, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return 0.0, err
 	}
@@ -57,6 +81,7 @@ func (client *Client) Convert(amount float64, from, to string) (float64, error)
 	if err != nil {
 		return 0.0, err
 	}
+	defer response.Body.Close()
 
 	var convertResponse ConvertResponse
 	err = json.NewDecoder(response.Body).Decode(&convertResponse)
@@ -70,3 +95,18 @@ func (client *Client) Convert(amount float64, from, to string) (float64, error)
 
 	return convertResponse.Result, nil
 }
+
+// Ping checks that the API is reachable without spending request quota: any HTTP response counts.
+func (client *Client) Ping(ctx context.Context) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://api.apilayer.com", nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := client.http.Do(request)
+	if err != nil {
+		return err
+	}
+
+	return response.Body.Close()
+}
@@ -0,0 +1,29 @@
+// Package backoff spaces out the retries of failed operations.
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+type Backoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// Next returns the delay before retry number attempt (starting at 1): Base doubled per attempt, capped at Max,
+// with the upper half jittered so operations that failed together don't retry in lockstep.
+func (backoff Backoff) Next(attempt int) time.Duration {
+	delay := backoff.Base
+	for i := 1; i < attempt && delay < backoff.Max; i++ {
+		delay *= 2
+	}
+
+	if delay > backoff.Max {
+		delay = backoff.Max
+	}
+
+	half := delay / 2
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
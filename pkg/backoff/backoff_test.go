@@ -0,0 +1,35 @@
+package backoff_test
+
+import (
+	"bank/pkg/backoff"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBackoff_Next(t *testing.T) {
+	b := backoff.Backoff{Base: time.Second, Max: 10 * time.Second}
+
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{attempt: 1, max: time.Second},
+		{attempt: 2, max: 2 * time.Second},
+		{attempt: 3, max: 4 * time.Second},
+		{attempt: 4, max: 8 * time.Second},
+		{attempt: 5, max: 10 * time.Second},
+		{attempt: 50, max: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempt), func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				delay := b.Next(tt.attempt)
+				assert.GreaterOrEqual(t, delay, tt.max/2)
+				assert.LessOrEqual(t, delay, tt.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+type Listener struct {
+	config Config
+	retry  time.Duration
+}
+
+func NewListener(config Config) *Listener {
+	return &Listener{
+		config: config,
+		retry:  time.Second,
+	}
+}
+
+// Listen subscribes to channel on a dedicated connection and passes every notification payload to handle until
+// ctx is done. A broken connection is re-established after a short pause; notifications sent in between are lost,
+// so consumers must be able to catch up from the tables themselves.
+func (listener *Listener) Listen(ctx context.Context, channel string, handle func(payload string)) error {
+	for {
+		err := listener.listen(ctx, channel, handle)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(listener.retry):
+			}
+		}
+	}
+}
+
+func (listener *Listener) listen(ctx context.Context, channel string, handle func(payload string)) error {
+	conn, err := pgx.Connect(ctx, listener.config.String())
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+	if err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		handle(notification.Payload)
+	}
+}
@@ -2,25 +2,71 @@ package postgres
 
 import (
 	"context"
-	"fmt"
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/lib/pq"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
 )
 
 type Config struct {
+	// DSN, when set, is the connection string and the connection fields below are ignored.
+	DSN string
+
 	Host     string
 	Port     string
 	User     string
 	Password string
 	DB       string
+
+	// SSLMode defaults to disable.
+	SSLMode     string
+	SSLRootCert string
+
+	// Pool settings, zero values keep the pgxpool defaults.
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+
+	// StatementTimeout aborts any statement of a pool connection running longer, zero disables it.
+	// It is not part of String, so migrations are not cut short.
+	StatementTimeout time.Duration
+
+	// Tracer, when set, is installed as ConnConfig.Tracer of every pool connection.
+	Tracer pgx.QueryTracer
 }
 
 func (config Config) String() string {
-	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", config.User, config.Password, config.Host,
-		config.Port, config.DB)
+	if config.DSN != "" {
+		return config.DSN
+	}
+
+	sslmode := config.SSLMode
+	if sslmode == "" {
+		sslmode = "disable"
+	}
+
+	query := url.Values{}
+	query.Set("sslmode", sslmode)
+	if config.SSLRootCert != "" {
+		query.Set("sslrootcert", config.SSLRootCert)
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(config.User, config.Password),
+		Host:     net.JoinHostPort(config.Host, config.Port),
+		Path:     "/" + config.DB,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
 }
 
 type Client interface {
@@ -32,6 +78,15 @@ type Client interface {
 	Select(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 }
 
+// Pool is the Client returned by NewClient. It owns its connections and must be closed.
+type Pool interface {
+	Client
+	BeginTx(ctx context.Context, options pgx.TxOptions) (pgx.Tx, error)
+	Stat() *pgxpool.Stat
+	// Close waits for acquired connections to be released and closes all of them.
+	Close()
+}
+
 type Tx interface {
 	Begin(ctx context.Context) (pgx.Tx, error)
 	Commit(ctx context.Context) error
@@ -62,18 +117,28 @@ func (t Txw) Select(ctx context.Context, dest interface{}, query string, args ..
 	return pgxscan.Select(ctx, t.Tx, dest, query, args...)
 }
 
-func NewClient(ctx context.Context, cfg Config) (Client, error) {
-	config, err := pgxpool.ParseConfig(cfg.String())
+// NewClient returns a Pool connected to the database, failing unless it answers a ping.
+func NewClient(ctx context.Context, cfg Config) (Pool, error) {
+	pool, err := newPool(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	var pool *pgxpool.Pool
-	pool, err = pgxpool.NewWithConfig(ctx, config)
+	err = pool.Ping(ctx)
 	if err != nil {
+		pool.Close()
 		return nil, err
 	}
-	err = pool.Ping(ctx)
+
+	return &client{
+		Pool: pool,
+	}, nil
+}
+
+// NewPool returns a Pool without checking the database, connections are opened when they are first used. It only
+// fails on an invalid Config.
+func NewPool(ctx context.Context, cfg Config) (Pool, error) {
+	pool, err := newPool(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -83,6 +148,33 @@ func NewClient(ctx context.Context, cfg Config) (Client, error) {
 	}, nil
 }
 
+func newPool(ctx context.Context, cfg Config) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(cfg.String())
+	if err != nil {
+		return nil, err
+	}
+	config.ConnConfig.Tracer = cfg.Tracer
+
+	if cfg.MaxConns > 0 {
+		config.MaxConns = cfg.MaxConns
+	}
+	config.MinConns = cfg.MinConns
+	if cfg.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+	if cfg.StatementTimeout > 0 {
+		config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	return pgxpool.NewWithConfig(ctx, config)
+}
+
 func (c *client) Begin(ctx context.Context) (pgx.Tx, error) {
 	return c.Pool.Begin(ctx)
 }
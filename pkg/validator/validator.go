@@ -11,6 +11,22 @@ import (
 
 var validate = validator.New()
 
+// messages are the errors reported for the tags added by Register.
+var messages = make(map[string]string)
+
+// Register adds tag, checked by valid on the value of the field. A field failing it is reported as the field name
+// followed by message. It is meant to be called from init functions, before any validation.
+func Register(tag, message string, valid func(value reflect.Value) bool) {
+	err := validate.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+		return valid(fl.Field())
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	messages[tag] = message
+}
+
 func Validate(s any) error {
 	err := validate.Struct(s)
 
@@ -55,6 +71,10 @@ func prepareValidationError(field string, err validator.FieldError) string {
 	case "oneof":
 		return fmt.Sprintf("%s must be one of from: %s", field, strings.Replace(err.Param(), " ", ", ", -1))
 	default:
+		if message, ok := messages[err.Tag()]; ok {
+			return fmt.Sprintf("%s %s", field, message)
+		}
+
 		return fmt.Sprintf("%s is invalid, expected type %s", field, err.Tag())
 	}
 }